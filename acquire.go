@@ -0,0 +1,139 @@
+package connectpool
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// AcquiredConn is a connection handed out by Acquire. Close gives it back to the pool, so it can be used
+// with defer conn.Close() wherever an io.Closer is expected.
+type AcquiredConn interface {
+	Conn() any                               // Gets the connection variable
+	Close() error                            // Gives the connection back to the pool
+	Invalidated() bool                       // Reports whether the pool reclaimed the connection while it was held
+	InvalidationReason() (CloseReason, bool) // Gets why the pool reclaimed the connection, if it did
+}
+
+type acquiredConn struct {
+	connect      any         // Connection variable handed out
+	connector    connector   // Connector holding the connection
+	generation   uint64      // Generation of the connector when it was handed out
+	cancel       func()      // Releases the connector
+	resetOnClose bool        // Whether Close resets the connection first
+	closed       atomic.Bool // Whether Close has been called
+}
+
+// WithResetOnClose makes AcquiredConn.Close reset the connection with the pool's resetMethod before giving it back.
+// A connection that cannot be reset is closed instead of reused.
+func WithResetOnClose() RegisterOption {
+	return func(config *registerConfig) {
+		config.resetOnClose = true
+	}
+}
+
+func (a *acquiredConn) Conn() any {
+	return a.connect
+}
+
+func (a *acquiredConn) Invalidated() bool {
+	_, invalidated := a.InvalidationReason()
+	return invalidated
+}
+
+func (a *acquiredConn) InvalidationReason() (CloseReason, bool) {
+	// An invalidated connector is never recycled, so a recycled one was not reclaimed while held
+	if a.connector.Generation() != a.generation {
+		return 0, false
+	}
+
+	return a.connector.Invalidation()
+}
+
+func (a *acquiredConn) Close() (err error) {
+	if !a.closed.CompareAndSwap(false, true) {
+		return ErrConnectReleased
+	}
+
+	if a.resetOnClose {
+		if err = a.connector.Reset(); err != nil {
+			a.connector.Discard() // Never reuse a connection left in an unknown state
+		}
+	}
+
+	a.cancel()
+	return err
+}
+
+// Acquire waits for a connection until ctx is done, returning it as an AcquiredConn.
+func (p *connectPool) Acquire(ctx context.Context, options ...RegisterOption) (AcquiredConn, error) {
+	config := newRegisterConfig(options)
+
+	c, err := p.searchConnector(ctx, config.priority)
+	if err != nil {
+		return nil, err
+	}
+
+	config.startWorking(c)
+	connect, cancel := p.handOut(c)
+
+	return &acquiredConn{
+		connect:      connect,
+		connector:    c,
+		generation:   c.Generation(),
+		cancel:       cancel,
+		resetOnClose: config.resetOnClose,
+	}, nil
+}
+
+// RegisterFunc registers a connection from pool, passes it to fn and gives it back once fn returns, even if fn
+// fails or panics, returning fn's typed result. It fails with the pool's error if no connection can be registered,
+// or with ErrPoolClosed if pool is not an Acquirer and its Register failed.
+func RegisterFunc[R any](pool ConnectPool, fn func(connect any) (R, error), options ...RegisterOption) (R, error) {
+	acquirer, ok := pool.(Acquirer)
+	if !ok {
+		connect, cancel := pool.Register(options...)
+		if cancel == nil {
+			var zero R
+			return zero, ErrPoolClosed
+		}
+		defer cancel()
+
+		return fn(connect)
+	}
+
+	conn, err := acquirer.Acquire(context.Background(), options...)
+	if err != nil {
+		var zero R
+		return zero, err
+	}
+	defer conn.Close()
+
+	return fn(conn.Conn())
+}
+
+// AcquireWithTimeout waits up to waitTimeout for a connection and hands it out for up to holdTimeout, after which
+// it is released automatically. A zero timeout means no limit. It fails with context.DeadlineExceeded if no
+// connection became available within waitTimeout.
+func (p *connectPool) AcquireWithTimeout(waitTimeout, holdTimeout time.Duration, options ...RegisterOption) (newConnect any, cancelFunc func(), err error) {
+	ctx := context.Background()
+	if waitTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, waitTimeout)
+		defer cancel()
+	}
+
+	c, err := p.searchConnector(ctx, newRegisterConfig(options).priority)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if holdTimeout > 0 {
+		c.StartTimingWork(holdTimeout)
+	} else {
+		c.StartWorking()
+	}
+
+	newConnect, cancelFunc = p.handOut(c)
+	return newConnect, cancelFunc, nil
+}
@@ -0,0 +1,61 @@
+package connectpool
+
+import (
+	"context"
+	"time"
+)
+
+const defaultAffinityWait = 10 * time.Millisecond // Default time RegisterForKey waits for a busy affine connector
+
+// rendezvousWeight scores token for key, the highest scoring token being the key's affine connector. Adding or
+// removing a connector only moves the keys whose highest scoring token changed.
+func rendezvousWeight(key, token uint64) uint64 {
+	// splitmix64 finalizer over the combined key and token
+	x := key ^ (token * 0x9e3779b97f4a7c15)
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// RegisterForKey registers the connection of the connector key hashes onto, so the same key lands on the same
+// connection while the pool is stable. If that connector is busy, it waits up to the WithAffinityWait duration
+// before falling back to any connection like Register.
+func (p *connectPool) RegisterForKey(key uint64, options ...RegisterOption) (newConnect any, cancelFunc func()) {
+	timer := p.clock.NewTimer(p.affinityWait)
+	defer timer.Stop()
+
+	for {
+		idle := p.idleSignal.wait() // Taken before the attempt, so a release in between is not missed
+
+		c, busy := p.pool.GetAffineConnector(key)
+		if c != nil {
+			p.affinityHits.Add(1)
+
+			c.StartWorking()
+			return p.handOut(c)
+		}
+
+		if !busy {
+			break // The pool holds no connector the key could land on
+		}
+
+		select {
+		case <-idle:
+			continue
+		case <-timer.C():
+		case <-p.done:
+		}
+
+		break
+	}
+
+	p.affinityMisses.Add(1)
+
+	c, _ := p.searchConnector(context.Background(), newRegisterConfig(options).priority)
+	if c == nil {
+		return nil, nil
+	}
+
+	c.StartWorking()
+	return p.handOut(c)
+}
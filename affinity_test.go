@@ -0,0 +1,55 @@
+package connectpool
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRegisterForKey_SameKeySameConnection(t *testing.T) {
+	p := newTestPool(t, counter(), WithTestabilityMode(), WithCap(4), WithAffinityWait(time.Millisecond))
+
+	// Builds a stable pool of four connections
+	var cancels []func()
+	for range 4 {
+		_, cancel := p.Register()
+		cancels = append(cancels, cancel)
+	}
+	for _, cancel := range cancels {
+		cancel()
+	}
+
+	const keys = 100
+	landed := make(map[uint64]any, keys)
+	used := make(map[any]bool)
+	for round := range 2 {
+		for key := range uint64(keys) {
+			connect, cancel := p.RegisterForKey(key)
+			cancel()
+
+			if round == 0 {
+				landed[key] = connect
+				used[connect] = true
+			} else if connect != landed[key] {
+				t.Fatalf("key %d got connection %v, then %v", key, landed[key], connect)
+			}
+		}
+	}
+	if len(used) < 2 {
+		t.Fatalf("%d keys all landed on connection %v, want them spread", keys, landed[0])
+	}
+
+	// A key whose connection stays busy falls back to another one once the wait is over
+	held, cancel := p.RegisterForKey(7)
+	other, cancelOther := p.RegisterForKey(7)
+	cancelOther()
+	cancel()
+
+	if held != landed[7] || other == held {
+		t.Fatalf("key 7 got %v while its connection %v was held, want another one", other, held)
+	}
+
+	stats := p.StatsFresh()
+	if stats.AffinityHits != 2*keys+1 || stats.AffinityMisses != 1 {
+		t.Fatalf("%d affinity hits and %d misses, want %d and 1", stats.AffinityHits, stats.AffinityMisses, 2*keys+1)
+	}
+}
@@ -0,0 +1,77 @@
+package connectpool
+
+import (
+	"sync"
+	"time"
+)
+
+const autoGrowInterval = 100 * time.Millisecond // Interval between the utilization checks of AutoGrow
+
+// AutoSizeToLoad adjusts the cap every interval from the utilization, WorkingNumber over Size: it grows the cap by
+// 10% while the utilization is above targetUtilization and shrinks it by 10% while it is below half of it, always
+// keeping it between minCap and maxCap. Connectors above a lowered cap are left to the idle policies. Auto-sizing
+// stops when the returned CancelFunc is called or the pool is closed.
+func (p *connectPool) AutoSizeToLoad(targetUtilization float64, minCap, maxCap int, interval time.Duration) CancelFunc {
+	done := make(chan struct{})
+
+	go func() {
+		for p.sleep(interval, done) {
+			utilization := p.utilization()
+			p.config.update(func(next *runtimeConfig) {
+				next.maxSize = autoSizedCap(next.maxSize, utilization, targetUtilization, minCap, maxCap)
+			})
+			p.trackCap()
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
+
+// AutoGrow raises the cap by step every autoGrowInterval while the pool is full and its utilization is above
+// triggerUtilization. Unlike AutoSizeToLoad it never lowers the cap. Growth stops when the returned CancelFunc is called
+// or the pool is closed.
+func (p *connectPool) AutoGrow(triggerUtilization float64, step int) CancelFunc {
+	done := make(chan struct{})
+
+	go func() {
+		for p.sleep(autoGrowInterval, done) {
+			// Below the cap the pool creates connectors on demand, so only a full pool needs a higher one
+			if p.Size() >= p.Cap() && p.utilization() > triggerUtilization {
+				p.config.update(func(next *runtimeConfig) { next.maxSize += step })
+				p.trackCap()
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
+
+// utilization returns the fraction of connectors in use, 0 for an empty pool.
+func (p *connectPool) utilization() float64 {
+	size := p.Size()
+	if size == 0 {
+		return 0
+	}
+
+	return float64(p.WorkingNumber()) / float64(size)
+}
+
+// autoSizedCap returns the cap AutoSizeToLoad sets for the current cap and utilization.
+func autoSizedCap(current int, utilization, targetUtilization float64, minCap, maxCap int) int {
+	step := max(current/10, 1)
+
+	switch {
+	case utilization > targetUtilization:
+		current += step
+	case utilization < targetUtilization/2:
+		current -= step
+	}
+
+	return min(max(current, minCap), maxCap)
+}
@@ -0,0 +1,73 @@
+package connectpool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/HuXin0817/ConnectPool/clock"
+)
+
+func TestAutoSizeToLoad_FollowsPoolClock(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	p := newTestPool(t, counter(), WithClock(fake), WithTestabilityMode(), WithCap(10))
+
+	_, cancel := p.Register()
+	defer cancel()
+
+	stop := p.AutoSizeToLoad(0.5, 1, 20, time.Minute)
+	defer stop()
+
+	// A fully used pool grows by one step per interval of the pool's clock, and not before
+	for want := 11; want <= 12; want++ {
+		waitUntil(t, "the interval's timer was armed", func() bool { return fake.Pending() == 1 })
+		if got := p.Cap(); got != want-1 {
+			t.Fatalf("Cap = %d before the interval elapsed, want %d", got, want-1)
+		}
+
+		fake.Advance(time.Minute)
+		waitUntil(t, "the cap grew", func() bool { return p.Cap() == want })
+	}
+}
+
+func TestAutoSizeToLoad_SimulatedLoad(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	p := newTestPool(t, counter(), WithClock(fake), WithTestabilityMode(), WithCap(10))
+
+	stop := p.AutoSizeToLoad(0.5, 8, 12, time.Minute)
+	defer stop()
+
+	// step lets one interval elapse and checks the cap it leaves
+	step := func(phase string, want int) {
+		t.Helper()
+
+		waitUntil(t, "the interval's timer was armed", func() bool { return fake.Pending() == 1 })
+		fake.Advance(time.Minute)
+		waitUntil(t, "the next interval's timer was armed", func() bool { return fake.Pending() == 1 })
+
+		if got := p.Cap(); got != want {
+			t.Fatalf("Cap = %d under %s, want %d", got, phase, want)
+		}
+	}
+
+	// Every connection in use: grows by 10% per interval up to maxCap
+	var cancels []func()
+	for range 4 {
+		_, cancel := p.Register()
+		cancels = append(cancels, cancel)
+	}
+	for _, want := range []int{11, 12, 12} {
+		step("full load", want)
+	}
+
+	// Half in use, neither above the target nor below half of it: the cap holds
+	cancels[0]()
+	cancels[1]()
+	step("half load", 12)
+
+	// Nothing in use: shrinks by 10% per interval down to minCap
+	cancels[2]()
+	cancels[3]()
+	for _, want := range []int{11, 10, 9, 8, 8} {
+		step("no load", want)
+	}
+}
@@ -0,0 +1,88 @@
+package connectpool
+
+import "context"
+
+// RegisterN registers k connections at once. Each connection is searched for like Register's, honoring the Throttle
+// limit and the priority queues, until k connectors are held simultaneously or ctx is done, in which case every
+// partially held connector is given back unused and ctx's error is returned. Concurrent RegisterN callers are served
+// one at a time, so they can never hold each other's connectors.
+func (p *connectPool) RegisterN(ctx context.Context, k int, options ...RegisterOption) (newConnects []any, cancelFunc func(), err error) {
+	if k < 0 {
+		return nil, nil, ErrNegativeBatch
+	}
+
+	if k > p.Cap() {
+		return nil, nil, ErrBatchTooLarge
+	}
+
+	// Waits for the previous RegisterN caller to finish, or for ctx to be done
+	select {
+	case p.batchTurn <- struct{}{}:
+		defer func() { <-p.batchTurn }()
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+
+	config := newRegisterConfig(options)
+
+	held := make([]connector, 0, k)
+	for len(held) < k {
+		c, err := p.searchConnector(ctx, config.priority)
+		if err != nil {
+			p.releaseUnused(held) // Releases the partial holds before giving up
+			return nil, nil, err
+		}
+
+		config.startWorking(c)
+		held = append(held, c)
+	}
+
+	newConnects, cancelFunc = p.acquireAll(held)
+	return newConnects, cancelFunc, nil
+}
+
+// TryRegisterN registers as many connections as are available immediately, up to k.
+func (p *connectPool) TryRegisterN(k int, options ...RegisterOption) (newConnects []any, cancelFunc func(), err error) {
+	if k < 0 {
+		return nil, nil, ErrNegativeBatch
+	}
+
+	config := newRegisterConfig(options)
+
+	held := make([]connector, 0, k)
+	for len(held) < k {
+		c, _ := p.tryAcquire()
+		if c == nil {
+			break
+		}
+
+		config.startWorking(c)
+		held = append(held, c)
+	}
+
+	newConnects, cancelFunc = p.acquireAll(held)
+	return newConnects, cancelFunc, nil
+}
+
+// releaseUnused gives back connectors held but never handed out, neither counting them as used nor reporting a release.
+func (p *connectPool) releaseUnused(held []connector) {
+	for _, c := range held {
+		c.ReleaseUnused()
+	}
+}
+
+// acquireAll hands out the held connectors, returning their connection variables and one combined cancelFunc.
+func (p *connectPool) acquireAll(held []connector) (newConnects []any, cancelFunc func()) {
+	newConnects = make([]any, len(held))
+	cancelFuncs := make([]func(), len(held))
+
+	for i, c := range held {
+		newConnects[i], cancelFuncs[i] = p.handOut(c)
+	}
+
+	return newConnects, func() {
+		for _, cancel := range cancelFuncs {
+			cancel()
+		}
+	}
+}
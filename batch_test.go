@@ -0,0 +1,126 @@
+package connectpool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRegisterN_NoDeadlock(t *testing.T) {
+	p := newTestPool(t, counter(), WithCap(4))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+
+		// Each caller needs 3 of the 4 connectors, so two partial holds would deadlock without serialization
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 50; j++ {
+				conns, release, err := p.RegisterN(ctx, 3)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+
+				if len(conns) != 3 {
+					t.Errorf("RegisterN returned %d connections, want 3", len(conns))
+				}
+
+				release()
+			}
+		}()
+	}
+
+	// Single acquirers share the pool with the batches
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				_, release := p.Register()
+				release()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if working := p.WorkingNumber(); working != 0 {
+		t.Fatalf("WorkingNumber = %d after every release", working)
+	}
+}
+
+func TestRegisterN_RollsBackUnused(t *testing.T) {
+	var released atomic.Int64
+	p := newTestPool(t, counter(), WithCap(3), WithOnRelease(func(any, ReleaseCause) { released.Add(1) }))
+
+	_, release := p.Register()
+	defer release()
+
+	// Only two connectors can be held, so the batch of three times out holding two
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, _, err := p.RegisterN(ctx, 3); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("RegisterN error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	if working := p.WorkingNumber(); working != 1 {
+		t.Fatalf("WorkingNumber = %d after the rollback, want 1", working)
+	}
+
+	if n := released.Load(); n != 0 {
+		t.Fatalf("the rollback reported %d releases", n)
+	}
+
+	for _, s := range p.Snapshot() {
+		if !s.Working && !p.pool.GetConnector(s.Token).NeverUsed() {
+			t.Fatalf("connector %d was marked used by the rollback", s.Token)
+		}
+	}
+}
+
+func TestRegisterN_InvalidSize(t *testing.T) {
+	p := newTestPool(t, counter(), WithCap(2))
+
+	if _, _, err := p.RegisterN(context.Background(), -1); !errors.Is(err, ErrNegativeBatch) {
+		t.Fatalf("RegisterN(-1) error = %v, want %v", err, ErrNegativeBatch)
+	}
+
+	if _, _, err := p.TryRegisterN(-1); !errors.Is(err, ErrNegativeBatch) {
+		t.Fatalf("TryRegisterN(-1) error = %v, want %v", err, ErrNegativeBatch)
+	}
+
+	if _, _, err := p.RegisterN(context.Background(), 3); !errors.Is(err, ErrBatchTooLarge) {
+		t.Fatalf("RegisterN(3) error = %v, want %v", err, ErrBatchTooLarge)
+	}
+}
+
+func TestTryRegisterN(t *testing.T) {
+	p := newTestPool(t, counter(), WithCap(2))
+
+	conns, release, err := p.TryRegisterN(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(conns) != 2 {
+		t.Fatalf("TryRegisterN returned %d connections, want the 2 available", len(conns))
+	}
+
+	release()
+
+	if working := p.WorkingNumber(); working != 0 {
+		t.Fatalf("WorkingNumber = %d after the release", working)
+	}
+}
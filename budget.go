@@ -0,0 +1,67 @@
+package connectpool
+
+// AddCreationBudget adds delta creations to the budget set by WithCreationBudget. It has no effect on a pool
+// without a creation budget.
+func (p *connectPool) AddCreationBudget(delta int64) {
+	for {
+		budget := p.creationBudget.Load()
+		if budget < 0 {
+			return
+		}
+
+		if p.creationBudget.CompareAndSwap(budget, max(budget+delta, 0)) {
+			p.idleSignal.signal() // Wakes the waiters, which may create connectors again
+			return
+		}
+	}
+}
+
+// takeCreationBudget consumes one creation from the budget, reporting false if it is exhausted.
+func (p *connectPool) takeCreationBudget() bool {
+	for {
+		budget := p.creationBudget.Load()
+		if budget < 0 {
+			return true // Unlimited
+		}
+
+		if budget == 0 {
+			return false
+		}
+
+		if p.creationBudget.CompareAndSwap(budget, budget-1) {
+			return true
+		}
+	}
+}
+
+// tryAcquire makes a single attempt to find a connector like tryConnector, failing with the sticky error once the pool
+// has failed, with ErrPoolClosed once the pool is closed, or with ErrBudgetExhausted once the budget is spent and the pool holds no connector that could ever be
+// given back.
+func (p *connectPool) tryAcquire() (connector, error) {
+	if err := p.Err(); err != nil {
+		return nil, err
+	}
+
+	if p.draining.Load() {
+		return nil, ErrPoolDraining
+	}
+
+	if c := p.tryConnector(); c != nil {
+		if err := p.checkInterception(c); err != nil {
+			c.ReleaseUnused()
+			return nil, err
+		}
+
+		return c, nil
+	}
+
+	if p.isClosed() {
+		return nil, ErrPoolClosed
+	}
+
+	if p.creationBudget.Load() == 0 && p.Size() == 0 {
+		return nil, ErrBudgetExhausted
+	}
+
+	return nil, nil
+}
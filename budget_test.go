@@ -0,0 +1,43 @@
+package connectpool
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCreationBudget_ExhaustAndTopUp(t *testing.T) {
+	p := newTestPool(t, counter(), WithTestabilityMode(), WithCreationBudget(2))
+
+	_, first := p.Register()
+	_, second := p.Register()
+	first()
+	second()
+
+	if budget := p.Stats().CreationBudget; budget != 0 {
+		t.Fatalf("CreationBudget = %d after two creations, want 0", budget)
+	}
+
+	// Evictions do not refund the budget, so nothing is left to acquire
+	p.ForceEvictAll()
+	if _, _, err := p.AcquireConnector(); !errors.Is(err, ErrBudgetExhausted) {
+		t.Fatalf("AcquireConnector error = %v with the budget spent, want %v", err, ErrBudgetExhausted)
+	}
+
+	p.AddCreationBudget(1)
+	if budget := p.Stats().CreationBudget; budget != 1 {
+		t.Fatalf("CreationBudget = %d after the top-up, want 1", budget)
+	}
+
+	c, release, err := p.AcquireConnector()
+	if err != nil {
+		t.Fatalf("AcquireConnector failed after the top-up: %v", err)
+	}
+	defer release()
+
+	if connect := c.Conn(); connect != int64(3) {
+		t.Fatalf("AcquireConnector returned connection %v, want a new one, 3", connect)
+	}
+	if budget := p.Stats().CreationBudget; budget != 0 {
+		t.Fatalf("CreationBudget = %d after spending the top-up, want 0", budget)
+	}
+}
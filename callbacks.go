@@ -0,0 +1,61 @@
+package connectpool
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// callbacks is an immutable bundle of the pool's callbacks, any of which may be nil. It is replaced as a whole, so an
+// operation loading it once sees a consistent set.
+type callbacks struct {
+	connectMethod   func() any              // Method for creating connections
+	resetMethod     func(connect any) error // Method for resetting a connection rejected by validateOnReturn
+	closeMethod     func(connect any)       // Method to execute before closing a connection
+	idleCloseMethod func(connect any)       // closeMethod timed for the clear passes
+	dealPanicMethod func(panicInfo any)     // Method for handling panic
+	healthCheck     func(connect any) error // Method checking whether a connection still works
+	connectTimeout  time.Duration           // Limit on the time spent in a single connectMethod call, 0 for none
+	onIdleEvict     func(c connector)       // Invoked with each connector a clear pass closes, before closing it
+}
+
+// callbackBundle holds the current callbacks, replaced copy-on-write.
+type callbackBundle struct {
+	current atomic.Pointer[callbacks] // Never nil once the pool is created
+}
+
+// Load returns the current callbacks, empty if none were stored.
+func (b *callbackBundle) Load() *callbacks {
+	if b == nil {
+		return &callbacks{}
+	}
+
+	if current := b.current.Load(); current != nil {
+		return current
+	}
+
+	return &callbacks{}
+}
+
+// update replaces the current callbacks with a copy changed by change, retrying if they were replaced meanwhile.
+func (b *callbackBundle) update(change func(next *callbacks)) {
+	for {
+		current := b.current.Load()
+
+		var next callbacks
+		if current != nil {
+			next = *current
+		}
+		change(&next)
+
+		if b.current.CompareAndSwap(current, &next) {
+			return
+		}
+	}
+}
+
+// handlePanic handles panicInfo with the current dealPanicMethod, if any.
+func (b *callbackBundle) handlePanic(panicInfo any) {
+	if dealPanicMethod := b.Load().dealPanicMethod; dealPanicMethod != nil {
+		dealPanicMethod(panicInfo)
+	}
+}
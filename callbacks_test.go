@@ -0,0 +1,81 @@
+package connectpool
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCallbackBundle_SwapDuringChurn(t *testing.T) {
+	var closed sync.Map // Connections closed so far
+	var closedTwice atomic.Bool
+	closeMethod := func(connect any) {
+		if _, loaded := closed.LoadOrStore(connect, true); loaded {
+			closedTwice.Store(true)
+		}
+	}
+
+	p := newTestPool(t, counter(), WithCloseMethod(closeMethod), WithMaxFreeTime(time.Millisecond),
+		WithAutoClearInterval(time.Millisecond))
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+
+				if _, cancel := p.Register(); cancel != nil {
+					cancel()
+				}
+			}
+		}()
+	}
+
+	// Swaps the bundle while the clear passes and the churn load it
+	var wrapped atomic.Int64
+	for range 200 {
+		p.SetPanicHandler(func(any) {})
+		p.WrapCloseMethod(func(any) { wrapped.Add(1) })
+		time.Sleep(50 * time.Microsecond)
+	}
+
+	close(stop)
+	wg.Wait()
+	p.Close()
+
+	if closedTwice.Load() {
+		t.Fatal("a connection was closed twice")
+	}
+	if wrapped.Load() == 0 {
+		t.Fatal("no close went through the swapped callbacks")
+	}
+}
+
+// BenchmarkClear measures a clear pass over 100 free connectors, none of which expires.
+func BenchmarkClear(b *testing.B) {
+	p := newTestPool(b, counter(), WithTestabilityMode(), WithCap(100), WithMaxFreeTime(time.Hour))
+
+	cancels := make([]func(), 100)
+	for i := range cancels {
+		_, cancels[i] = p.Register()
+	}
+	for _, cancel := range cancels {
+		cancel()
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		if p.ClearNow() != 0 {
+			b.Fatal("a free connector was evicted")
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package connectpool
+
+import "context"
+
+// CapChannel returns a channel closed the first time the pool's size reaches its cap. It is never reset.
+func (p *connectPool) CapChannel() <-chan struct{} {
+	return p.capReached
+}
+
+// AtCapCount returns how many times the pool's size has grown to its cap.
+func (p *connectPool) AtCapCount() int64 {
+	return p.atCapCount.Load()
+}
+
+// trackCap records the pool reaching or leaving its cap, called whenever the size changes.
+func (p *connectPool) trackCap() {
+	if p.Size() < p.Cap() {
+		p.atCap.Store(false)
+		return
+	}
+
+	// Counts only the crossings from below the cap
+	if p.atCap.CompareAndSwap(false, true) {
+		p.atCapCount.Add(1)
+		p.capReachedOnce.Do(func() { close(p.capReached) })
+	}
+}
+
+// SetMaxSize changes the cap to n and converges the pool to it at once: above n, connectors are force-evicted like
+// ShrinkTo at its deadline; below MinSize, the pool is warmed up to min(MinSize, n) for up to one autoClear cycle. It
+// does not block: evicted connectors in use are closed once released, and warming runs in the background. A negative
+// n is ignored.
+func (p *connectPool) SetMaxSize(n int) {
+	if n < 0 {
+		return
+	}
+
+	p.config.update(func(next *runtimeConfig) { next.maxSize = n })
+	p.forceEvictDownTo(n)
+	p.trackCap()
+	p.idleSignal.signal() // Wakes the waiters, which may create connectors under the raised cap
+	p.warmToMinSize()
+}
+
+// warmToMinSize warms the pool in the background, for up to one autoClear interval, if it holds fewer connectors than
+// MinSize allows under the cap.
+func (p *connectPool) warmToMinSize() {
+	if p.Size() >= min(p.MinSize(), p.Cap()) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.AutoClearInterval())
+	go func() {
+		defer cancel()
+
+		for range p.Warm(ctx) {
+			// Failures are retried by Warm until the cycle ends
+		}
+	}()
+}
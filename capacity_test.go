@@ -0,0 +1,56 @@
+package connectpool
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSetMaxSize_ConvergesWithinOneCycle(t *testing.T) {
+	t.Run("shrink", func(t *testing.T) {
+		p := newTestPool(t, counter(), WithTestabilityMode(), WithCap(4))
+
+		// The connectors in use are evicted at once rather than waited for
+		_, cancel, err := p.RegisterN(context.Background(), 4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer cancel()
+
+		returned := make(chan struct{})
+		go func() {
+			defer close(returned)
+			p.SetMaxSize(2)
+		}()
+
+		select {
+		case <-returned:
+		case <-time.After(time.Second):
+			t.Fatal("SetMaxSize blocked until the evicted connections were released")
+		}
+
+		if p.Cap() != 2 || p.Size() != 2 {
+			t.Fatalf("cap %d, size %d after SetMaxSize(2), want 2 and 2", p.Cap(), p.Size())
+		}
+	})
+
+	t.Run("grow", func(t *testing.T) {
+		p := newTestPool(t, counter(), WithTestabilityMode(), WithCap(1), WithMinSize(3), WithAutoClearInterval(time.Second))
+
+		p.SetMaxSize(4)
+		waitUntil(t, "the pool was warmed to its min size", func() bool { return p.Size() == 3 })
+
+		if p.Cap() != 4 {
+			t.Fatalf("cap %d after SetMaxSize(4), want 4", p.Cap())
+		}
+	})
+
+	t.Run("negative", func(t *testing.T) {
+		p := newTestPool(t, counter(), WithTestabilityMode(), WithCap(4))
+
+		p.SetMaxSize(-1)
+		if p.Cap() != 4 {
+			t.Fatalf("cap %d after SetMaxSize(-1), want 4", p.Cap())
+		}
+	})
+}
@@ -0,0 +1,116 @@
+package connectpool
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// ClassicPool is a Get/Put façade over a ConnectPool, easing the migration of code written against pools with that
+// API. Connections are tracked by value, so the values handed out must be unique and hashable: pointers, or
+// connection handles of a comparable type.
+type ClassicPool struct {
+	pool  ConnectPool          // Pool the connections are acquired from
+	mutex sync.Mutex           // Protects held
+	held  map[any]AcquiredConn // Handles of the connections handed out by Get and not yet Put
+}
+
+// Classic returns a Get/Put façade over pool.
+func Classic(pool ConnectPool) *ClassicPool {
+	return &ClassicPool{pool: pool, held: make(map[any]AcquiredConn)}
+}
+
+// Get waits for a connection, which must be given back with Put. It fails with ErrConnNotUnique, giving the
+// connection back, if the value cannot be tracked because it is not hashable or is already handed out, or with
+// ErrPoolClosed if the pool is not an Acquirer and its Register failed.
+func (c *ClassicPool) Get() (any, error) {
+	conn, err := c.acquire()
+	if err != nil {
+		return nil, err
+	}
+
+	connect := conn.Conn()
+	if connect == nil || !reflect.TypeOf(connect).Comparable() {
+		_ = conn.Close()
+		return nil, ErrConnNotUnique
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, ok := c.held[connect]; ok {
+		_ = conn.Close()
+		return nil, ErrConnNotUnique
+	}
+
+	c.held[connect] = conn
+	return connect, nil
+}
+
+// acquire acquires a connection from the pool, registering it if the pool is not an Acquirer.
+func (c *ClassicPool) acquire() (AcquiredConn, error) {
+	if acquirer, ok := c.pool.(Acquirer); ok {
+		return acquirer.Acquire(context.Background())
+	}
+
+	connect, cancel := c.pool.Register()
+	if cancel == nil {
+		return nil, ErrPoolClosed
+	}
+
+	return &registeredConn{connect: connect, cancel: cancel}, nil
+}
+
+// Put gives back a connection returned by Get. With a nil err the connection returns to idle; otherwise it is
+// considered broken and closed instead of reused, unless the pool is not an Acquirer, which cannot be asked to. It fails with ErrConnectNotFound if conn was not handed out by
+// Get or was already Put.
+func (c *ClassicPool) Put(conn any, err error) error {
+	if conn == nil || !reflect.TypeOf(conn).Comparable() {
+		return ErrConnectNotFound
+	}
+
+	c.mutex.Lock()
+	handle, ok := c.held[conn]
+	delete(c.held, conn)
+	c.mutex.Unlock()
+
+	if !ok {
+		return ErrConnectNotFound
+	}
+
+	// Never hands the broken connection out again
+	if acquired, ok := handle.(*acquiredConn); ok && err != nil {
+		acquired.connector.Discard()
+	}
+
+	return handle.Close()
+}
+
+// registeredConn is a connection registered from a pool that is not an Acquirer, handed out as an AcquiredConn.
+type registeredConn struct {
+	connect any         // Connection variable handed out
+	cancel  func()      // Gives the connection back
+	closed  atomic.Bool // Whether Close has been called
+}
+
+func (r *registeredConn) Conn() any {
+	return r.connect
+}
+
+func (r *registeredConn) Invalidated() bool {
+	return false
+}
+
+func (r *registeredConn) InvalidationReason() (CloseReason, bool) {
+	return 0, false
+}
+
+func (r *registeredConn) Close() error {
+	if !r.closed.CompareAndSwap(false, true) {
+		return ErrConnectReleased
+	}
+
+	r.cancel()
+	return nil
+}
@@ -0,0 +1,50 @@
+package connectpool
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+)
+
+func TestClassicPool_Put(t *testing.T) {
+	var closed atomic.Int64
+	p := newTestPool(t, func() any { return new(int) }, WithTestabilityMode(), WithCloseMethod(func(any) { closed.Add(1) }))
+	classic := Classic(p)
+
+	conn, err := classic.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A connection given back with an error is broken, so it is closed rather than reused
+	if err = classic.Put(conn, errors.New("broken")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	if closed.Load() != 1 || p.Size() != 0 {
+		t.Fatalf("%d connections closed, size %d after a Put with an error, want 1 and 0", closed.Load(), p.Size())
+	}
+
+	// A double Put is reported and changes nothing
+	if err = classic.Put(conn, nil); !errors.Is(err, ErrConnectNotFound) {
+		t.Fatalf("second Put error = %v, want ErrConnectNotFound", err)
+	}
+
+	// So is a connection the pool never handed out
+	if err = classic.Put(new(int), nil); !errors.Is(err, ErrConnectNotFound) {
+		t.Fatalf("Put of an unknown connection error = %v, want ErrConnectNotFound", err)
+	}
+
+	// A healthy connection returns to idle
+	if conn, err = classic.Get(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = classic.Put(conn, nil); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	if closed.Load() != 1 || p.Size() != 1 || p.WorkingNumber() != 0 {
+		t.Fatalf("%d closed, size %d, %d working after a healthy Put, want 1, 1 and 0", closed.Load(), p.Size(), p.WorkingNumber())
+	}
+}
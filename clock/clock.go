@@ -0,0 +1,131 @@
+// Package clock abstracts the time source of a connection pool, so tests can drive idle eviction with a fake clock.
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock reads the current time and creates timers.
+type Clock interface {
+	Now() time.Time                  // Gets the current time
+	NewTimer(d time.Duration) Timer  // Creates a timer firing once after d
+	Since(t time.Time) time.Duration // Gets the time elapsed since t
+}
+
+// Timer is a single-shot timer created by a Clock.
+type Timer interface {
+	C() <-chan time.Time // Gets the channel receiving the time the timer fired at
+	Stop() bool          // Stops the timer, reporting whether it had not fired yet
+}
+
+// Real returns the Clock backed by the time package. Inside a testing/synctest bubble (Go 1.24+) it already follows
+// the bubble's fake time.
+func Real() Clock {
+	return realClock{}
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+func (realClock) Since(t time.Time) time.Duration {
+	return time.Since(t)
+}
+
+type realTimer struct {
+	timer *time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.timer.C
+}
+
+func (t realTimer) Stop() bool {
+	return t.timer.Stop()
+}
+
+// Fake is a Clock whose time only moves when Advance is called.
+type Fake struct {
+	mutex  sync.Mutex
+	now    time.Time               // Current fake time
+	timers map[*fakeTimer]struct{} // Timers that have not fired or been stopped
+}
+
+// NewFake returns a Fake clock reading now.
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now, timers: make(map[*fakeTimer]struct{})}
+}
+
+func (f *Fake) Now() time.Time {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.now
+}
+
+func (f *Fake) Since(t time.Time) time.Duration {
+	return f.Now().Sub(t)
+}
+
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	t := &fakeTimer{clock: f, deadline: f.now.Add(d), c: make(chan time.Time, 1)}
+	if d <= 0 {
+		t.c <- f.now
+		return t
+	}
+
+	f.timers[t] = struct{}{}
+	return t
+}
+
+// Pending returns the number of timers that have neither fired nor been stopped, so a test can wait for a goroutine
+// to arm its timer before calling Advance.
+func (f *Fake) Pending() int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return len(f.timers)
+}
+
+// Advance moves the fake time forward by d, firing every timer whose deadline is reached.
+func (f *Fake) Advance(d time.Duration) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.now = f.now.Add(d)
+	for t := range f.timers {
+		if !t.deadline.After(f.now) {
+			delete(f.timers, t)
+			t.c <- f.now // Buffered, and each timer fires once
+		}
+	}
+}
+
+type fakeTimer struct {
+	clock    *Fake          // Clock the timer was created by
+	deadline time.Time      // Fake time the timer fires at
+	c        chan time.Time // Receives the fake time the timer fired at
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mutex.Lock()
+	defer t.clock.mutex.Unlock()
+
+	_, pending := t.clock.timers[t]
+	delete(t.clock.timers, t)
+	return pending
+}
@@ -0,0 +1,16 @@
+package connectpool
+
+import "slices"
+
+// CloneWith creates a new pool with the parent's connectMethod and the options the parent was created with, followed
+// by opts, which take precedence. The clone is named after the parent with a "-clone" suffix unless opts name it.
+// Both pools have independent lifecycles, although options holding their own resources, such as a statsd client,
+// are shared by both. It fails with ErrPoolClosed if the parent is closed.
+func (p *connectPool) CloneWith(opts ...Option) (ConnectPool, error) {
+	if p.isClosed() {
+		return nil, ErrPoolClosed
+	}
+
+	options := append(slices.Clip(p.options), WithName(p.name+"-clone"))
+	return NewConnectPool(p.callbacks.Load().connectMethod, append(options, opts...)...), nil
+}
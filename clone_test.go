@@ -0,0 +1,85 @@
+package connectpool
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCloneWith_InheritsAndOverrides(t *testing.T) {
+	var mutex sync.Mutex
+	var closed, panics []any
+	events := make(map[ConnectPool]int)
+
+	parent := newTestPool(t, counter(), WithName("main"), WithCap(5), WithMaxFreeTime(time.Minute),
+		WithCloseMethod(func(connect any) {
+			mutex.Lock()
+			closed = append(closed, connect)
+			mutex.Unlock()
+		}),
+		WithDealPanicMethod(func(panicInfo any) {
+			mutex.Lock()
+			panics = append(panics, panicInfo)
+			mutex.Unlock()
+		}),
+		WithEventHook(func(pool ConnectPool, _ Event) {
+			mutex.Lock()
+			events[pool]++
+			mutex.Unlock()
+		}))
+
+	cloned, err := parent.CloneWith(WithCap(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	clone := cloned.(*connectPool)
+	defer clone.Close()
+
+	// The supplied options take precedence over the inherited ones
+	if clone.Cap() != 2 || clone.MaxFreeTime() != time.Minute || clone.Name() != "main-clone" {
+		t.Fatalf("clone cap %d, maxFreeTime %v, name %q, want 2, 1m0s and main-clone",
+			clone.Cap(), clone.MaxFreeTime(), clone.Name())
+	}
+
+	// The clone dials with the parent's connectMethod and reports to its close method, panic handler and hooks
+	clone.callbacks.handlePanic("boom")
+	_, cancel := parent.Register()
+	defer cancel()
+	connect, cancelClone := clone.Register()
+	cancelClone()
+	clone.Close()
+
+	mutex.Lock()
+	if connect != int64(2) || len(closed) != 1 || closed[0] != connect || len(panics) != 1 || events[clone] == 0 {
+		t.Fatalf("clone dialed %v, closed %v, handled panics %v, reported %d events, "+
+			"want 2 dialed and closed, the panic handled and events reported", connect, closed, panics, events[clone])
+	}
+	mutex.Unlock()
+
+	// Closing the clone leaves the parent running
+	if connect, cancel := parent.Register(); connect == nil {
+		t.Fatal("the parent stopped serving connections when its clone was closed")
+	} else {
+		cancel()
+	}
+
+	named, err := parent.CloneWith(WithName("batch"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer named.Close()
+
+	if name := named.(*connectPool).Name(); name != "batch" {
+		t.Fatalf("clone named %q, want the overriding batch", name)
+	}
+}
+
+func TestCloneWith_ClosedParent(t *testing.T) {
+	parent := newTestPool(t, counter())
+	parent.Close()
+
+	if _, err := parent.CloneWith(); !errors.Is(err, ErrPoolClosed) {
+		t.Fatalf("CloneWith error = %v on a closed pool, want %v", err, ErrPoolClosed)
+	}
+}
@@ -0,0 +1,40 @@
+package connectpool
+
+import "sort"
+
+// CloseOrder is the order in which Close, Flush and ShrinkTo close idle connections.
+type CloseOrder int
+
+const (
+	Unordered   CloseOrder = iota // No particular order, avoiding the cost of sorting
+	NewestFirst                   // Most recently created connections first
+	OldestFirst                   // Least recently created connections first
+)
+
+// closeInOrder runs closeMethod on every connector of idle, in the pool's close order, timing each call for reason.
+// The calls are made sequentially on the calling goroutine so the order is preserved.
+func (p *connectPool) closeInOrder(idle []connector, reason CloseReason) {
+	switch p.closeOrder {
+	case NewestFirst:
+		sort.Slice(idle, func(i, j int) bool { return idle[i].CreatedAt().After(idle[j].CreatedAt()) })
+	case OldestFirst:
+		sort.Slice(idle, func(i, j int) bool { return idle[i].CreatedAt().Before(idle[j].CreatedAt()) })
+	}
+
+	closeMethod := p.closeMethodFor(reason, p.callbacks.Load().closeMethod)
+	for _, c := range idle {
+		c.Do(closeMethod, &p.callbacks)
+	}
+}
+
+// Flush closes every idle connection, in the pool's close order, and returns how many were closed.
+func (p *connectPool) Flush() int {
+	idle := p.pool.TakeIdle()
+	p.closeInOrder(idle, CloseEvicted)
+
+	for range idle {
+		p.emit(EventEvict)
+	}
+
+	return len(idle)
+}
@@ -0,0 +1,51 @@
+package connectpool
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/HuXin0817/ConnectPool/clock"
+)
+
+func TestCloseOrder(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		order CloseOrder
+		want  []any
+	}{
+		{"NewestFirst", NewestFirst, []any{int64(4), int64(3), int64(2), int64(1)}},
+		{"OldestFirst", OldestFirst, []any{int64(1), int64(2), int64(3), int64(4)}},
+	} {
+		for _, shutdown := range []string{"Flush", "Close"} {
+			t.Run(tt.name+"/"+shutdown, func(t *testing.T) {
+				fake := clock.NewFake(time.Unix(0, 0))
+
+				var closed []any
+				p := newTestPool(t, counter(), WithTestabilityMode(), WithClock(fake), WithCloseOrder(tt.order),
+					WithCloseMethod(func(connect any) { closed = append(closed, connect) }))
+
+				// Creates the connections a second apart, held so that each Register dials a new one
+				var cancels []func()
+				for range tt.want {
+					_, cancel := p.Register()
+					cancels = append(cancels, cancel)
+					fake.Advance(time.Second)
+				}
+				for _, cancel := range cancels {
+					cancel()
+				}
+
+				if shutdown == "Flush" {
+					p.Flush()
+				} else {
+					p.Close()
+				}
+
+				if !slices.Equal(closed, tt.want) {
+					t.Fatalf("%s closed %v, want %v", shutdown, closed, tt.want)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package connectpool
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// CloseReason tells why a connection was closed.
+type CloseReason int
+
+const (
+	CloseIdle      CloseReason = iota // Removed by a clear pass for idling too long or exceeding the memory budget
+	CloseDiscarded                    // Released by its holder and not reused
+	CloseUnhealthy                    // Failed a background health check
+	CloseEvicted                      // Removed on demand by Flush, ShrinkTo or EvictByLabel
+	CloseShutdown                     // Closed by Close
+	closeReasonCount
+)
+
+var closeReasonNames = [closeReasonCount]string{
+	CloseIdle:      "idle",
+	CloseDiscarded: "discarded",
+	CloseUnhealthy: "unhealthy",
+	CloseEvicted:   "evicted",
+	CloseShutdown:  "shutdown",
+}
+
+func (r CloseReason) String() string {
+	if r < 0 || int(r) >= len(closeReasonNames) {
+		return "unknown"
+	}
+
+	return closeReasonNames[r]
+}
+
+// closeDurations accumulates the durations of closeMethod calls.
+type closeDurations struct {
+	count atomic.Int64 // Number of calls
+	total atomic.Int64 // Summed duration, in nanoseconds
+	max   atomic.Int64 // Longest duration, in nanoseconds
+}
+
+func (d *closeDurations) observe(duration time.Duration) {
+	d.count.Add(1)
+	d.total.Add(int64(duration))
+
+	for {
+		longest := d.max.Load()
+		if int64(duration) <= longest || d.max.CompareAndSwap(longest, int64(duration)) {
+			return
+		}
+	}
+}
+
+// Average returns the mean duration of a call, 0 if there was none.
+func (d *closeDurations) Average() time.Duration {
+	count := d.count.Load()
+	if count == 0 {
+		return 0
+	}
+
+	return time.Duration(d.total.Load() / count)
+}
+
+// closedByReasonCounts returns the number of connections closed per reason name.
+func (p *connectPool) closedByReasonCounts() map[string]uint64 {
+	counts := make(map[string]uint64, closeReasonCount)
+	for reason := range closeReasonCount {
+		counts[reason.String()] = p.closedByReason[reason].Load()
+	}
+
+	return counts
+}
+
+// recordCloseError counts err returned by a WithCloseMethodE close method.
+func (p *connectPool) recordCloseError(err error, reportToPanicHandler bool) {
+	p.closeErrors.Add(1)
+	p.lastCloseError.Store(&err)
+
+	if reportToPanicHandler {
+		p.handlePanic(err)
+	}
+}
+
+// lastCloseErrorValue returns the last error recorded by recordCloseError, nil if none.
+func (p *connectPool) lastCloseErrorValue() error {
+	if err := p.lastCloseError.Load(); err != nil {
+		return *err
+	}
+
+	return nil
+}
+
+// closeMethodFor wraps closeMethod to count each close made for reason and time each call, reporting those slower
+// than the WithSlowCloseThreshold threshold. A nil closeMethod is only counted.
+func (p *connectPool) closeMethodFor(reason CloseReason, closeMethod func(connect any)) func(connect any) {
+	return func(connect any) {
+		p.closedByReason[reason].Add(1)
+		p.history.record(func(b *Bucket) { b.Evictions[reason]++ })
+
+		if closeMethod == nil {
+			return
+		}
+
+		start := p.clock.Now()
+		panicked := true
+
+		defer func() {
+			p.trackPanicStreak(FatalClosePanics, &p.fatal.closePanics, panicked)
+
+			duration := p.clock.Since(start)
+			p.closeDurations.observe(duration)
+
+			if p.slowClose != nil && p.slowCloseThreshold > 0 && duration >= p.slowCloseThreshold {
+				p.slowClose(duration, reason)
+			}
+		}()
+
+		closeMethod(connect)
+		panicked = false
+	}
+}
@@ -0,0 +1,76 @@
+package connectpool
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSlowClose_CallbackAndStats(t *testing.T) {
+	const slow = 20 * time.Millisecond
+
+	var slowCloses []time.Duration
+	var reasons []CloseReason
+	p := newTestPool(t, counter(), WithTestabilityMode(),
+		WithCloseMethod(func(connect any) {
+			if connect == int64(1) {
+				time.Sleep(slow)
+			}
+		}),
+		WithSlowCloseThreshold(slow/2, func(duration time.Duration, reason CloseReason) {
+			slowCloses = append(slowCloses, duration)
+			reasons = append(reasons, reason)
+		}))
+
+	var cancels []func()
+	for range 3 {
+		_, cancel := p.Register()
+		cancels = append(cancels, cancel)
+	}
+	for _, cancel := range cancels {
+		cancel()
+	}
+	p.Flush()
+
+	// Only the sleeping close is reported, with its duration and reason
+	if len(slowCloses) != 1 || slowCloses[0] < slow || reasons[0] != CloseEvicted {
+		t.Fatalf("slow closes %v for %v, want one of at least %v for %v", slowCloses, reasons, slow, CloseEvicted)
+	}
+
+	stats := p.StatsFresh()
+	if stats.MaxCloseDuration != slowCloses[0] {
+		t.Fatalf("MaxCloseDuration = %v, want the slow close's %v", stats.MaxCloseDuration, slowCloses[0])
+	}
+	if stats.AvgCloseDuration < slow/3 || stats.AvgCloseDuration >= stats.MaxCloseDuration {
+		t.Fatalf("AvgCloseDuration = %v over one close of %v and two fast ones", stats.AvgCloseDuration, slowCloses[0])
+	}
+}
+
+func TestSlowClose_StatsNotBlocked(t *testing.T) {
+	unblock := make(chan struct{})
+	var closing atomic.Bool
+	p := newTestPool(t, counter(), WithCloseMethod(func(any) {
+		closing.Store(true)
+		<-unblock
+	}))
+
+	_, cancel := p.Register()
+	cancel()
+
+	flushed := make(chan struct{})
+	go func() {
+		defer close(flushed)
+		p.Flush()
+	}()
+	waitUntil(t, "the close started", closing.Load)
+
+	// Stats is served while the close method is still running
+	start := time.Now()
+	p.StatsFresh()
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Fatalf("StatsFresh took %v during a slow close", elapsed)
+	}
+
+	close(unblock)
+	<-flushed
+}
@@ -0,0 +1,49 @@
+package connectpool
+
+import (
+	"context"
+	"sync"
+)
+
+// concurrencyLimits holds the semaphores of LimitConcurrency, one per limit.
+type concurrencyLimits struct {
+	mutex      sync.Mutex
+	semaphores map[int]chan struct{} // Semaphores by maxConcurrent, created on first use
+}
+
+// semaphore returns the semaphore shared by the callers limited to maxConcurrent.
+func (l *concurrencyLimits) semaphore(maxConcurrent int) chan struct{} {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	semaphore, ok := l.semaphores[maxConcurrent]
+	if !ok {
+		if l.semaphores == nil {
+			l.semaphores = make(map[int]chan struct{})
+		}
+
+		semaphore = make(chan struct{}, maxConcurrent)
+		l.semaphores[maxConcurrent] = semaphore
+	}
+
+	return semaphore
+}
+
+// LimitConcurrency calls fn with a pooled connection, queuing the caller until fewer than maxConcurrent calls are in
+// flight. Callers passing the same maxConcurrent share the limit, which is separate from the pool's cap; a
+// non-positive maxConcurrent does not limit the call. It returns the error of acquiring the connection, or else fn's.
+func (p *connectPool) LimitConcurrency(fn func(any) error, maxConcurrent int) error {
+	if maxConcurrent > 0 {
+		semaphore := p.concurrency.semaphore(maxConcurrent)
+		semaphore <- struct{}{}
+		defer func() { <-semaphore }()
+	}
+
+	conn, err := p.Acquire(context.Background())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return fn(conn.Conn())
+}
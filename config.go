@@ -0,0 +1,45 @@
+package connectpool
+
+import "time"
+
+// PoolConfig is the pool's current scalar configuration, serializable to JSON so that it can be persisted,
+// compared between pools or diffed during a reload. Callback options are not part of it.
+type PoolConfig struct {
+	Name                string        `json:"name"`
+	MaxSize             int           `json:"maxSize"`
+	MinSize             int           `json:"minSize"`
+	MaxIdleBytes        int64         `json:"maxIdleBytes"`
+	MaxFreeTime         time.Duration `json:"maxFreeTime"`
+	AutoClearInterval   time.Duration `json:"autoClearInterval"`
+	HealthCheckFraction float64       `json:"healthCheckFraction"`
+	HealthCheckInterval time.Duration `json:"healthCheckInterval"`
+	DemoteAfter         time.Duration `json:"demoteAfter"`
+	DemoteInterval      time.Duration `json:"demoteInterval"`
+	CreationWindow      time.Duration `json:"creationWindow"`
+	CallbackWorkers     int           `json:"callbackWorkers"`
+	DrainTimeout        time.Duration `json:"drainTimeout"`
+	CloseOrder          CloseOrder    `json:"closeOrder"`
+	ConnectTimeout      time.Duration `json:"connectTimeout"`
+}
+
+func (p *connectPool) ExportConfig() PoolConfig {
+	config := p.config.Load() // Loaded once, so the runtime settings are consistent
+
+	return PoolConfig{
+		Name:                p.Name(),
+		MaxSize:             config.maxSize,
+		MinSize:             config.minSize,
+		MaxIdleBytes:        p.maxIdleBytes,
+		MaxFreeTime:         config.maxFreeTime,
+		AutoClearInterval:   config.autoClearInterval,
+		HealthCheckFraction: p.healthCheckFraction,
+		HealthCheckInterval: p.healthCheckInterval,
+		DemoteAfter:         p.demoteAfter,
+		DemoteInterval:      p.demoteInterval,
+		CreationWindow:      p.creations.window,
+		CallbackWorkers:     p.callbackWorkers,
+		DrainTimeout:        p.drainTimeout,
+		CloseOrder:          p.closeOrder,
+		ConnectTimeout:      p.callbacks.Load().connectTimeout,
+	}
+}
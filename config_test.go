@@ -0,0 +1,39 @@
+package connectpool
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestExportConfig_JSONRoundTrip(t *testing.T) {
+	p := newTestPool(t, counter(), WithName("orders"), WithCap(7), WithMinSize(2), WithMaxIdleBytes(1<<20),
+		WithMaxFreeTime(3*time.Minute), WithAutoClearInterval(20*time.Second), WithSampledHealthCheck(0.25, time.Minute),
+		WithIdleSegmentation(time.Minute, 10*time.Second), WithCreationWindow(5*time.Minute), WithCallbackWorkers(3),
+		WithCallbackDrainTimeout(2*time.Second), WithCloseOrder(NewestFirst), WithConnectMethodTimeout(time.Second))
+
+	exported := p.ExportConfig()
+
+	// Every field is set, so one the JSON dropped could not pass for its zero value
+	fields := reflect.ValueOf(exported)
+	for i := range fields.NumField() {
+		if fields.Field(i).IsZero() {
+			t.Fatalf("ExportConfig().%s is zero, want the configured value", fields.Type().Field(i).Name)
+		}
+	}
+
+	data, err := json.Marshal(exported)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded PoolConfig
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded != exported {
+		t.Fatalf("ExportConfig round-tripped through %s as %+v, want %+v", data, decoded, exported)
+	}
+}
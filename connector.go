@@ -1,109 +1,500 @@
 package connectpool
 
 import (
+	"fmt"
+	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/HuXin0817/ConnectPool/clock"
 )
 
 type connector interface {
-	GetConnect() any                             // Get the Connector's connection variable
-	SinceLastWorkingTime() time.Duration         // Get the time since the Connector last worked
-	IsFree() bool                                // Determine if the Connector is free
-	StartWorking()                               // Begin working
-	StopWorking()                                // End working
-	StartTimingWork(time.Duration)               // Start working for a specified duration
-	Do(f *func(any), dealPanicMethod *func(any)) // Invoke an external method and handle any potential Panic
+	GetConnect() any                                      // Get the Connector's connection variable
+	Err() error                                           // Get the error recorded while creating the connection variable, if any
+	SinceLastWorkingTime() time.Duration                  // Get the time since the Connector last worked
+	LastWorkingTime() time.Time                           // Get the time the Connector last worked
+	StartWorkingTime() time.Time                          // Get the time the Connector last started working
+	HoldDuration() time.Duration                          // Get the time since the Connector started working, 0 if it is not working
+	CreatedAt() time.Time                                 // Get the time the Connector was created
+	Age() time.Duration                                   // Get the time since the Connector was created
+	IsExpired(maxLifetime time.Duration) bool             // Determine if the Connector has outlived maxLifetime
+	IsFree() bool                                         // Determine if the Connector is free
+	StartWorking()                                        // Begin working
+	TryStartWorking() bool                                // Begin working unless the Connector is already working or pinned, reporting whether it did
+	StopWorking()                                         // End working
+	StartTimingWork(time.Duration)                        // Start working for a specified duration
+	SetWorkingTimeout(d time.Duration) CancelFunc         // Have the clear pass evict the Connector if it is still working after d, without a goroutine
+	WorkingTimedOut() bool                                // Determine if the Connector is working past its working timeout
+	Do(f func(any), callbacks *callbackBundle)            // Invoke an external method and handle any potential Panic
+	SetPermanentlyWorking(bool)                           // Pin or unpin the Connector as permanently working
+	IsPermanentlyWorking() bool                           // Determine if the Connector is pinned as permanently working
+	Discard()                                             // Mark the Connector as closed by its user, so it is never reused
+	IsDiscarded() bool                                    // Determine if the Connector was marked as closed by its user
+	Invalidate(reason CloseReason)                        // Discard the Connector because the pool reclaimed it while it was in use
+	Retire(reason CloseReason)                            // End the current hand-out for good, its release and deadline no longer acting on the Connector
+	Retired() bool                                        // Determine if Retire ended the Connector's hand-out
+	Invalidation() (reason CloseReason, invalidated bool) // Get why the pool reclaimed the Connector, if it did
+	ReleaseUnused()                                       // Give back a newly created Connector without counting it as used, starting its idle clock now
+	NeverUsed() bool                                      // Determine if no holder has released the Connector yet
+	RestoreIdle()                                         // Give back a Connector claimed by the pool itself without counting it as work
+	SetHealthCheckPass(uint64)                            // Record the health check pass that last checked the Connector
+	HealthCheckPass() uint64                              // Get the health check pass that last checked the Connector, 0 if never checked
+	Reset() error                                         // Reset the connection variable's protocol state with the configured resetMethod
+	SetMemorySize(int64)                                  // Record the memory held by the connection variable
+	MemorySize() int64                                    // Get the recorded memory held by the connection variable
+	SetLabel(string)                                      // Record the label identifying the connection's backend
+	Label() string                                        // Get the recorded label, empty if none
+	SetID(uint64)                                         // Record the Token the Connector is stored under
+	ID() uint64                                           // Get the Token the Connector is stored under, 0 if it is in no set
+	Generation() uint64                                   // Get the number of times the Connector's shell was recycled, changing with each reuse
+	WorkID() uint64                                       // Get the number identifying the Connector's current or last work
+	KeepShell()                                           // Keep the Connector's shell from being recycled, as a holder keeps referencing it
+	WorkEnded(work uint64) bool                           // Determine if work was ended by its deadline or followed by another work
+	ReleaseCause() (cause ReleaseCause, released bool)    // Get why the Connector last stopped working, if it did
+	SetCold(bool)                                         // Move the Connector between the hot and cold idle segments
+	IsCold() bool                                         // Determine if the Connector was demoted to the cold idle segment
+	Clone() (connector, error)                            // Create a new Connector with the same connectMethod, leaving this one unaffected
 }
 
 type atomicConnector struct {
-	connect         any           // Connection variable
-	isWorking       atomic.Bool   // Working state
-	lastWorkingTime atomic.Value  // Last work time, stored as time.Time
-	waitCloseState  atomic.Bool   // State of waiting to automatically stop working
-	stopSignalChan  chan struct{} // Channel for transmitting work stop signals
+	connect            any                                                       // Connection variable
+	err                error                                                     // Error recorded while creating the connection variable
+	callbacks          *callbackBundle                                           // Methods that created the connection variable, reset it and handle panics
+	onStop             func(c connector, cause ReleaseCause, hold time.Duration) // Invoked with the cause and hold time whenever the connector stops working, may be nil
+	clock              clock.Clock                                               // Time source of the connector's timestamps and timers
+	used               atomic.Bool                                               // Whether a holder has released the connector at least once
+	isWorking          atomic.Bool                                               // Working state
+	permanentlyWorking atomic.Bool                                               // Pinned state, hiding the connector from reuse and cleanup
+	invalidation       atomic.Int32                                              // Reason the pool reclaimed the connector plus one, 0 if it did not
+	discarded          atomic.Bool                                               // Whether the connection variable was closed by its user and must not be reused
+	lastWorkingTime    atomic.Int64                                              // Last work time, stored as nanoseconds since createdAt so that storing it does not box a time.Time
+	startWorkingTime   atomic.Int64                                              // Time the current or last work began, stored as nanoseconds since createdAt
+	createdAt          atomic.Value                                              // Creation time, stored as time.Time
+	memorySize         atomic.Int64                                              // Memory held by the connection variable, in bytes
+	cold               atomic.Bool                                               // Whether the connector was demoted to the cold idle segment
+	label              atomic.Value                                              // Label identifying the connection's backend, stored as string
+	id                 atomic.Uint64                                             // Token the connector is stored under in its set, 0 if it is in none
+	generation         atomic.Uint64                                             // Number of times the connector's shell was recycled, kept across reuses
+	healthCheckPass    atomic.Uint64                                             // Health check pass that last checked the connector
+	session            atomic.Pointer[timingSession]                             // Current timed work session, nil when not timing work
+	keepShell          atomic.Bool                                               // Whether a holder may reference the connector after its work ended, so its shell is never recycled
+	works              atomic.Uint64                                             // Number of times the connector started working, identifying each work
+	expiredWork        atomic.Uint64                                             // Work ended by its deadline rather than its holder, 0 if none
+	releaseCause       atomic.Int32                                              // Why the connector last stopped working after a hand-out, 0 if it never did
+	workingDeadline    atomic.Int64                                              // Time after which the clear pass evicts the working connector, in Unix nanoseconds, 0 if none
+	retired            atomic.Bool                                               // Whether Retire ended the hand-out, the pool having closed the connection
 }
 
 // newConnector creates a new connector with connect as the connection variable
-func newConnector(connectMethod *func() any, dealPanicMethod *func(any)) connector {
+func newConnector(callbacks *callbackBundle, clock clock.Clock, onStop func(c connector, cause ReleaseCause, hold time.Duration)) connector {
 
-	c := &atomicConnector{
-		stopSignalChan: make(chan struct{}, 1), // Allocate a buffer of length 1 for stopSignalChan
-	}
-
-	c.updateLastWorkingTime() // Update the working time to the most recent
+	c := newShell(callbacks, clock, onStop)
 
 	func() {
 		defer func() {
 			// If dealPanicMethod is not nil, invoke dealPanicMethod to handle any possible panic
-			if r := recover(); r != nil && dealPanicMethod != nil && *dealPanicMethod != nil {
-				(*dealPanicMethod)(r)
+			if r := recover(); r != nil {
+				c.err = fmt.Errorf("%w: %v", ErrConnectPanicked, r) // Record the failure for callers creating connectors explicitly
+
+				callbacks.handlePanic(r)
 			}
 		}()
 
 		// If the connection strategy is nil, abandon this connection attempt
-		if connectMethod == nil || *connectMethod == nil {
+		connectMethod := callbacks.Load().connectMethod
+		if connectMethod == nil {
 			return
 		}
 
 		// Store the connection variable in c.connect
-		c.connect = (*connectMethod)()
+		if timeout := callbacks.Load().connectTimeout; timeout > 0 {
+			c.connect, c.err = connectWithTimeout(connectMethod, timeout, callbacks, clock)
+			return
+		}
+
+		c.connect = connectMethod()
 	}()
 
 	return c
 }
 
+// adoptConnector creates a connector holding connect, an existing connection, without calling connectMethod
+func adoptConnector(connect any, callbacks *callbackBundle, clock clock.Clock, onStop func(c connector, cause ReleaseCause, hold time.Duration)) connector {
+	c := newShell(callbacks, clock, onStop)
+	c.connect = connect
+	return c
+}
+
+// newShell takes a connector without connection from connectorShells, created now
+func newShell(callbacks *callbackBundle, clock clock.Clock, onStop func(c connector, cause ReleaseCause, hold time.Duration)) *atomicConnector {
+	c := connectorShells.Get().(*atomicConnector) // A recycled shell was cleared by recycle
+	c.onStop = onStop
+	c.clock = clock
+	c.callbacks = callbacks
+
+	c.createdAt.Store(c.clock.Now())
+	c.startWorkingTime.Store(0)
+	c.updateLastWorkingTime() // Update the working time to the most recent
+	return c
+}
+
+// connectWithTimeout runs connectMethod in a goroutine, giving up with ErrConnectTimeout, also reported to the panic
+// handler, if it does not return within timeout. A connection returned after the timeout is closed with closeMethod.
+func connectWithTimeout(connectMethod func() any, timeout time.Duration, callbacks *callbackBundle, clock clock.Clock) (any, error) {
+	type result struct {
+		connect   any // Connection variable returned by connectMethod
+		panicInfo any // Value connectMethod panicked with, nil if it returned
+	}
+
+	done := make(chan result, 1) // Buffered so that a late connectMethod never blocks
+	go func() {
+		var r result
+		defer func() { done <- r }()
+		defer func() { r.panicInfo = recover() }()
+
+		r.connect = connectMethod()
+	}()
+
+	timer := clock.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case r := <-done:
+		if r.panicInfo != nil {
+			panic(r.panicInfo) // Handled like a panic of a connectMethod run inline
+		}
+
+		return r.connect, nil
+
+	case <-timer.C():
+		go func() {
+			if r := <-done; r.connect != nil {
+				if closeMethod := callbacks.Load().closeMethod; closeMethod != nil {
+					closeMethod(r.connect)
+				}
+			}
+		}()
+
+		callbacks.handlePanic(ErrConnectTimeout)
+		return nil, ErrConnectTimeout
+	}
+}
+
 func (c *atomicConnector) GetConnect() any {
 	return c.connect
 }
 
+func (c *atomicConnector) Err() error {
+	return c.err
+}
+
 func (c *atomicConnector) StartWorking() {
+	c.works.Add(1)
+	c.startWorkingTime.Store(c.sinceCreation())
 	c.isWorking.Store(true)
+	c.cold.Store(false) // A reused connector is promoted back to the hot segment
+}
+
+func (c *atomicConnector) TryStartWorking() bool {
+	if c.permanentlyWorking.Load() || !c.isWorking.CompareAndSwap(false, true) {
+		return false
+	}
+
+	c.works.Add(1)
+	c.startWorkingTime.Store(c.sinceCreation())
+	c.cold.Store(false)
+	return true
 }
 
 func (c *atomicConnector) StopWorking() {
-	c.isWorking.Store(false)  // Update the working state
+	c.used.Store(true)
 	c.updateLastWorkingTime() // Update the last working time
 
-	// If in waitCloseState, send an end signal to stopSignalChan
-	if c.waitCloseState.Load() {
-		c.stopSignalChan <- struct{}{}
+	// If timing work, end the session so that its timer goroutine exits without ending work again
+	if session := c.session.Swap(nil); session != nil {
+		session.stop()
+	}
+
+	c.free(ReleaseCallerCancelled)
+}
+
+// free drops the working timeout and marks the connector free, then reports that it stopped working for cause. Its
+// fields are only read before it is marked free, as a clear pass may recycle the shell from then on
+func (c *atomicConnector) free(cause ReleaseCause) {
+	c.workingDeadline.Store(0)
+	hold := time.Duration(c.lastWorkingTime.Load() - c.startWorkingTime.Load())
+
+	if cause != releaseByPool {
+		c.releaseCause.Store(int32(cause))
+	}
+
+	onStop := c.onStop
+	c.isWorking.Store(false) // Update the working state
+
+	if onStop != nil {
+		onStop(c, cause, hold)
 	}
 }
 
 // updateLastWorkingTime updates the working time to the most recent
 func (c *atomicConnector) updateLastWorkingTime() {
-	c.lastWorkingTime.Store(time.Now())
+	c.lastWorkingTime.Store(c.sinceCreation())
+}
+
+// sinceCreation returns the time elapsed since the connector was created, in nanoseconds
+func (c *atomicConnector) sinceCreation() int64 {
+	return int64(c.clock.Now().Sub(c.CreatedAt()))
+}
+
+// timingSession is a single period of timed work, ended once by whichever comes first of its deadline and StopWorking
+type timingSession struct {
+	done chan struct{} // Closed when the session is stopped
+	once sync.Once     // Guards the closing of done
+}
+
+// stop ends the session; it is idempotent
+func (s *timingSession) stop() {
+	s.once.Do(func() { close(s.done) })
 }
 
 // endTimingWork ends TimingWork
 func (c *atomicConnector) endTimingWork() {
-	c.waitCloseState.Store(false) // End the connector's waitCloseState
-	c.isWorking.Store(false)
+	c.expiredWork.Store(c.works.Load()) // Lets the holder's late release tell its work already ended
+	c.used.Store(true)
 	c.updateLastWorkingTime()
+	c.free(ReleaseDeadlineExpired)
 }
 
 func (c *atomicConnector) StartTimingWork(deadline time.Duration) {
-	// Start a new goroutine, asynchronously wait and end work
-	go func() {
-		c.waitCloseState.Store(true) // Make the connector enter waitCloseState
+	// Start a fresh session, replacing any previous one
+	session := &timingSession{done: make(chan struct{})}
+	if previous := c.session.Swap(session); previous != nil {
+		previous.stop()
+	}
 
-		c.StartWorking()
+	c.KeepShell() // Its holder may outlive the work
 
-		timer := time.NewTimer(deadline) // Set a timer with a deadline duration
+	c.StartWorking()
+
+	// Armed before the goroutine starts, so the deadline counts from the start of the work
+	timer := c.clock.NewTimer(deadline)
+
+	// Start a new goroutine, asynchronously wait and end work
+	go func() {
+		defer timer.Stop()
 
 		// Exit TimingWork upon meeting one of the conditions
 		select {
-		case <-timer.C: // Time reached the deadline
-			c.endTimingWork()
+		case <-timer.C(): // Time reached the deadline
+			// Ends work unless StopWorking or a newer session took over meanwhile
+			if c.session.CompareAndSwap(session, nil) {
+				session.stop()
+				c.endTimingWork()
+			}
 
-		case <-c.stopSignalChan: // External force actively ended TimingWork
-			c.endTimingWork()
+		case <-session.done: // External force actively ended TimingWork
 		}
 	}()
 }
 
+// SetWorkingTimeout has the clear pass evict the connector if it is still working after d. Unlike StartTimingWork it
+// starts no goroutine, so the timeout is only enforced with the granularity of the autoClear interval.
+func (c *atomicConnector) SetWorkingTimeout(d time.Duration) CancelFunc {
+	deadline := c.clock.Now().Add(d).UnixNano()
+	c.workingDeadline.Store(deadline)
+
+	return func() { c.workingDeadline.CompareAndSwap(deadline, 0) }
+}
+
+func (c *atomicConnector) WorkingTimedOut() bool {
+	deadline := c.workingDeadline.Load()
+	return deadline != 0 && !c.IsFree() && c.clock.Now().UnixNano() > deadline
+}
+
 func (c *atomicConnector) IsFree() bool {
-	return !c.isWorking.Load()
+	return !c.isWorking.Load() && !c.permanentlyWorking.Load()
+}
+
+func (c *atomicConnector) SetPermanentlyWorking(permanentlyWorking bool) {
+	// When unpinned, restart the idle clock so the connector is not evicted immediately
+	if c.permanentlyWorking.Swap(permanentlyWorking) && !permanentlyWorking {
+		c.updateLastWorkingTime()
+	}
+}
+
+func (c *atomicConnector) IsPermanentlyWorking() bool {
+	return c.permanentlyWorking.Load()
+}
+
+func (c *atomicConnector) CreatedAt() time.Time {
+	return c.createdAt.Load().(time.Time)
+}
+
+func (c *atomicConnector) Age() time.Duration {
+	return c.clock.Since(c.CreatedAt())
+}
+
+// IsExpired reports whether the connector is older than maxLifetime; a non-positive maxLifetime never expires.
+func (c *atomicConnector) IsExpired(maxLifetime time.Duration) bool {
+	return maxLifetime > 0 && c.Age() > maxLifetime
+}
+
+func (c *atomicConnector) Reset() (err error) {
+	// If the reset strategy is nil, the connection cannot be reset
+	resetMethod := c.callbacks.Load().resetMethod
+	if resetMethod == nil {
+		return ErrNoResetMethod
+	}
+
+	defer func() {
+		// A panicking resetMethod counts as a failed reset
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", ErrResetPanicked, r)
+
+			c.callbacks.handlePanic(r)
+		}
+	}()
+
+	return resetMethod(c.connect)
+}
+
+func (c *atomicConnector) ReleaseUnused() {
+	c.updateLastWorkingTime()
+	c.free(releaseByPool)
+}
+
+func (c *atomicConnector) NeverUsed() bool {
+	return !c.used.Load()
+}
+
+// RestoreIdle gives back a connector claimed by the pool itself, without counting the claim as work.
+func (c *atomicConnector) RestoreIdle() {
+	c.free(releaseByPool)
+}
+
+func (c *atomicConnector) SetHealthCheckPass(pass uint64) {
+	c.healthCheckPass.Store(pass)
+}
+
+func (c *atomicConnector) HealthCheckPass() uint64 {
+	return c.healthCheckPass.Load()
+}
+
+func (c *atomicConnector) Discard() {
+	c.discarded.Store(true)
+}
+
+func (c *atomicConnector) Invalidate(reason CloseReason) {
+	c.invalidation.CompareAndSwap(0, int32(reason)+1) // Keeps the first reason
+	c.Discard()
+}
+
+// Retire records reason as Invalidate does, without marking the connector for its holder's release to close, so that
+// the release leaves it alone, and drops its timed work
+func (c *atomicConnector) Retire(reason CloseReason) {
+	c.invalidation.CompareAndSwap(0, int32(reason)+1) // Keeps the first reason
+	c.retired.Store(true)
+
+	if session := c.session.Swap(nil); session != nil {
+		session.stop()
+	}
+}
+
+func (c *atomicConnector) Retired() bool {
+	return c.retired.Load()
+}
+
+func (c *atomicConnector) Invalidation() (reason CloseReason, invalidated bool) {
+	stored := c.invalidation.Load()
+	return CloseReason(stored - 1), stored != 0
+}
+
+func (c *atomicConnector) IsDiscarded() bool {
+	return c.discarded.Load()
+}
+
+func (c *atomicConnector) SetMemorySize(size int64) {
+	c.memorySize.Store(size)
+}
+
+func (c *atomicConnector) MemorySize() int64 {
+	return c.memorySize.Load()
+}
+
+// Clone creates a new connector by calling connectMethod again, returning the error recorded while creating it.
+func (c *atomicConnector) Clone() (connector, error) {
+	clone := newConnector(c.callbacks, c.clock, c.onStop)
+	clone.SetLabel(c.Label())
+	return clone, clone.Err()
+}
+
+func (c *atomicConnector) SetCold(cold bool) {
+	c.cold.Store(cold)
+}
+
+func (c *atomicConnector) IsCold() bool {
+	return c.cold.Load()
+}
+
+// recyclable reports whether the connector's shell may be recycled once removed and closed
+func (c *atomicConnector) recyclable() bool {
+	return !c.keepShell.Load()
+}
+
+func (c *atomicConnector) KeepShell() {
+	c.keepShell.Store(true)
+}
+
+func (c *atomicConnector) WorkID() uint64 {
+	return c.works.Load()
+}
+
+func (c *atomicConnector) WorkEnded(work uint64) bool {
+	return c.works.Load() != work || c.expiredWork.Load() == work
+}
+
+func (c *atomicConnector) ReleaseCause() (cause ReleaseCause, released bool) {
+	cause = ReleaseCause(c.releaseCause.Load())
+	return cause, cause != 0
+}
+
+func (c *atomicConnector) Generation() uint64 {
+	return c.generation.Load()
+}
+
+func (c *atomicConnector) SetID(id uint64) {
+	c.id.Store(id)
+}
+
+func (c *atomicConnector) ID() uint64 {
+	return c.id.Load()
+}
+
+func (c *atomicConnector) SetLabel(label string) {
+	c.label.Store(label)
+}
+
+func (c *atomicConnector) Label() string {
+	label, _ := c.label.Load().(string)
+	return label
+}
+
+func (c *atomicConnector) LastWorkingTime() time.Time {
+	return c.CreatedAt().Add(time.Duration(c.lastWorkingTime.Load()))
+}
+
+func (c *atomicConnector) StartWorkingTime() time.Time {
+	return c.CreatedAt().Add(time.Duration(c.startWorkingTime.Load()))
+}
+
+func (c *atomicConnector) HoldDuration() time.Duration {
+	// If the connector is not working, it is not held
+	if !c.isWorking.Load() {
+		return 0
+	}
+
+	return c.clock.Since(c.StartWorkingTime())
 }
 
 func (c *atomicConnector) SinceLastWorkingTime() time.Duration {
@@ -112,22 +503,21 @@ func (c *atomicConnector) SinceLastWorkingTime() time.Duration {
 		return 0
 	}
 
-	t := c.lastWorkingTime.Load().(time.Time)
-	return time.Since(t)
+	return c.clock.Since(c.LastWorkingTime())
 }
 
-func (c *atomicConnector) Do(f *func(any), dealPanicMethod *func(any)) {
+func (c *atomicConnector) Do(f func(any), callbacks *callbackBundle) {
 	defer func() {
 		// Handle any panic that occurs during work
-		if r := recover(); r != nil && dealPanicMethod != nil && *dealPanicMethod != nil {
-			(*dealPanicMethod)(r)
+		if r := recover(); r != nil {
+			callbacks.handlePanic(r)
 		}
 	}()
 
 	// If the function is nil, abandon executing it
-	if f == nil || *f == nil {
+	if f == nil {
 		return
 	}
 
-	(*f)(c.connect)
+	f(c.connect)
 }
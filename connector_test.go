@@ -0,0 +1,77 @@
+package connectpool
+
+import (
+	"math/rand"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/HuXin0817/ConnectPool/clock"
+)
+
+// settle waits, yielding rather than sleeping, until cond holds, failing after a second.
+func settle(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	for deadline := time.Now().Add(time.Second); !cond(); runtime.Gosched() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting until %s", what)
+		}
+	}
+}
+
+func TestTimingSession_RandomStartStopExpiry(t *testing.T) {
+	const seed, steps = 694, 2000
+
+	fake := clock.NewFake(time.Unix(0, 0))
+	var bundle callbackBundle
+	bundle.update(func(next *callbacks) { next.connectMethod = func() any { return 1 } })
+
+	c := newConnector(&bundle, fake, nil).(*atomicConnector)
+	c.ReleaseUnused()
+
+	random := rand.New(rand.NewSource(seed))
+	var sessions []*timingSession
+	var expires time.Time // Fake time the live session expires at, zero if none
+	working := false
+
+	for step := 0; step < steps; step++ {
+		switch op := random.Intn(3); {
+		case op == 0:
+			deadline := time.Duration(1+random.Intn(3)) * time.Minute
+			c.StartTimingWork(deadline)
+			sessions = append(sessions, c.session.Load())
+			expires, working = fake.Now().Add(deadline), true
+
+			// The session replaced, if any, stops its timer before the clock moves
+			settle(t, "the replaced timer was stopped", func() bool { return fake.Pending() == 1 })
+
+		case op == 1 && working:
+			c.StopWorking()
+			expires, working = time.Time{}, false
+
+		default:
+			fake.Advance(time.Duration(random.Intn(90)) * time.Second)
+			if working && !fake.Now().Before(expires) {
+				expires, working = time.Time{}, false
+			}
+		}
+
+		settle(t, "the connector reached the expected state", func() bool { return c.IsFree() != working })
+	}
+
+	if working {
+		c.StopWorking()
+	}
+
+	// Every session ended exactly once: a second close of its done channel would have panicked
+	for i, session := range sessions {
+		select {
+		case <-session.done:
+		default:
+			t.Fatalf("session %d of %d never ended", i, len(sessions))
+		}
+	}
+
+	settle(t, "every session's timer was stopped", func() bool { return fake.Pending() == 0 })
+}
@@ -1,107 +1,441 @@
 package connectpool
 
 import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"slices"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/HuXin0817/ConnectPool/clock"
 )
 
+// globalToken is the Token counter shared by the Sets created with global IDs, so their Tokens never overlap.
+var globalToken atomic.Uint64
+
+// connectorSet stores the pool's Connectors. Once closed, AddConnector and GetFreeConnector return nil,
+// Clear and ClearNow are no-ops returning 0, and Size returns 0.
 type connectorSet interface {
-	AddConnector(connectMethod *func() any, dealPanicMethod *func(panicInfo any)) (newConnector connector)       // Adds a new Connector
-	GetFreeConnector() connector                                                                                 // Retrieves a free Connector
-	Size() int                                                                                                   // Returns the size of the connector set
-	WorkingNumber() int64                                                                                        // Returns the count of the Working Connector
-	Close()                                                                                                      // Closes the ConnectorSet, terminating the Set's AutoClear
-	Clear(maxFreeTime *time.Duration, closeMethod *func(any), dealPanicMethod *func(any))                        // Actively performs a cleanup
-	autoClear(autoClearInterval, maxFreeTime *time.Duration, closeMethod *func(any), dealPanicMethod *func(any)) // Asynchronously performs the auto-cleanup function
+	AddConnector() (newConnector connector)                                      // Adds a new Connector
+	AdoptConnector(connect any) (newConnector connector)                         // Adds a Connector holding connect, an existing connection
+	GetFreeConnector() connector                                                 // Retrieves a free Connector
+	GetAffineConnector(key uint64) (c connector, busy bool)                      // Marks the Connector key hashes onto as working and returns it, or reports whether it is busy
+	Demote(threshold time.Duration) (demoted int)                                // Moves the Connectors idle for longer than threshold to the cold segment
+	FindConnector(connect any) connector                                         // Retrieves the Connector holding connect, or nil
+	GetConnector(token uint64) connector                                         // Retrieves the Connector stored under token, or nil
+	Contains(c connector) bool                                                   // Determines whether c is in the set
+	RemoveConnector(c connector, closeMethod func(any)) bool                     // Removes c from the set and closes it, reporting whether it was present
+	ClaimIdleSample(fraction float64, eligible func(connector) bool) []connector // Claims a random sample of the free Connectors accepted by eligible
+	ForceEvict(n int) (idle []connector, evicted int)                            // Removes up to n Connectors regardless of the idle policies, returning the free ones to close
+	TakeIdle() (idle []connector)                                                // Removes every free Connector, returning them to close
+	TakeAll() (all []connector)                                                  // Removes every Connector, retiring the working ones, returning them all to close
+	RemoveMatching(match func(connector) bool) (idle []connector, evicted int)   // Removes every unpinned Connector matched, returning the idle ones to be closed
+	Size() int                                                                   // Returns the size of the connector set
+	WorkingNumber() int64                                                        // Returns the count of the Working Connector
+	CountByState(maxFreeTime time.Duration) map[string]int                       // Counts the Connectors by state
+	FreeConnects() []any                                                         // Retrieves the connection variables of the free Connectors
+	IdleByLabel() map[string]int                                                 // Counts the free Connectors per label
+	ForEachWorking(fn func(connector) bool)                                      // Calls fn for each working Connector under the read lock until fn returns false
+	IdleBytes() int64                                                            // Returns the summed memory size of the free Connectors
+	Validate() []error                                                           // Checks the Set's internal invariants, returning one error per violation
+	CheckInvariants() []error                                                    // Cheaply checks the Set's invariants under a single lock, for checks after every state transition
+	Snapshot() []ConnectorSnapshot                                               // Describes every Connector in the set
+	Contention() map[string]LockContention                                       // Returns the write lock waits per site, nil without the connectpool_debugstats build tag
+	Close()                                                                      // Closes the ConnectorSet, terminating the Set's AutoClear
+	Clear(maxFreeTime *time.Duration) (evicted int)                              // Actively performs a cleanup, returning the number of removed Connectors
+	ClearNow() int                                                               // Interrupts the auto-cleanup wait to clean up immediately, returning the number of removed Connectors
+	PauseAutoClear()                                                             // Stops the automatic cleanups until ResumeAutoClear, ClearNow still cleaning up
+	ResumeAutoClear()                                                            // Restarts the automatic cleanups stopped by PauseAutoClear
+	RearmAutoClear()                                                             // Restarts the wait for the next automatic cleanup with the current interval
+	autoClear()                                                                  // Asynchronously performs the auto-cleanup function
+}
+
+// connectorSetOptions are what a connectorSet is created with.
+type connectorSetOptions struct {
+	globalIDs     bool                                                      // Whether Tokens are drawn from the counter shared by every Set with global IDs
+	maxIdleBytes  *int64                                                    // Budget for the summed memory size of free Connectors, 0 means unlimited
+	callbacks     *callbackBundle                                           // Creates the Connectors, closes the idle ones and handles panics
+	unusedGrace   time.Duration                                             // Extra idle time granted to Connectors never used yet
+	segmented     bool                                                      // Whether free Connectors are handed out hot segment first, most recently released first
+	deterministic bool                                                      // Whether Connectors are visited in Token order rather than map order
+	recycle       bool                                                      // Whether the shells of the Connectors closed by the clear passes are recycled
+	manual        bool                                                      // Whether clear passes only run on ClearNow, on the caller's goroutine, no autoClear goroutine running
+	config        *configBundle                                             // maxFreeTime and autoClearInterval of the clear passes, the defaults if nil
+	random        *rand.Rand                                                // Draws the health check samples, used under the write lock only
+	keyHasher     func(uint64) uint64                                       // Spreads the Tokens drawn from the counter, identity if nil
+	clock         clock.Clock                                               // Time source of the clear passes
+	onStop        func(c connector, cause ReleaseCause, hold time.Duration) // Invoked with the cause and hold time whenever a Connector stops working, may be nil
+	afterClear    func(evicted int)                                         // Invoked after each automatic cleanup, may be nil
+	executor      *executor                                                 // Runs the closeMethod of removed Connectors, inline if nil
 }
 
 type autoClearConnectorSet struct {
-	token               atomic.Uint64        // An internally incremented Token for encoding Connectors
+	token               *atomic.Uint64       // An internally incremented Token for encoding Connectors, shared by every Set with global IDs
 	closed              atomic.Bool          // Indicates whether it's closed
+	connectorSetOptions                      // Options the Set was created with
 	connectorSet        map[uint64]connector // Collection of Connectors
 	connectorSetRWMutex sync.RWMutex         // Read-write lock protecting the connector collection
+	autoClearExited     chan struct{}        // Closed when the autoClear goroutine terminates
+	clearRequests       chan chan int        // Carries ClearNow calls to the autoClear goroutine
+	rearm               chan struct{}        // Signals the autoClear goroutine that the interval changed
+	stop                chan struct{}        // Closed when the Set is closed, interrupting the autoClear wait
+	pauseMutex          sync.Mutex           // Guards resumed
+	resumed             chan struct{}        // Closed by ResumeAutoClear, nil while the automatic cleanups run
+	contention          contention           // Write lock wait statistics, recorded with the connectpool_debugstats build tag only
 }
 
-func newConnectorSet(autoClearInterval, maxFreeTime *time.Duration, closeMethod *func(any), dealPanicMethod *func(any)) (NewConnectorSet connectorSet) {
+func newConnectorSet(options connectorSetOptions) (NewConnectorSet connectorSet) {
+	token := &globalToken
+	if !options.globalIDs {
+		token = new(atomic.Uint64)
+	}
+
 	NewConnectorSet = &autoClearConnectorSet{
-		connectorSet: make(map[uint64]connector),
+		token:               token,
+		connectorSet:        make(map[uint64]connector),
+		connectorSetOptions: options,
+		autoClearExited:     make(chan struct{}),
+		clearRequests:       make(chan chan int),
+		rearm:               make(chan struct{}, 1),
+		stop:                make(chan struct{}),
 	}
 
-	go NewConnectorSet.autoClear(autoClearInterval, maxFreeTime, closeMethod, dealPanicMethod) // Starts a new goroutine to periodically clean up Connectors
+	if options.manual {
+		return NewConnectorSet
+	}
+
+	go NewConnectorSet.autoClear() // Starts a new goroutine to periodically clean up Connectors
 	return NewConnectorSet
 }
 
-func (s *autoClearConnectorSet) Clear(maxFreeTime *time.Duration, closeMethod *func(any), dealPanicMethod *func(any)) {
+func (s *autoClearConnectorSet) Clear(maxFreeTime *time.Duration) (evicted int) {
+	if s.closed.Load() {
+		return 0
+	}
+
+	// Loads the callbacks once so that a single pass closes and handles panics with a consistent set
+	callbacks := s.callbacks.Load()
 
 	var RemoveList []uint64
+	var ExpiredList []uint64 // Free Connectors to close, claimed only once the write lock is held
+	var IdleList []uint64    // Free Connectors kept by the time-based cleanup
+	var idleBytes int64
 
 	// Finds all Connectors to be removed under a read lock
 	s.connectorSetRWMutex.RLock()
 
-	for key, value := range s.connectorSet {
+	s.each(func(key uint64, value connector) bool {
 		// Actively cleans up the Connector if a nil Connector is found
 		if value == nil || value.GetConnect() == nil {
 			RemoveList = append(RemoveList, key)
-			continue
+			return true
 		}
 
-		if value.SinceLastWorkingTime() > *maxFreeTime {
+		// Evicts the Connectors working past their working timeout, closed once their holder releases them
+		if value.WorkingTimedOut() {
+			value.Invalidate(CloseEvicted)
 			RemoveList = append(RemoveList, key)
+			return true
+		}
+
+		if s.idleExpired(value, *maxFreeTime) {
+			ExpiredList = append(ExpiredList, key)
+			return true
+		}
+
+		if value.IsFree() {
+			IdleList = append(IdleList, key)
+			idleBytes += value.MemorySize()
+		}
+
+		return true
+	})
+
+	// Evicts the longest-idle Connectors while the free Connectors exceed the memory budget
+	if s.maxIdleBytes != nil && *s.maxIdleBytes > 0 && idleBytes > *s.maxIdleBytes {
+		sort.SliceStable(IdleList, func(i, j int) bool {
+			return evictsBefore(s.connectorSet[IdleList[i]], s.connectorSet[IdleList[j]])
+		})
 
-			// Executes the respective closeMethod before removal
-			value.Do(closeMethod, dealPanicMethod)
+		for _, key := range IdleList {
+			if idleBytes <= *s.maxIdleBytes {
+				break
+			}
+
+			ExpiredList = append(ExpiredList, key)
+			idleBytes -= s.connectorSet[key].MemorySize()
 		}
 	}
 
 	s.connectorSetRWMutex.RUnlock()
 
-	if len(RemoveList) > 0 {
+	if len(RemoveList) == 0 && len(ExpiredList) == 0 {
+		return 0
+	}
 
-		// Removes the Connectors listed in RemoveList under a write lock
-		s.connectorSetRWMutex.Lock()
-		defer s.connectorSetRWMutex.Unlock()
+	// Removes the Connectors listed under a write lock
+	s.contention.lock(lockClear, &s.connectorSetRWMutex)
 
-		for _, key := range RemoveList {
+	for _, key := range RemoveList {
+		delete(s.connectorSet, key)
+	}
+
+	// Claims the expired Connectors, skipping those acquired since the read lock was released
+	var claimed [8]connector // Backs closeList for small passes, sparing an allocation
+	closeList := claimed[:0]
+	for _, key := range ExpiredList {
+		if value := s.connectorSet[key]; value != nil && value.TryStartWorking() {
 			delete(s.connectorSet, key)
+			closeList = append(closeList, value)
 		}
 	}
+
+	s.connectorSetRWMutex.Unlock()
+
+	if len(closeList) == 0 {
+		return len(RemoveList)
+	}
+
+	pass := new(callbackBundle)
+	pass.current.Store(callbacks)
+
+	// Executes the respective closeMethod once removed, then recycles the shell, which nothing references anymore
+	for _, value := range closeList {
+		s.executor.Submit(func() {
+			if callbacks.onIdleEvict != nil {
+				callbacks.onIdleEvict(value)
+			}
+
+			value.Do(callbacks.idleCloseMethod, pass)
+
+			if shell, ok := value.(*atomicConnector); ok && s.recycle && shell.recyclable() {
+				shell.recycle()
+			}
+		})
+	}
+
+	return len(RemoveList) + len(closeList)
 }
 
-func (s *autoClearConnectorSet) autoClear(autoClearInterval, maxFreeTime *time.Duration, closeMethod *func(any), dealPanicMethod *func(any)) {
+func (s *autoClearConnectorSet) autoClear() {
+	defer close(s.autoClearExited) // Signals that the cleanup thread is no longer running
+
+	var reply chan int // Channel of a ClearNow call waiting for this pass, if any
+
 	for {
 
-		// Determines AutoClearInterval; uses defaultAutoCleanInterval if autoClearInterval is nil
-		AutoClearInterval := defaultAutoCleanInterval
-		if autoClearInterval != nil {
-			AutoClearInterval = *autoClearInterval
+		// Waits while the automatic cleanups are paused, still serving ClearNow calls
+		if resumed := s.pausedUntil(); resumed != nil && reply == nil {
+			select {
+			case <-resumed:
+			case reply = <-s.clearRequests:
+			case <-s.stop:
+				return
+			}
 		}
 
 		// Creates a timer with a length of AutoClearInterval
-		timer := time.NewTimer(AutoClearInterval)
+		timer := s.clock.NewTimer(s.interval())
 
-		// Determines MaxFreeTime; uses defaultMaxFreeTime if maxFreeTime is nil
-		MaxFreeTime := defaultMaxFreeTime
-		if maxFreeTime != nil {
-			MaxFreeTime = *maxFreeTime
-		}
+		MaxFreeTime := s.config.Load().maxFreeTime
+		evicted := s.Clear(&MaxFreeTime) // Automatically performs a cleanup
 
-		s.Clear(&MaxFreeTime, closeMethod, dealPanicMethod) // Automatically performs a cleanup
+		// Answers the ClearNow call that interrupted the wait
+		if reply != nil {
+			reply <- evicted
+			reply = nil
+		}
 
 		// Terminates the cleanup thread if the Set is closed
 		if s.closed.Load() {
+			timer.Stop()
 			return
 		}
 
-		<-timer.C // Waits for the timer to expire
+		// Reports the finished cleanup
+		if s.afterClear != nil {
+			s.afterClear(evicted)
+		}
+
+		// Waits for the timer to expire, a ClearNow call, or the Set to be closed, restarting the wait when the interval changes
+		for waiting := true; waiting; {
+			select {
+			case <-timer.C():
+				waiting = false
+			case <-s.rearm:
+				timer.Stop()
+				timer = s.clock.NewTimer(s.interval())
+			case reply = <-s.clearRequests:
+				timer.Stop()
+				waiting = false
+			case <-s.stop:
+				timer.Stop()
+				return
+			}
+		}
 	}
 }
 
+// interval returns the current autoClearInterval, defaultAutoCleanInterval if it is not positive.
+func (s *autoClearConnectorSet) interval() time.Duration {
+	if autoClearInterval := s.config.Load().autoClearInterval; autoClearInterval > 0 {
+		return autoClearInterval
+	}
+
+	return defaultAutoCleanInterval
+}
+
+// each calls fn for every Connector until fn returns false, in Token order with deterministic ordering and in map
+// order otherwise. The caller must hold the lock; fn may delete the Connector it is called with.
+func (s *autoClearConnectorSet) each(fn func(key uint64, value connector) bool) {
+	if !s.deterministic {
+		for key, value := range s.connectorSet {
+			if !fn(key, value) {
+				return
+			}
+		}
+
+		return
+	}
+
+	keys := make([]uint64, 0, len(s.connectorSet))
+	for key := range s.connectorSet {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		if !fn(key, s.connectorSet[key]) {
+			return
+		}
+	}
+}
+
+// idleExpired reports whether value has been idle for longer than maxFreeTime, sparing the Connectors never used yet
+// during the grace period.
+func (s *autoClearConnectorSet) idleExpired(value connector, maxFreeTime time.Duration) bool {
+	idleFor := value.SinceLastWorkingTime()
+	if value.NeverUsed() && idleFor <= maxFreeTime+s.unusedGrace {
+		return false
+	}
+
+	return idleFor > maxFreeTime
+}
+
+// CountByState counts the Connectors by state, each counted once: unhealthy if it holds no usable connection,
+// pendingClose if it is working but discarded, working, expired if it is idle for longer than maxFreeTime and
+// awaiting the next clear pass, and idle otherwise.
+func (s *autoClearConnectorSet) CountByState(maxFreeTime time.Duration) map[string]int {
+	s.connectorSetRWMutex.RLock()
+	defer s.connectorSetRWMutex.RUnlock()
+
+	counts := map[string]int{"working": 0, "idle": 0, "expired": 0, "unhealthy": 0, "pendingClose": 0}
+	for _, value := range s.connectorSet {
+		switch {
+		case value == nil || value.GetConnect() == nil || value.Err() != nil:
+			counts["unhealthy"]++
+		case !value.IsFree() && value.IsDiscarded():
+			counts["pendingClose"]++
+		case !value.IsFree():
+			counts["working"]++
+		case s.idleExpired(value, maxFreeTime):
+			counts["expired"]++
+		default:
+			counts["idle"]++
+		}
+	}
+
+	return counts
+}
+
+// ClearNow interrupts the autoClear wait to run a cleanup immediately, returning the number of removed Connectors.
+func (s *autoClearConnectorSet) ClearNow() int {
+	// Without the autoClear goroutine, the caller runs the pass itself
+	if s.manual {
+		MaxFreeTime := s.config.Load().maxFreeTime
+		evicted := s.Clear(&MaxFreeTime)
+		if s.afterClear != nil && !s.closed.Load() {
+			s.afterClear(evicted)
+		}
+
+		return evicted
+	}
+
+	reply := make(chan int, 1)
+
+	select {
+	case s.clearRequests <- reply:
+	case <-s.autoClearExited:
+		return 0
+	}
+
+	return <-reply
+}
+
+func (s *autoClearConnectorSet) PauseAutoClear() {
+	s.pauseMutex.Lock()
+	defer s.pauseMutex.Unlock()
+
+	if s.resumed == nil {
+		s.resumed = make(chan struct{})
+	}
+}
+
+func (s *autoClearConnectorSet) ResumeAutoClear() {
+	s.pauseMutex.Lock()
+	defer s.pauseMutex.Unlock()
+
+	if s.resumed != nil {
+		close(s.resumed)
+		s.resumed = nil
+	}
+}
+
+func (s *autoClearConnectorSet) RearmAutoClear() {
+	select {
+	case s.rearm <- struct{}{}:
+	default: // A re-arm is already pending and will read the latest interval
+	}
+}
+
+// pausedUntil returns the channel closed when the automatic cleanups resume, nil if they are not paused.
+func (s *autoClearConnectorSet) pausedUntil() chan struct{} {
+	s.pauseMutex.Lock()
+	defer s.pauseMutex.Unlock()
+
+	return s.resumed
+}
+
 func (s *autoClearConnectorSet) registerToken() uint64 {
-	return s.token.Add(1) // Increment token, ensuring a unique token value each time
+	token := s.token.Add(1) // Increment token, ensuring a unique token value each time
+
+	// Spreads the counter values with the configured hasher, the Token being the hashed value
+	if s.keyHasher != nil {
+		token = s.keyHasher(token)
+	}
+
+	return token
+}
+
+func (s *autoClearConnectorSet) AddConnector() connector {
+	return s.addConnector(func() connector { return newConnector(s.callbacks, s.clock, s.onStop) })
+}
+
+func (s *autoClearConnectorSet) AdoptConnector(connect any) connector {
+	return s.addConnector(func() connector { return adoptConnector(connect, s.callbacks, s.clock, s.onStop) })
 }
 
-func (s *autoClearConnectorSet) AddConnector(connectMethod *func() any, dealPanicMethod *func(panicInfo any)) (NewConnector connector) {
+// addConnector inserts the Connector created by create under an unused Token, working, or returns nil if the Set is
+// closed. create runs without the lock held.
+func (s *autoClearConnectorSet) addConnector(create func() connector) (NewConnector connector) {
+	if s.closed.Load() {
+		return nil
+	}
 
 	var contains bool
 	var connectorToken uint64
@@ -124,26 +458,134 @@ func (s *autoClearConnectorSet) AddConnector(connectMethod *func() any, dealPani
 
 	s.connectorSetRWMutex.RUnlock()
 
-	// Obtains a new Connector
-	NewConnector = newConnector(connectMethod, dealPanicMethod)
+	// Obtains a new Connector, working so that no GetFreeConnector can take it before the caller does
+	NewConnector = create()
+	NewConnector.StartWorking()
+
+	s.contention.lock(lockAddConnector, &s.connectorSetRWMutex)
+	defer s.connectorSetRWMutex.Unlock()
+
+	// Closes the new Connector instead of inserting it if the Set was closed meanwhile
+	if s.closed.Load() {
+		created := NewConnector
+		closeMethod := s.callbacks.Load().idleCloseMethod
+		s.executor.Submit(func() { created.Do(closeMethod, s.callbacks) })
+		return nil
+	}
 
-	s.connectorSetRWMutex.Lock()
 	// Inserts connectorToken and NewConnector into the dictionary
+	NewConnector.SetID(connectorToken)
 	s.connectorSet[connectorToken] = NewConnector
-	s.connectorSetRWMutex.Unlock()
-
 	return
 }
 
 func (s *autoClearConnectorSet) GetFreeConnector() connector {
 
 	// Uses a write lock to ensure the retrieved FreeConnector is only used by one owner
-	s.connectorSetRWMutex.Lock()
+	if s.closed.Load() {
+		return nil
+	}
+
+	s.contention.lock(lockGetFreeConnector, &s.connectorSetRWMutex)
+	defer s.connectorSetRWMutex.Unlock()
+
+	var hot, cold connector
+	s.each(func(_ uint64, v connector) bool {
+		switch {
+		case !v.IsFree():
+		case !s.segmented:
+			hot = v // Without segments, any FreeConnector is handed out
+			return false
+		case v.IsCold():
+			// The cold segment is a queue, its head was released first
+			if cold == nil || v.LastWorkingTime().Before(cold.LastWorkingTime()) {
+				cold = v
+			}
+		default:
+			// The hot segment is a stack, its top was released last
+			if hot == nil || v.LastWorkingTime().After(hot.LastWorkingTime()) {
+				hot = v
+			}
+		}
+
+		return true
+	})
+
+	if hot == nil {
+		hot = cold
+	}
+
+	if hot != nil {
+		hot.StartWorking() // Marks the retrieved FreeConnector as busy to avoid reuse
+	}
+
+	return hot
+}
+
+// GetAffineConnector marks the Connector key hashes onto by rendezvous hashing over the Tokens as working and returns
+// it, or reports whether it is busy. Pinned and discarded Connectors are never chosen.
+func (s *autoClearConnectorSet) GetAffineConnector(key uint64) (c connector, busy bool) {
+	if s.closed.Load() {
+		return nil, false
+	}
+
+	s.contention.lock(lockGetFreeConnector, &s.connectorSetRWMutex)
 	defer s.connectorSetRWMutex.Unlock()
 
+	var best uint64
+	for token, v := range s.connectorSet {
+		if v == nil || v.IsPermanentlyWorking() || v.IsDiscarded() {
+			continue
+		}
+
+		if weight := rendezvousWeight(key, token); c == nil || weight > best {
+			c, best = v, weight
+		}
+	}
+
+	if c == nil {
+		return nil, false
+	}
+
+	if !c.IsFree() {
+		return nil, true
+	}
+
+	c.StartWorking() // Marks the retrieved Connector as busy to avoid reuse
+	return c, false
+}
+
+// Demote moves the free Connectors idle for longer than threshold from the hot to the cold segment,
+// returning how many were moved.
+func (s *autoClearConnectorSet) Demote(threshold time.Duration) (demoted int) {
+	s.connectorSetRWMutex.RLock()
+	defer s.connectorSetRWMutex.RUnlock()
+
 	for _, v := range s.connectorSet {
-		if v.IsFree() {
-			v.StartWorking() // Marks the retrieved FreeConnector as busy to avoid reuse
+		if v != nil && v.IsFree() && !v.IsCold() && v.SinceLastWorkingTime() > threshold {
+			v.SetCold(true)
+			demoted++
+		}
+	}
+
+	return demoted
+}
+
+// evictsBefore orders free Connectors for eviction, the cold segment first and then the longest idle.
+func evictsBefore(a, b connector) bool {
+	if a.IsCold() != b.IsCold() {
+		return a.IsCold()
+	}
+
+	return a.SinceLastWorkingTime() > b.SinceLastWorkingTime()
+}
+
+func (s *autoClearConnectorSet) FindConnector(connect any) connector {
+	s.connectorSetRWMutex.RLock()
+	defer s.connectorSetRWMutex.RUnlock()
+
+	for _, v := range s.connectorSet {
+		if v != nil && sameConnect(v.GetConnect(), connect) {
 			return v
 		}
 	}
@@ -151,7 +593,208 @@ func (s *autoClearConnectorSet) GetFreeConnector() connector {
 	return nil
 }
 
+func (s *autoClearConnectorSet) GetConnector(token uint64) connector {
+	s.connectorSetRWMutex.RLock()
+	defer s.connectorSetRWMutex.RUnlock()
+
+	return s.connectorSet[token]
+}
+
+func (s *autoClearConnectorSet) Contains(c connector) bool {
+	s.connectorSetRWMutex.RLock()
+	defer s.connectorSetRWMutex.RUnlock()
+
+	for _, v := range s.connectorSet {
+		if v == c {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *autoClearConnectorSet) RemoveConnector(c connector, closeMethod func(any)) bool {
+	s.contention.lock(lockRemoveConnector, &s.connectorSetRWMutex)
+
+	removed := false
+	for key, v := range s.connectorSet {
+		if v == c {
+			delete(s.connectorSet, key)
+			removed = true
+			break
+		}
+	}
+
+	s.connectorSetRWMutex.Unlock()
+
+	// Executes the closeMethod asynchronously, only if this call removed the Connector
+	if removed {
+		s.executor.Submit(func() { c.Do(closeMethod, s.callbacks) })
+	}
+
+	return removed
+}
+
+// ForceEvict removes up to n Connectors regardless of the idle policies, longest-idle free Connectors first and then
+// the oldest working ones, and returns the removed free Connectors, which the caller must close, along with the total
+// number removed. Working Connectors are discarded, so they are closed when their holder releases them. Pinned
+// Connectors are never evicted.
+func (s *autoClearConnectorSet) ForceEvict(n int) (idle []connector, evicted int) {
+	s.connectorSetRWMutex.Lock()
+	defer s.connectorSetRWMutex.Unlock()
+
+	var free, working []uint64
+	s.each(func(key uint64, value connector) bool {
+		switch {
+		case value == nil || value.IsPermanentlyWorking():
+		case value.IsFree():
+			free = append(free, key)
+		default:
+			working = append(working, key)
+		}
+
+		return true
+	})
+
+	sort.SliceStable(free, func(i, j int) bool {
+		return evictsBefore(s.connectorSet[free[i]], s.connectorSet[free[j]])
+	})
+
+	sort.SliceStable(working, func(i, j int) bool {
+		return s.connectorSet[working[i]].CreatedAt().Before(s.connectorSet[working[j]].CreatedAt())
+	})
+
+	for _, key := range append(free, working...) {
+		if evicted >= n {
+			break
+		}
+
+		value := s.connectorSet[key]
+		delete(s.connectorSet, key)
+		evicted++
+
+		if value.IsFree() {
+			idle = append(idle, value)
+		} else {
+			value.Invalidate(CloseEvicted)
+		}
+	}
+
+	return idle, evicted
+}
+
+// RemoveMatching removes every Connector for which match returns true, except pinned ones. Idle Connectors are
+// returned for the caller to close, working ones are discarded so that they are closed when released.
+func (s *autoClearConnectorSet) RemoveMatching(match func(connector) bool) (idle []connector, evicted int) {
+	s.connectorSetRWMutex.Lock()
+	defer s.connectorSetRWMutex.Unlock()
+
+	s.each(func(key uint64, value connector) bool {
+		if value == nil || value.IsPermanentlyWorking() || !match(value) {
+			return true
+		}
+
+		delete(s.connectorSet, key)
+		evicted++
+
+		if value.IsFree() {
+			idle = append(idle, value)
+		} else {
+			value.Invalidate(CloseEvicted)
+		}
+
+		return true
+	})
+
+	return idle, evicted
+}
+
+// TakeAll removes every Connector from the set, pinned ones included, and returns them; the caller must close them.
+// The working ones are retired, so their holders' releases do not close them again.
+func (s *autoClearConnectorSet) TakeAll() (all []connector) {
+	s.connectorSetRWMutex.Lock()
+	defer s.connectorSetRWMutex.Unlock()
+
+	s.each(func(key uint64, value connector) bool {
+		delete(s.connectorSet, key)
+
+		if value == nil {
+			return true
+		}
+
+		if !value.IsFree() {
+			value.Retire(CloseEvicted)
+		}
+
+		all = append(all, value)
+		return true
+	})
+
+	return all
+}
+
+// TakeIdle removes every free Connector from the set and returns them; the caller must close them.
+func (s *autoClearConnectorSet) TakeIdle() (idle []connector) {
+	s.connectorSetRWMutex.Lock()
+	defer s.connectorSetRWMutex.Unlock()
+
+	s.each(func(key uint64, value connector) bool {
+		if value != nil && value.IsFree() {
+			idle = append(idle, value)
+			delete(s.connectorSet, key)
+		}
+
+		return true
+	})
+
+	return idle
+}
+
+// ClaimIdleSample marks a uniform random sample of ceil(fraction × free Connectors) as working and returns it,
+// drawing only from the free Connectors accepted by eligible. Claimed Connectors are given back with RestoreIdle.
+func (s *autoClearConnectorSet) ClaimIdleSample(fraction float64, eligible func(connector) bool) []connector {
+	s.connectorSetRWMutex.Lock()
+	defer s.connectorSetRWMutex.Unlock()
+
+	var free, candidates []connector
+	s.each(func(_ uint64, v connector) bool {
+		if v != nil && v.IsFree() {
+			free = append(free, v)
+
+			if eligible(v) {
+				candidates = append(candidates, v)
+			}
+		}
+
+		return true
+	})
+
+	n := min(int(math.Ceil(fraction*float64(len(free)))), len(candidates))
+
+	// Partially shuffles the candidates to draw n of them without replacement
+	for i := 0; i < n; i++ {
+		j := i + s.random.Intn(len(candidates)-i)
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+		candidates[i].StartWorking()
+	}
+
+	return candidates[:n]
+}
+
+// sameConnect reports whether a and b are the same connection variable, treating uncomparable values as different.
+func sameConnect(a, b any) bool {
+	if a == nil || b == nil || reflect.TypeOf(a) != reflect.TypeOf(b) || !reflect.TypeOf(a).Comparable() {
+		return false
+	}
+
+	return a == b
+}
+
 func (s *autoClearConnectorSet) Size() (size int) {
+	if s.closed.Load() {
+		return 0
+	}
+
 	s.connectorSetRWMutex.RLock()
 	defer s.connectorSetRWMutex.RUnlock()
 
@@ -163,10 +806,62 @@ func (s *autoClearConnectorSet) Close() {
 	s.connectorSetRWMutex.Lock()
 	defer s.connectorSetRWMutex.Unlock()
 
-	s.closed.Store(true)  // Signals the autoClear coroutine to terminate
+	// Signals the autoClear coroutine to terminate
+	if !s.closed.Swap(true) {
+		close(s.stop)
+	}
+
+	// Discards the Connectors still in use, so they are closed once their holders release them
+	for _, value := range s.connectorSet {
+		if value != nil {
+			value.Invalidate(CloseShutdown)
+		}
+	}
+
 	clear(s.connectorSet) // Cleans up the connectorSet to avoid memory usage
 }
 
+func (s *autoClearConnectorSet) IdleByLabel() map[string]int {
+	s.connectorSetRWMutex.RLock()
+	defer s.connectorSetRWMutex.RUnlock()
+
+	idle := make(map[string]int)
+	s.each(func(_ uint64, v connector) bool {
+		if v != nil && v.IsFree() {
+			idle[v.Label()]++
+		}
+
+		return true
+	})
+
+	return idle
+}
+
+func (s *autoClearConnectorSet) FreeConnects() (connects []any) {
+	s.connectorSetRWMutex.RLock()
+	defer s.connectorSetRWMutex.RUnlock()
+
+	s.each(func(_ uint64, v connector) bool {
+		if v != nil && v.IsFree() && v.GetConnect() != nil {
+			connects = append(connects, v.GetConnect())
+		}
+
+		return true
+	})
+
+	return connects
+}
+
+// ForEachWorking calls fn for each working Connector under the read lock, stopping once fn returns false.
+func (s *autoClearConnectorSet) ForEachWorking(fn func(connector) bool) {
+	s.connectorSetRWMutex.RLock()
+	defer s.connectorSetRWMutex.RUnlock()
+
+	s.each(func(_ uint64, v connector) bool {
+		return v == nil || v.IsFree() || fn(v)
+	})
+}
+
 func (s *autoClearConnectorSet) WorkingNumber() int64 {
 	s.connectorSetRWMutex.RLock()
 	defer s.connectorSetRWMutex.RUnlock()
@@ -180,3 +875,104 @@ func (s *autoClearConnectorSet) WorkingNumber() int64 {
 
 	return cnt
 }
+
+func (s *autoClearConnectorSet) IdleBytes() int64 {
+	s.connectorSetRWMutex.RLock()
+	defer s.connectorSetRWMutex.RUnlock()
+
+	var idleBytes int64
+	for _, v := range s.connectorSet {
+		if v.IsFree() {
+			idleBytes += v.MemorySize()
+		}
+	}
+
+	return idleBytes
+}
+
+func (s *autoClearConnectorSet) Validate() (errs []error) {
+	s.connectorSetRWMutex.RLock()
+	defer s.connectorSetRWMutex.RUnlock()
+
+	tokens := make(map[connector]uint64, len(s.connectorSet))
+
+	for key, value := range s.connectorSet {
+		if value == nil {
+			continue
+		}
+
+		// Each Connector must be stored under exactly one Token
+		if other, ok := tokens[value]; ok {
+			errs = append(errs, fmt.Errorf("connectpool: connector stored under both tokens %d and %d", other, key))
+		}
+		tokens[value] = key
+
+		// Reads the clock after the Connector, which a concurrent release may update without the lock
+		if lastWorkingTime := value.LastWorkingTime(); lastWorkingTime.After(s.clock.Now()) {
+			errs = append(errs, fmt.Errorf("connectpool: connector %d last worked in the future", key))
+		}
+	}
+
+	// The cleanup thread must be running until the Set is closed
+	select {
+	case <-s.autoClearExited:
+		if !s.closed.Load() {
+			errs = append(errs, errors.New("connectpool: autoClear goroutine is not running"))
+		}
+	default:
+	}
+
+	return errs
+}
+
+// CheckInvariants cheaply validates the Connectors in a single pass, unlike the thorough Validate.
+func (s *autoClearConnectorSet) CheckInvariants() (errs []error) {
+	s.connectorSetRWMutex.RLock()
+	defer s.connectorSetRWMutex.RUnlock()
+
+	for key, value := range s.connectorSet {
+		if value == nil {
+			errs = append(errs, fmt.Errorf("connectpool: nil connector stored under token %d", key))
+			continue
+		}
+
+		// Each Connector must record the Token it is stored under, which also rules out storing it twice
+		if id := value.ID(); id != key {
+			errs = append(errs, fmt.Errorf("connectpool: connector stored under token %d records token %d", key, id))
+		}
+
+		// Only a segmented Set demotes Connectors, and only free ones may sit in the cold segment
+		switch {
+		case value.IsCold() && !s.segmented:
+			errs = append(errs, fmt.Errorf("connectpool: connector %d is in the cold segment of an unsegmented set", key))
+		case value.IsCold() && !value.IsFree() && !value.IsPermanentlyWorking():
+			errs = append(errs, fmt.Errorf("connectpool: connector %d is both working and in the cold idle segment", key))
+		}
+	}
+
+	if s.closed.Load() && len(s.connectorSet) > 0 {
+		errs = append(errs, fmt.Errorf("connectpool: closed set still holds %d connectors", len(s.connectorSet)))
+	}
+
+	return errs
+}
+
+func (s *autoClearConnectorSet) Snapshot() []ConnectorSnapshot {
+	s.connectorSetRWMutex.RLock()
+	defer s.connectorSetRWMutex.RUnlock()
+
+	snapshots := make([]ConnectorSnapshot, 0, len(s.connectorSet))
+	s.each(func(key uint64, value connector) bool {
+		if value != nil {
+			snapshots = append(snapshots, snapshotConnector(key, value))
+		}
+
+		return true
+	})
+
+	return snapshots
+}
+
+func (s *autoClearConnectorSet) Contention() map[string]LockContention {
+	return s.contention.Snapshot()
+}
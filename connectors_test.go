@@ -0,0 +1,395 @@
+package connectpool
+
+import (
+	"errors"
+	"math/rand"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/HuXin0817/ConnectPool/clock"
+)
+
+func TestClear_ConsistentCallbacks(t *testing.T) {
+	p := newTestPool(t, counter(), WithTestabilityMode(), WithMaxFreeTime(0))
+
+	var closed, mismatched atomic.Int64
+	started, stop := make(chan struct{}), make(chan struct{})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	// Swaps the close and panic methods as a pair while the clear passes run
+	go func() {
+		defer wg.Done()
+
+		for generation := 0; ; generation++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+
+			p.callbacks.update(func(next *callbacks) {
+				next.idleCloseMethod = func(any) {
+					closed.Add(1)
+					runtime.Gosched() // Gives the swapper a chance to replace the pair mid-close
+					panic(generation)
+				}
+				next.dealPanicMethod = func(panicInfo any) {
+					if panicInfo != generation {
+						mismatched.Add(1)
+					}
+				}
+			})
+
+			if generation == 0 {
+				close(started)
+			}
+
+			runtime.Gosched()
+		}
+	}()
+	<-started
+
+	for i := 0; i < 50; i++ {
+		var cancels []func()
+		for j := 0; j < 4; j++ {
+			_, cancel := p.Register()
+			cancels = append(cancels, cancel)
+		}
+
+		for _, cancel := range cancels {
+			cancel()
+		}
+
+		p.ClearNow()
+	}
+
+	close(stop)
+	wg.Wait()
+
+	if closed.Load() == 0 {
+		t.Fatal("no connection was closed by a clear pass")
+	}
+
+	if n := mismatched.Load(); n > 0 {
+		t.Fatalf("%d panics handled by the panic method of another pass", n)
+	}
+}
+
+func TestClear_MaxIdleBytes(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	p := newTestPool(t, counter(), WithTestabilityMode(), WithClock(fake), WithMaxFreeTime(time.Hour), WithConnSize(func(any) int64 { return 10 }), WithMaxIdleBytes(25))
+
+	conns := make([]any, 5)
+	cancels := make([]func(), 5)
+	for i := range conns {
+		conns[i], cancels[i] = p.Register()
+	}
+
+	// Working connections are never evicted for the budget, however much memory they hold
+	if evicted := p.ClearNow(); evicted != 0 {
+		t.Fatalf("ClearNow evicted %d working connections", evicted)
+	}
+
+	// Releases three connections, the first one becoming the longest idle
+	for i := 0; i < 3; i++ {
+		fake.Advance(time.Second)
+		cancels[i]()
+	}
+
+	if idleBytes := p.Stats().IdleBytes; idleBytes != 30 {
+		t.Fatalf("IdleBytes = %d, want 30", idleBytes)
+	}
+
+	if evicted := p.ClearNow(); evicted != 1 {
+		t.Fatalf("ClearNow evicted %d connections, want 1", evicted)
+	}
+
+	if idleBytes := p.Stats().IdleBytes; idleBytes != 20 {
+		t.Fatalf("IdleBytes = %d, want 20", idleBytes)
+	}
+
+	for _, conn := range p.AllFree() {
+		if conn == conns[0] {
+			t.Fatal("the longest idle connection was kept")
+		}
+	}
+
+	if working := p.WorkingNumber(); working != 2 {
+		t.Fatalf("WorkingNumber = %d, want 2", working)
+	}
+}
+
+func TestCheckInvariants_DetectsInconsistentSet(t *testing.T) {
+	p := NewConnectPool(counter(), WithInvariantChecks(true), WithFatalOn(FatalInvariantViolation)).(*connectPool)
+	defer p.Close()
+
+	_, cancel := p.Register()
+	cancel()
+
+	if errs := p.pool.CheckInvariants(); len(errs) != 0 {
+		t.Fatalf("consistent set reported %v", errs)
+	}
+
+	c := p.pool.GetConnector(p.Snapshot()[0].Token)
+
+	// An unsegmented set never demotes, so a cold connector means its bookkeeping is broken
+	c.SetCold(true)
+	if errs := p.pool.CheckInvariants(); len(errs) != 1 {
+		t.Fatalf("cold connector in an unsegmented set reported %v", errs)
+	}
+	c.SetCold(false)
+
+	// A connector recording another token than the one it is stored under
+	c.SetID(c.ID() + 1)
+	if errs := p.pool.CheckInvariants(); len(errs) != 1 {
+		t.Fatalf("mismatched token reported %v", errs)
+	}
+
+	// The next state transition counts the violation and fails the pool, also panicking under connectpool_invariants
+	func() {
+		defer func() {
+			if r := recover(); r != nil && !strictInvariants {
+				panic(r)
+			}
+		}()
+
+		if _, cancel := p.Register(); cancel != nil {
+			cancel()
+		}
+	}()
+	if p.invariantViolations.Load() == 0 || !errors.Is(p.Err(), ErrPoolFailed) {
+		t.Fatalf("violation not reported, Err = %v", p.Err())
+	}
+}
+
+func TestDeterministicOrdering_IdleSelection(t *testing.T) {
+	p := newTestPool(t, counter(), WithDeterministicOrdering(true))
+
+	cancels := make([]func(), 0, 3)
+	for range cap(cancels) {
+		_, cancel := p.Register()
+		cancels = append(cancels, cancel)
+	}
+	for i := len(cancels) - 1; i >= 0; i-- {
+		cancels[i]()
+	}
+
+	// Every idle connection is equally eligible, so the lowest token wins each time
+	for want := int64(1); want <= 3; want++ {
+		connect, cancel := p.Register()
+		defer cancel()
+
+		if connect.(int64) != want {
+			t.Fatalf("Register handed out connection %v, want %d", connect, want)
+		}
+	}
+}
+
+// BenchmarkKeyHasher looks up connectors in a set of 100k, stored under their sequential tokens or under mixed keys.
+func BenchmarkKeyHasher(b *testing.B) {
+	const connectors = 100_000
+
+	hashers := []struct {
+		name      string
+		keyHasher func(uint64) uint64
+	}{
+		{"Identity", nil},
+		{"SplitMix64", func(token uint64) uint64 { return rendezvousWeight(0, token) }},
+	}
+
+	for _, hasher := range hashers {
+		b.Run(hasher.name, func(b *testing.B) {
+			p := newTestPool(b, counter(), WithTestabilityMode(), WithKeyHasher(hasher.keyHasher))
+
+			keys := make([]uint64, 0, connectors)
+			for range connectors {
+				keys = append(keys, p.pool.AddConnector().ID())
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := range b.N {
+				if p.pool.GetConnector(keys[i%connectors]) == nil {
+					b.Fatal("connector not found")
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkChurn creates, uses and evicts one connector per iteration, with and without recycling the shells of the
+// evicted connectors.
+func BenchmarkChurn(b *testing.B) {
+	for _, recycle := range []bool{false, true} {
+		b.Run(map[bool]string{false: "Allocate", true: "Recycle"}[recycle], func(b *testing.B) {
+			p := newTestPool(b, counter(), WithTestabilityMode(), WithMaxFreeTime(0))
+			p.pool.(*autoClearConnectorSet).recycle = recycle
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				_, cancel := p.Register()
+				cancel()
+
+				if p.ClearNow() != 1 {
+					b.Fatal("the idle connector was not evicted")
+				}
+			}
+		})
+	}
+}
+
+func TestRecycle_StaleConnector(t *testing.T) {
+	p := newTestPool(t, counter(), WithTestabilityMode(), WithMaxFreeTime(0))
+
+	stale, release, err := p.AcquireConnector()
+	if err != nil {
+		t.Fatal(err)
+	}
+	connect := stale.Conn()
+	release()
+
+	// A holder keeps reading its Connector while the pool churns through evictions and creations
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		for i := 0; i < 1000; i++ {
+			if got := stale.Conn(); got != connect {
+				t.Errorf("stale Connector reads %v, want the connection it held, %v", got, connect)
+				return
+			}
+			stale.Release()
+			_ = stale.CreatedAt()
+		}
+	}()
+
+	for i := 0; i < 1000; i++ {
+		_, cancel := p.Register()
+		cancel()
+		p.ClearNow()
+	}
+
+	<-done
+}
+
+func TestIdleSegmentation_DemotionAndPromotion(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+
+	p := newTestPool(t, counter(), WithTestabilityMode(), WithClock(fake), WithIdleSegmentation(time.Minute, time.Minute))
+
+	waitUntil(t, "the demotion timer was armed", func() bool { return fake.Pending() == 1 })
+
+	cold := func(connect any) bool { return p.pool.FindConnector(connect).IsCold() }
+
+	// Releases the connections 1, 2 and 3 ten seconds apart
+	var cancels []func()
+	for range 3 {
+		_, cancel := p.Register()
+		cancels = append(cancels, cancel)
+	}
+	for _, cancel := range cancels {
+		cancel()
+		fake.Advance(10 * time.Second)
+	}
+
+	// The pass at one minute finds only the connection 1 idle for longer than a minute
+	fake.Advance(40 * time.Second)
+	waitUntil(t, "the connection 1 was demoted", func() bool { return cold(int64(1)) })
+	if cold(int64(2)) || cold(int64(3)) {
+		t.Fatal("a connection idle for less than a minute was demoted")
+	}
+
+	// The hot segment is handed out first, most recently released first, then the cold one, which promotes
+	for _, want := range []int64{3, 2, 1} {
+		connect, cancel := p.Register()
+		defer cancel()
+
+		if connect != want {
+			t.Fatalf("Register returned connection %v, want %d", connect, want)
+		}
+		if cold(connect) {
+			t.Fatalf("connection %v still cold once acquired", connect)
+		}
+	}
+}
+
+func TestIdleSegmentation_EvictsColdFirst(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+
+	var closed []any
+	p := newTestPool(t, counter(), WithTestabilityMode(), WithClock(fake), WithIdleSegmentation(time.Hour, time.Hour),
+		WithCloseMethod(func(connect any) { closed = append(closed, connect) }))
+
+	var cancels []func()
+	for range 3 {
+		_, cancel := p.Register()
+		cancels = append(cancels, cancel)
+	}
+	for _, cancel := range cancels {
+		cancel()
+		fake.Advance(time.Second)
+	}
+
+	// The most recently released connection is cold, and evicted before the longer idle hot ones
+	p.pool.FindConnector(int64(3)).SetCold(true)
+	p.SetMaxSize(2)
+
+	if len(closed) != 1 || closed[0] != int64(3) {
+		t.Fatalf("SetMaxSize closed %v, want the cold connection, [3]", closed)
+	}
+}
+
+func TestConnectorSet_AfterClose(t *testing.T) {
+	for _, manual := range []bool{false, true} {
+		t.Run(map[bool]string{false: "AutoClear", true: "Manual"}[manual], func(t *testing.T) {
+			bundle := new(callbackBundle)
+			bundle.update(func(next *callbacks) { next.connectMethod = counter() })
+
+			config := new(configBundle)
+			config.update(func(next *runtimeConfig) { next.maxFreeTime = 0 })
+
+			s := newConnectorSet(connectorSetOptions{callbacks: bundle, config: config, manual: manual,
+				random: rand.New(rand.NewSource(1)), clock: clock.Real()})
+
+			s.AddConnector().StopWorking()
+			s.Close()
+
+			maxFreeTime := time.Duration(0)
+			c, busy := s.GetAffineConnector(1)
+			switch {
+			case s.AddConnector() != nil:
+				t.Fatal("AddConnector added a connector to a closed set")
+			case s.AdoptConnector(1) != nil:
+				t.Fatal("AdoptConnector added a connector to a closed set")
+			case s.GetFreeConnector() != nil:
+				t.Fatal("GetFreeConnector returned a connector of a closed set")
+			case c != nil || busy:
+				t.Fatal("GetAffineConnector returned a connector of a closed set")
+			case s.Clear(&maxFreeTime) != 0:
+				t.Fatal("Clear evicted connectors of a closed set")
+			case s.ClearNow() != 0:
+				t.Fatal("ClearNow evicted connectors of a closed set")
+			case s.Size() != 0:
+				t.Fatalf("Size = %d after Close, want 0", s.Size())
+			}
+		})
+	}
+}
+
+func TestRegister_AfterClose(t *testing.T) {
+	p := newTestPool(t, counter())
+	p.Close()
+
+	if _, _, err := p.AcquireConnector(); !errors.Is(err, ErrPoolClosed) {
+		t.Fatalf("AcquireConnector error = %v after Close, want %v", err, ErrPoolClosed)
+	}
+	if connect, cancel := p.Register(); connect != nil || cancel != nil {
+		t.Fatalf("Register returned %v after Close, want nothing", connect)
+	}
+}
@@ -0,0 +1,150 @@
+// Package connectpooltest is a conformance suite for custom pools built from the connectpool primitives: a pool
+// handing out the Connectors of a connectpool.ConnectorSet passes it if it reuses released connectors, never hands one
+// to two holders, never exceeds its capacity and closes its idle connectors on Close.
+package connectpooltest
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	connectpool "github.com/HuXin0817/ConnectPool"
+)
+
+// Pool is a custom pool as the suite drives it.
+type Pool interface {
+	Acquire() *connectpool.Connector  // Waits for a free connector and returns it acquired
+	Release(c *connectpool.Connector) // Gives back a connector returned by Acquire
+	Close()                           // Closes the pool along with its idle connectors
+}
+
+// NewPool creates an empty Pool of at most cap connectors, created and closed with callbacks.
+type NewPool func(callbacks connectpool.Callbacks, cap int) Pool
+
+// waitTimeout bounds how long the suite waits for a connector it expects.
+const waitTimeout = time.Second
+
+// counted returns callbacks numbering the connections they create, counting both creations and closings.
+func counted(connects, closes *atomic.Int64) connectpool.Callbacks {
+	return connectpool.Callbacks{
+		Connect: func() any { return connects.Add(1) },
+		Close:   func(any) { closes.Add(1) },
+	}
+}
+
+// TestConnectorSet runs the conformance suite against the pools newPool creates, one subtest each.
+func TestConnectorSet(t *testing.T, newPool NewPool) {
+	t.Run("ReusesReleased", func(t *testing.T) { testReusesReleased(t, newPool) })
+	t.Run("Exclusive", func(t *testing.T) { testExclusive(t, newPool) })
+	t.Run("WaitsAtCap", func(t *testing.T) { testWaitsAtCap(t, newPool) })
+	t.Run("CloseClosesIdle", func(t *testing.T) { testCloseClosesIdle(t, newPool) })
+}
+
+func testReusesReleased(t *testing.T, newPool NewPool) {
+	var connects, closes atomic.Int64
+	pool := newPool(counted(&connects, &closes), 2)
+	defer pool.Close()
+
+	c := pool.Acquire()
+	switch {
+	case c == nil:
+		t.Fatal("Acquire returned nil")
+	case c.Err() != nil:
+		t.Fatalf("Acquire returned a failed connector: %v", c.Err())
+	case c.IsFree():
+		t.Fatal("Acquire returned a free connector")
+	}
+
+	conn := c.Conn()
+	pool.Release(c)
+
+	c = pool.Acquire()
+	if c.Conn() != conn {
+		t.Fatalf("Acquire returned connection %v after releasing %v, want it reused", c.Conn(), conn)
+	}
+	pool.Release(c)
+
+	if n := connects.Load(); n != 1 {
+		t.Fatalf("%d connections created, want 1", n)
+	}
+}
+
+func testExclusive(t *testing.T, newPool NewPool) {
+	const cap = 2
+
+	var connects, closes atomic.Int64
+	pool := newPool(counted(&connects, &closes), cap)
+	defer pool.Close()
+
+	var holders sync.Map // Connection to the number of its holders
+	var shared atomic.Bool
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				c := pool.Acquire()
+				n, _ := holders.LoadOrStore(c.Conn(), new(atomic.Int64))
+				if n.(*atomic.Int64).Add(1) > 1 {
+					shared.Store(true)
+				}
+				n.(*atomic.Int64).Add(-1)
+				pool.Release(c)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if shared.Load() {
+		t.Fatal("a connector was handed to two holders at once")
+	}
+	if n := connects.Load(); n > cap {
+		t.Fatalf("%d connections created, want at most %d", n, cap)
+	}
+}
+
+func testWaitsAtCap(t *testing.T, newPool NewPool) {
+	var connects, closes atomic.Int64
+	pool := newPool(counted(&connects, &closes), 1)
+	defer pool.Close()
+
+	first := pool.Acquire()
+
+	acquired := make(chan *connectpool.Connector)
+	go func() { acquired <- pool.Acquire() }()
+
+	select {
+	case <-acquired:
+		t.Fatal("Acquire returned while the only connector was held")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	pool.Release(first)
+
+	select {
+	case c := <-acquired:
+		if c.Conn() != first.Conn() {
+			t.Fatalf("waiter got connection %v, want the released %v", c.Conn(), first.Conn())
+		}
+		pool.Release(c)
+	case <-time.After(waitTimeout):
+		t.Fatal("waiter not served after the connector was released")
+	}
+}
+
+func testCloseClosesIdle(t *testing.T, newPool NewPool) {
+	var connects, closes atomic.Int64
+	pool := newPool(counted(&connects, &closes), 2)
+
+	first, second := pool.Acquire(), pool.Acquire()
+	pool.Release(first)
+	pool.Release(second)
+	pool.Close()
+
+	if n := closes.Load(); n != connects.Load() {
+		t.Fatalf("%d of %d idle connections closed, want all", n, connects.Load())
+	}
+}
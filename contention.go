@@ -0,0 +1,27 @@
+package connectpool
+
+import "time"
+
+// lockSite identifies a place where the connectorSet write lock is taken.
+type lockSite int
+
+const (
+	lockGetFreeConnector lockSite = iota // Retrieving a free Connector
+	lockAddConnector                     // Inserting a new Connector
+	lockRemoveConnector                  // Removing a released Connector that is not reused
+	lockClear                            // Removing the Connectors found by a clear pass
+	lockSiteCount
+)
+
+var lockSiteNames = [lockSiteCount]string{
+	lockGetFreeConnector: "GetFreeConnector",
+	lockAddConnector:     "AddConnector",
+	lockRemoveConnector:  "RemoveConnector",
+	lockClear:            "Clear",
+}
+
+// LockContention summarizes the waits for the connectorSet write lock at one site.
+type LockContention struct {
+	Acquisitions uint64        // Number of times the lock was taken
+	WaitTime     time.Duration // Summed time spent waiting for the lock
+}
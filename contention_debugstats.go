@@ -0,0 +1,37 @@
+//go:build connectpool_debugstats
+
+package connectpool
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// contention records how long each site waits for the connectorSet write lock.
+type contention struct {
+	acquisitions [lockSiteCount]atomic.Uint64
+	waitTime     [lockSiteCount]atomic.Int64
+}
+
+// lock takes mutex for writing, recording the wait against site.
+func (c *contention) lock(site lockSite, mutex *sync.RWMutex) {
+	start := time.Now()
+	mutex.Lock()
+
+	c.acquisitions[site].Add(1)
+	c.waitTime[site].Add(int64(time.Since(start)))
+}
+
+// Snapshot returns the recorded waits keyed by site name.
+func (c *contention) Snapshot() map[string]LockContention {
+	snapshot := make(map[string]LockContention, lockSiteCount)
+	for site := range lockSiteCount {
+		snapshot[lockSiteNames[site]] = LockContention{
+			Acquisitions: c.acquisitions[site].Load(),
+			WaitTime:     time.Duration(c.waitTime[site].Load()),
+		}
+	}
+
+	return snapshot
+}
@@ -0,0 +1,36 @@
+//go:build connectpool_debugstats
+
+package connectpool
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestContention_CountersMove(t *testing.T) {
+	p := newTestPool(t, counter(), WithCap(2))
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				_, cancel := p.Register()
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+
+	contention := p.Stats().Contention
+	for _, site := range []string{"GetFreeConnector", "AddConnector"} {
+		if contention[site].Acquisitions == 0 {
+			t.Fatalf("no %s acquisitions recorded: %v", site, contention)
+		}
+	}
+
+	if contention["GetFreeConnector"].WaitTime <= 0 {
+		t.Fatalf("no GetFreeConnector wait time recorded: %v", contention)
+	}
+}
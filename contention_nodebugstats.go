@@ -0,0 +1,16 @@
+//go:build !connectpool_debugstats
+
+package connectpool
+
+import "sync"
+
+// contention compiles to no-ops without the connectpool_debugstats build tag.
+type contention struct{}
+
+func (c *contention) lock(_ lockSite, mutex *sync.RWMutex) {
+	mutex.Lock()
+}
+
+func (c *contention) Snapshot() map[string]LockContention {
+	return nil
+}
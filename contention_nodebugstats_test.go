@@ -0,0 +1,41 @@
+//go:build !connectpool_debugstats
+
+package connectpool
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestContention_Disabled(t *testing.T) {
+	p := newTestPool(t, counter())
+
+	_, cancel := p.Register()
+	cancel()
+
+	if contention := p.Stats().Contention; contention != nil {
+		t.Fatalf("Contention = %v without the connectpool_debugstats tag", contention)
+	}
+}
+
+// BenchmarkContentionLock compares the instrumented lock to a plain one; without the tag both should match.
+func BenchmarkContentionLock(b *testing.B) {
+	var mutex sync.RWMutex
+
+	b.Run("Plain", func(b *testing.B) {
+		b.ReportAllocs()
+		for range b.N {
+			mutex.Lock()
+			mutex.Unlock()
+		}
+	})
+
+	b.Run("Instrumented", func(b *testing.B) {
+		var c contention
+		b.ReportAllocs()
+		for range b.N {
+			c.lock(lockGetFreeConnector, &mutex)
+			mutex.Unlock()
+		}
+	})
+}
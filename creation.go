@@ -0,0 +1,107 @@
+package connectpool
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	defaultCreationWindow = 10 * time.Minute // Default length of the creation failure window
+	creationBuckets       = 10               // Number of buckets the creation failure window is split into
+)
+
+// creationBucket counts the creations of one slice of the window.
+type creationBucket struct {
+	index    int64  // Index of the slice of time the bucket currently counts
+	success  uint64 // Successful creations
+	failures uint64 // Failed creations
+}
+
+// creationTracker keeps a sliding window of connector creation outcomes in a fixed ring of buckets,
+// so its memory stays constant however many connectors are created.
+type creationTracker struct {
+	mutex     sync.Mutex
+	window    time.Duration                   // Length of the window
+	buckets   [creationBuckets]creationBucket // Ring of buckets covering the window
+	threshold float64                         // Failure rate firing alert, alert is disabled if 0
+	alert     func(rate float64)              // Called at most once per window when the failure rate reaches threshold
+	lastAlert time.Time                       // Time alert was last fired
+	now       func() time.Time                // Clock reading the current time
+}
+
+func newCreationTracker(window time.Duration) *creationTracker {
+	return &creationTracker{window: window, now: time.Now}
+}
+
+// record counts one creation, reporting the failure rate to alert if it reaches the threshold.
+func (t *creationTracker) record(success bool, submit func(func())) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := t.now()
+	index := now.UnixNano() / int64(t.bucketWidth())
+
+	// Reuses the slot of the bucket that fell out of the window
+	bucket := &t.buckets[index%creationBuckets]
+	if bucket.index != index {
+		*bucket = creationBucket{index: index}
+	}
+
+	if success {
+		bucket.success++
+	} else {
+		bucket.failures++
+	}
+
+	if t.alert == nil || t.threshold <= 0 || now.Sub(t.lastAlert) < t.window {
+		return
+	}
+
+	if rate := t.rate(index); rate >= t.threshold {
+		t.lastAlert = now
+
+		alert := t.alert
+		submit(func() { alert(rate) })
+	}
+}
+
+// FailureRate returns the fraction of failed creations within the window.
+func (t *creationTracker) FailureRate() float64 {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return t.rate(t.now().UnixNano() / int64(t.bucketWidth()))
+}
+
+// rate sums the buckets still inside the window ending at index. It must be called with the mutex held.
+func (t *creationTracker) rate(index int64) float64 {
+	var success, failures uint64
+	for _, bucket := range t.buckets {
+		if bucket.index > index-creationBuckets && bucket.index <= index {
+			success += bucket.success
+			failures += bucket.failures
+		}
+	}
+
+	if success+failures == 0 {
+		return 0
+	}
+
+	return float64(failures) / float64(success+failures)
+}
+
+func (t *creationTracker) bucketWidth() time.Duration {
+	return max(t.window/creationBuckets, 1)
+}
+
+// recordCreation counts the outcome of creating c.
+func (p *connectPool) recordCreation(c connector) {
+	if c.Err() == nil {
+		p.totalCreated.Add(1)
+		p.history.record(func(b *Bucket) { b.Creations++ })
+	}
+
+	p.creations.record(c.Err() == nil, p.executor.Submit)
+	p.trackPanicStreak(FatalConnectPanics, &p.fatal.connectPanics, errors.Is(c.Err(), ErrConnectPanicked))
+}
@@ -0,0 +1,107 @@
+package connectpool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/HuXin0817/ConnectPool/clock"
+)
+
+// scriptedTracker returns a tracker reading fake, alerting into the returned slice synchronously.
+func scriptedTracker(fake *clock.Fake, threshold float64) (*creationTracker, *[]float64) {
+	alerts := new([]float64)
+
+	t := newCreationTracker(10 * time.Minute)
+	t.now = fake.Now
+	t.threshold = threshold
+	t.alert = func(rate float64) { *alerts = append(*alerts, rate) }
+	return t, alerts
+}
+
+// script records n outcomes of the same kind.
+func script(t *creationTracker, n int, success bool) {
+	for range n {
+		t.record(success, func(task func()) { task() })
+	}
+}
+
+func TestCreationTracker_AlertOncePerWindow(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	tracker, alerts := scriptedTracker(fake, 0.05)
+
+	// 1 failure in 20 creations reaches the 5% threshold
+	script(tracker, 19, true)
+	if len(*alerts) != 0 {
+		t.Fatalf("alert fired below the threshold: %v", *alerts)
+	}
+	script(tracker, 1, false)
+	if len(*alerts) != 1 || (*alerts)[0] != 0.05 {
+		t.Fatalf("alerts = %v, want [0.05]", *alerts)
+	}
+
+	// Further failures within the same window stay silent
+	fake.Advance(time.Minute)
+	script(tracker, 5, false)
+	if len(*alerts) != 1 {
+		t.Fatalf("alert fired twice within the window: %v", *alerts)
+	}
+
+	// A window after the first alert the threshold is crossed again
+	fake.Advance(9 * time.Minute)
+	script(tracker, 1, false)
+	if len(*alerts) != 2 {
+		t.Fatalf("alert not fired again after the window: %v", *alerts)
+	}
+
+	// The first minute's successes left the window, so only failures remain
+	if rate := (*alerts)[1]; rate != 1 {
+		t.Fatalf("second alert rate = %v, want 1", rate)
+	}
+}
+
+func TestCreationTracker_WindowSlides(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	tracker, alerts := scriptedTracker(fake, 0.5)
+
+	script(tracker, 3, true)
+	script(tracker, 1, false)
+	if len(*alerts) != 0 {
+		t.Fatalf("alert fired below the threshold: %v", *alerts)
+	}
+	if rate := tracker.FailureRate(); rate != 0.25 {
+		t.Fatalf("FailureRate = %v, want 0.25", rate)
+	}
+
+	fake.Advance(5 * time.Minute)
+	script(tracker, 4, false)
+	if rate := tracker.FailureRate(); rate != 5.0/8 {
+		t.Fatalf("FailureRate = %v, want 0.625", rate)
+	}
+	if len(*alerts) != 1 {
+		t.Fatalf("alerts = %v, want one alert once 50%% was crossed", *alerts)
+	}
+
+	// The first outcomes fall out of the window, then every outcome does
+	fake.Advance(5 * time.Minute)
+	if rate := tracker.FailureRate(); rate != 1 {
+		t.Fatalf("FailureRate = %v, want 1", rate)
+	}
+
+	fake.Advance(10 * time.Minute)
+	if rate := tracker.FailureRate(); rate != 0 {
+		t.Fatalf("FailureRate = %v, want 0 after the window", rate)
+	}
+}
+
+func TestStats_CreationFailureRate(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	p := newTestPool(t, counter(), WithClock(fake))
+
+	_, cancel := p.Register()
+	cancel()
+	p.creations.record(false, p.executor.Submit)
+
+	if rate := p.Stats().CreationFailureRate; rate != 0.5 {
+		t.Fatalf("CreationFailureRate = %v, want 0.5", rate)
+	}
+}
@@ -0,0 +1,63 @@
+package connectpool
+
+import (
+	"log/slog"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+)
+
+// misuse identifies a kind of incorrect use of the pool detected in diagnostics mode.
+type misuse int
+
+const (
+	misuseCancelTwice     misuse = iota // A cancelFunc was called more than once
+	misuseForgottenCancel               // A cancelFunc was dropped without being called
+	misuseInvalidDeadline               // RegisterWithTimeLimit was called with a zero or negative deadline
+	misuseLongHold                      // A connection was held far longer than the others, likely used after it should have been released
+	misuseCount
+)
+
+const (
+	holdAnomalySamples = 10 // Number of holds averaged before a hold can be reported as anomalous
+	holdAnomalyFactor  = 10 // Multiple of the mean hold time beyond which a hold is reported
+)
+
+var misuseMessages = [misuseCount]string{
+	misuseCancelTwice:     "connectpool: cancelFunc called more than once",
+	misuseForgottenCancel: "connectpool: cancelFunc was never called, the connection leaked",
+	misuseInvalidDeadline: "connectpool: RegisterWithTimeLimit called with a non-positive deadline",
+	misuseLongHold:        "connectpool: connection held far longer than usual, it may be used after it should have been released",
+}
+
+// diagnostics logs each distinct misuse of the pool once.
+type diagnostics struct {
+	logger *slog.Logger             // Logger receiving the warnings
+	warned [misuseCount]atomic.Bool // Whether each misuse has already been reported
+	holds  atomic.Int64             // Number of holds observed
+	held   atomic.Int64             // Summed duration of the holds observed, in nanoseconds
+}
+
+// warn logs kind together with stack the first time kind is detected. It is a no-op on a nil diagnostics.
+func (d *diagnostics) warn(kind misuse, stack []byte) {
+	if d == nil || d.warned[kind].Swap(true) {
+		return
+	}
+
+	d.logger.Warn(misuseMessages[kind], "stack", string(stack))
+}
+
+// observeHold records hold, the time a connection was held, warning if it lasted over holdAnomalyFactor times the mean
+// of the previous holds once holdAnomalySamples were observed. It is a no-op on a nil diagnostics.
+func (d *diagnostics) observeHold(hold time.Duration) {
+	if d == nil {
+		return
+	}
+
+	if n := d.holds.Load(); n >= holdAnomalySamples && int64(hold) > holdAnomalyFactor*max(d.held.Load()/n, 1) && !d.warned[misuseLongHold].Load() {
+		d.warn(misuseLongHold, debug.Stack())
+	}
+
+	d.holds.Add(1)
+	d.held.Add(int64(hold))
+}
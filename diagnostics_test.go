@@ -0,0 +1,140 @@
+package connectpool
+
+import (
+	"bytes"
+	"log/slog"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HuXin0817/ConnectPool/clock"
+)
+
+// logBuffer collects the log output of a diagnostics logger, safe for the finalizer goroutine.
+type logBuffer struct {
+	mutex  sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.buffer.Write(p)
+}
+
+// count returns the number of times message was logged.
+func (b *logBuffer) count(message string) int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return strings.Count(b.buffer.String(), message)
+}
+
+// newDiagnosedPool creates a pool configured by options in diagnostics mode logging to the returned buffer.
+func newDiagnosedPool(t *testing.T, options ...Option) (*connectPool, *logBuffer) {
+	logs := new(logBuffer)
+	return newTestPool(t, counter(), append(options, WithDiagnostics(slog.New(slog.NewTextHandler(logs, nil))))...), logs
+}
+
+func TestDiagnostics_CancelTwice(t *testing.T) {
+	p, logs := newDiagnosedPool(t)
+
+	for i := 0; i < 2; i++ {
+		_, cancel := p.Register()
+		cancel()
+		cancel()
+		cancel()
+	}
+
+	if n := logs.count(misuseMessages[misuseCancelTwice]); n != 1 {
+		t.Fatalf("cancel-twice warning logged %d times, want 1", n)
+	}
+
+	if logs.count("TestDiagnostics_CancelTwice") == 0 {
+		t.Fatal("the warning does not carry the stack of the misuse")
+	}
+}
+
+func TestDiagnostics_ForgottenCancel(t *testing.T) {
+	p, logs := newDiagnosedPool(t)
+
+	// Leaks two hand-outs, dropping their cancelFuncs
+	for i := 0; i < 2; i++ {
+		p.Register()
+	}
+
+	for deadline := time.Now().Add(time.Second); logs.count(misuseMessages[misuseForgottenCancel]) == 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("forgotten cancelFunc never reported")
+		}
+
+		runtime.GC()
+		time.Sleep(time.Millisecond)
+	}
+
+	runtime.GC()
+	time.Sleep(10 * time.Millisecond)
+
+	if n := logs.count(misuseMessages[misuseForgottenCancel]); n != 1 {
+		t.Fatalf("forgotten-cancel warning logged %d times, want 1", n)
+	}
+}
+
+func TestDiagnostics_InvalidDeadline(t *testing.T) {
+	p, logs := newDiagnosedPool(t)
+
+	for _, deadline := range []time.Duration{0, -time.Second} {
+		_, cancel := p.RegisterWithTimeLimit(deadline)
+		cancel()
+	}
+
+	if n := logs.count(misuseMessages[misuseInvalidDeadline]); n != 1 {
+		t.Fatalf("invalid-deadline warning logged %d times, want 1", n)
+	}
+}
+
+func TestDiagnostics_LongHold(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	p, logs := newDiagnosedPool(t, WithClock(fake), WithTestabilityMode())
+
+	hold := func(d time.Duration) {
+		_, cancel := p.Register()
+		fake.Advance(d)
+		cancel()
+	}
+
+	for i := 0; i < holdAnomalySamples; i++ {
+		hold(time.Second)
+	}
+
+	if n := logs.count(misuseMessages[misuseLongHold]); n != 0 {
+		t.Fatalf("long-hold warning logged %d times for usual holds, want 0", n)
+	}
+
+	// Both holds are far above the mean of the previous ones
+	hold(time.Hour)
+	hold(100 * time.Hour)
+
+	if n := logs.count(misuseMessages[misuseLongHold]); n != 1 {
+		t.Fatalf("long-hold warning logged %d times, want 1", n)
+	}
+}
+
+func TestDiagnostics_Disabled(t *testing.T) {
+	p := newTestPool(t, counter())
+
+	// Without diagnostics the misuses are tolerated silently, without paying for a stack
+	_, cancel := p.Register()
+	cancel()
+
+	if allocs := testing.AllocsPerRun(10, cancel); allocs != 0 {
+		t.Fatalf("%v allocations per repeated cancel, want none", allocs)
+	}
+
+	if p.diagnostics != nil {
+		t.Fatal("diagnostics enabled without WithDiagnostics")
+	}
+}
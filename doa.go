@@ -0,0 +1,48 @@
+package connectpool
+
+import "time"
+
+// checkDeadOnArrival counts c as dead on arrival if its holder discarded it within the WithDOAWindow window of its
+// creation. The failure also counts against the creation failure rate, so WithCreationFailureAlert fires sooner for a
+// factory handing out broken connections.
+func (p *connectPool) checkDeadOnArrival(c connector) {
+	if p.doaWindow <= 0 || !c.IsDiscarded() {
+		return
+	}
+
+	// A connection invalidated by the pool was not found broken by its holder
+	if _, invalidated := c.Invalidation(); invalidated {
+		return
+	}
+
+	age := c.Age()
+	if age > p.doaWindow {
+		return
+	}
+
+	p.deadOnArrival.Add(1)
+	p.creations.record(false, p.executor.Submit)
+	p.emit(EventDeadOnArrival)
+
+	if p.onDeadOnArrival == nil {
+		return
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			p.handlePanic(r)
+		}
+	}()
+
+	p.onDeadOnArrival(c.GetConnect(), age)
+}
+
+// WithDOAWindow counts a connection discarded by its holder, directly or through ErrBadConn, within window of its
+// creation as dead on arrival: it is reported in Stats().DeadOnArrival, to the event hooks as EventDeadOnArrival and
+// to onDeadOnArrival, if not nil, with its age. 0 disables the tracking.
+func WithDOAWindow(window time.Duration, onDeadOnArrival func(conn any, age time.Duration)) Option {
+	return func(pool *connectPool) {
+		pool.doaWindow = window
+		pool.onDeadOnArrival = onDeadOnArrival
+	}
+}
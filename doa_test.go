@@ -0,0 +1,106 @@
+package connectpool
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HuXin0817/ConnectPool/clock"
+)
+
+func TestDeadOnArrival_Accounting(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+
+	var (
+		mutex  sync.Mutex
+		ages   []time.Duration
+		events int
+		alerts []float64
+	)
+	p := newTestPool(t, counter(), WithClock(fake), WithTestabilityMode(), WithCreationWindow(time.Hour),
+		WithCreationFailureAlert(0.5, func(rate float64) {
+			mutex.Lock()
+			alerts = append(alerts, rate)
+			mutex.Unlock()
+		}),
+		WithEventHook(func(_ ConnectPool, event Event) {
+			if event == EventDeadOnArrival {
+				mutex.Lock()
+				events++
+				mutex.Unlock()
+			}
+		}),
+		WithDOAWindow(time.Second, func(_ any, age time.Duration) {
+			mutex.Lock()
+			ages = append(ages, age)
+			mutex.Unlock()
+		}))
+
+	// failAfter has the connection found broken once it was held for d
+	failAfter := func(d time.Duration) error {
+		return p.DoWithRetry(context.Background(), func(any) error {
+			fake.Advance(d)
+			return ErrBadConn
+		}, 0)
+	}
+
+	check := func(when string, deadOnArrival uint64, wantAges []time.Duration, wantAlerts []float64) {
+		t.Helper()
+
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if got := p.Stats().DeadOnArrival; got != deadOnArrival || events != int(deadOnArrival) ||
+			!slices.Equal(ages, wantAges) || !slices.Equal(alerts, wantAlerts) {
+			t.Fatalf("%s: %d dead on arrival, %d events, ages %v, alerts %v, want %d, %d, %v and %v",
+				when, got, events, ages, alerts, deadOnArrival, deadOnArrival, wantAges, wantAlerts)
+		}
+	}
+
+	// A connection broken 100ms after its creation is dead on arrival, and counts as a failed creation, so one
+	// success and one failure trip the 50% creation failure alert
+	if err := failAfter(100 * time.Millisecond); !errors.Is(err, ErrBadConn) {
+		t.Fatalf("DoWithRetry error = %v, want %v", err, ErrBadConn)
+	}
+	check("an immediate failure", 1, []time.Duration{100 * time.Millisecond}, []float64{0.5})
+
+	// A connection broken after the window is an ordinary failure
+	if err := failAfter(2 * time.Second); !errors.Is(err, ErrBadConn) {
+		t.Fatalf("DoWithRetry error = %v, want %v", err, ErrBadConn)
+	}
+	check("a late failure", 1, []time.Duration{100 * time.Millisecond}, []float64{0.5})
+
+	// A connection the pool reclaimed while it was held was not found broken by its holder
+	conn, err := p.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.EvictIf(func(any, bool, time.Duration) bool { return true })
+	conn.(*acquiredConn).connector.Discard()
+	_ = conn.Close()
+	check("a failure after an eviction", 1, []time.Duration{100 * time.Millisecond}, []float64{0.5})
+}
+
+func TestDeadOnArrival_Disabled(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+
+	var alerts int
+	p := newTestPool(t, counter(), WithClock(fake), WithTestabilityMode(), WithCreationWindow(time.Hour),
+		WithCreationFailureAlert(0.5, func(float64) { alerts++ }))
+
+	// Without WithDOAWindow, an immediate failure is neither counted nor held against the creations
+	err := p.DoWithRetry(context.Background(), func(any) error {
+		fake.Advance(100 * time.Millisecond)
+		return ErrBadConn
+	}, 0)
+	if !errors.Is(err, ErrBadConn) {
+		t.Fatalf("DoWithRetry error = %v, want %v", err, ErrBadConn)
+	}
+
+	if got := p.Stats().DeadOnArrival; got != 0 || alerts != 0 {
+		t.Fatalf("%d dead on arrival, %d alerts without a window, want 0 and 0", got, alerts)
+	}
+}
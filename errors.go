@@ -0,0 +1,31 @@
+package connectpool
+
+import "errors"
+
+var (
+	ErrConnectNotFound       = errors.New("connectpool: connection not found in pool")                                   // The connection is not held by any connector of the pool
+	ErrBatchTooLarge         = errors.New("connectpool: batch is larger than the pool's cap")                            // RegisterN asked for more connections than the pool can ever hold
+	ErrNegativeBatch         = errors.New("connectpool: batch size is negative")                                         // RegisterN or TryRegisterN asked for fewer than zero connections
+	ErrConnectPanicked       = errors.New("connectpool: connectMethod panicked")                                         // The connectMethod panicked while creating a connection
+	ErrConnectReleased       = errors.New("connectpool: connection already released")                                    // Close was called through a proxy whose connection was already released
+	ErrNoResetMethod         = errors.New("connectpool: no reset method configured")                                     // Reset was called on a pool without WithResetMethod
+	ErrResetPanicked         = errors.New("connectpool: resetMethod panicked")                                           // The resetMethod panicked while resetting a connection
+	ErrHealthCheckPanicked   = errors.New("connectpool: healthCheck panicked")                                           // The healthCheck panicked while checking a connection
+	ErrBudgetExhausted       = errors.New("connectpool: creation budget exhausted")                                      // WithCreationBudget's budget is spent and no connector can be given back
+	ErrPoolClosed            = errors.New("connectpool: pool is closed")                                                 // The pool was closed before a connection could be handed out
+	ErrPoolDraining          = errors.New("connectpool: pool is handing off to another pool")                            // Handoff stopped the pool's acquisitions
+	ErrForeignPool           = errors.New("connectpool: pool was not created by NewConnectPool")                         // A ConnectPool implemented outside this package lacks the capability the call needs
+	ErrInvalidConfig         = errors.New("connectpool: invalid configuration")                                          // Reconfigure was given options resulting in an inconsistent configuration
+	ErrNotReconfigurable     = errors.New("connectpool: option cannot be applied to a running pool")                     // Reconfigure was given an option only NewConnectPool can apply
+	ErrConnType              = errors.New("connectpool: connection is not of the Pool's type")                           // A Pool was handed a connection its connectMethod did not create, such as one from WithDialerPerPool
+	ErrUnhealthy             = errors.New("connectpool: connection failed its health check")                             // TestConnector found the connection broken
+	ErrBadConn               = errors.New("connectpool: bad connection")                                                 // Returned by a DoWithRetry function, wrapped or not, to have the connection closed and the call retried
+	ErrSharedPanicked        = errors.New("connectpool: DoShared function panicked")                                     // The function run by DoShared panicked, reported to every caller sharing the run
+	ErrPoolFailed            = errors.New("connectpool: pool failed")                                                    // The pool reached a condition configured by WithFatalOn, wrapped by the sticky error of Err
+	ErrConnNotUnique         = errors.New("connectpool: connection value is not unique and hashable")                    // Classic cannot track a connection whose value is not hashable or already handed out
+	ErrConnectTimeout        = errors.New("connectpool: connectMethod timed out")                                        // The connectMethod did not return within WithConnectMethodTimeout
+	ErrConnectorFuncPanicked = errors.New("connectpool: function run by Connector.DoE panicked")                         // The function passed to Connector.DoE panicked
+	ErrNilConnectMethod      = errors.New("connectpool: connectMethod is nil")                                           // Panicked by the constructors given no connectMethod, which would hand out nil connections
+	ErrNotCloser             = errors.New("connectpool: close interception requires connections implementing io.Closer") // WithCloseInterception was used with a connection type it cannot wrap
+	ErrConcreteConn          = errors.New("connectpool: close interception would hide the connection's methods")         // WithCloseInterception was used with a concrete type its holders would type-assert to
+)
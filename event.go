@@ -0,0 +1,60 @@
+package connectpool
+
+// Event identifies a change of the pool's state reported to event hooks.
+type Event int
+
+const (
+	EventAcquire       Event = iota // A connection was handed out to a caller
+	EventRelease                    // A connection was given back by its caller
+	EventCreate                     // A new connector was created
+	EventEvict                      // A connector was removed from the pool
+	EventClose                      // The pool was closed
+	EventDeadOnArrival              // A connection was discarded within the WithDOAWindow window of its creation
+)
+
+var eventNames = [...]string{
+	EventAcquire:       "acquire",
+	EventRelease:       "release",
+	EventCreate:        "create",
+	EventEvict:         "evict",
+	EventClose:         "close",
+	EventDeadOnArrival: "dead_on_arrival",
+}
+
+func (e Event) String() string {
+	if e < 0 || int(e) >= len(eventNames) {
+		return "unknown"
+	}
+
+	return eventNames[e]
+}
+
+// eventHookFactory creates the event hook of a pool.
+type eventHookFactory = func(pool ConnectPool) (eventHook func(pool ConnectPool, event Event), err error)
+
+// emit reports event to every registered event hook, handling any panic with dealPanicMethod.
+func (p *connectPool) emit(event Event) {
+	switch event {
+	case EventCreate, EventEvict, EventClose:
+		// These events change the pool's size
+		p.trackCap()
+		p.sizeWatchers.notify()
+		p.idleSignal.signal()
+	}
+
+	if p.invariantChecks {
+		p.checkInvariants(event)
+	}
+
+	for _, hook := range p.eventHooks {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					p.handlePanic(r)
+				}
+			}()
+
+			hook(p, event)
+		}()
+	}
+}
@@ -0,0 +1,45 @@
+package connectpool
+
+import "time"
+
+// ForceEvictAll removes every connection, pinned and in use ones included, closes them at once with closeMethod and
+// returns how many were closed. Holders of the connections in use are not waited for: their release no longer acts on
+// the connection, and Invalidated reports it reclaimed. It is meant for emergency shutdowns; Flush only closes idle
+// connections.
+func (p *connectPool) ForceEvictAll() int {
+	all := p.pool.TakeAll()
+	p.closeInOrder(all, CloseEvicted)
+
+	for range all {
+		p.emit(EventEvict)
+	}
+
+	return len(all)
+}
+
+// EvictIf removes every connection for which predicate returns true, given the connection, whether it is in use and
+// the age of its connector, and returns how many were removed. Idle connections are closed at once with closeMethod,
+// connections in use are marked pending close and closed when released, and pinned ones are kept. predicate runs
+// under the pool's lock, so it must not call the pool; a panic of predicate is handled by the panic handler and keeps
+// the connection.
+func (p *connectPool) EvictIf(predicate func(conn any, isWorking bool, age time.Duration) bool) int {
+	idle, evicted := p.pool.RemoveMatching(func(c connector) (matched bool) {
+		defer func() {
+			if r := recover(); r != nil {
+				matched = false
+
+				p.handlePanic(r)
+			}
+		}()
+
+		return predicate(c.GetConnect(), !c.IsFree(), c.Age())
+	})
+
+	p.closeInOrder(idle, CloseEvicted)
+
+	for i := 0; i < evicted; i++ {
+		p.emit(EventEvict)
+	}
+
+	return evicted
+}
@@ -0,0 +1,31 @@
+package connectpool
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+func TestForceEvictAll_EmptiesPool(t *testing.T) {
+	var closes atomic.Int64
+	p := newTestPool(t, counter(), WithTestabilityMode(), WithCloseMethod(func(any) { closes.Add(1) }))
+
+	_, first := p.Register()
+	_, second := p.Register()
+	_, idle := p.Register()
+	idle()
+
+	if n := p.ForceEvictAll(); n != 3 {
+		t.Fatalf("ForceEvictAll evicted %d connections, want 3", n)
+	}
+	if size, closed := p.Size(), closes.Load(); size != 0 || closed != 3 {
+		t.Fatalf("size %d with %d connections closed right after ForceEvictAll, want 0 and 3", size, closed)
+	}
+
+	// The holders' releases no longer act on the evicted connections
+	first()
+	second()
+
+	if size, closed := p.Size(), closes.Load(); size != 0 || closed != 3 {
+		t.Fatalf("size %d with %d connections closed after the holders released, want 0 and 3", size, closed)
+	}
+}
@@ -0,0 +1,110 @@
+// Command custompool builds a tiny priority-aware pool from the package's exported primitives: a ConnectorSet
+// provides the connectors and closes idle ones, while the pool only adds its own queuing, serving high priority
+// waiters first.
+package main
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	connectpool "github.com/HuXin0817/ConnectPool"
+)
+
+// priorityPool hands out up to cap connectors, waking high priority waiters before low priority ones.
+type priorityPool struct {
+	set   *connectpool.ConnectorSet
+	cap   int
+	mutex sync.Mutex
+	high  []chan *connectpool.Connector // High priority waiters, in arrival order
+	low   []chan *connectpool.Connector // Low priority waiters, in arrival order
+}
+
+func newPriorityPool(callbacks connectpool.Callbacks, cap int) *priorityPool {
+	return &priorityPool{
+		set: connectpool.NewConnectorSet(callbacks, time.Second, 100*time.Millisecond),
+		cap: cap,
+	}
+}
+
+// get acquires a connector, waiting behind the earlier waiters of the same or a higher priority.
+func (p *priorityPool) get(high bool) *connectpool.Connector {
+	p.mutex.Lock()
+
+	if c := p.set.AcquireFree(); c != nil {
+		p.mutex.Unlock()
+		return c
+	}
+
+	// Adds under the lock, so that concurrent callers cannot all pass the check and exceed cap
+	if p.set.Size() < p.cap {
+		defer p.mutex.Unlock()
+		return p.set.Add()
+	}
+
+	wait := make(chan *connectpool.Connector, 1)
+	if high {
+		p.high = append(p.high, wait)
+	} else {
+		p.low = append(p.low, wait)
+	}
+
+	p.mutex.Unlock()
+	return <-wait
+}
+
+// put hands c to the first waiter, high priority first, or releases it to the set.
+func (p *priorityPool) put(c *connectpool.Connector) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	var wait chan *connectpool.Connector
+	switch {
+	case len(p.high) > 0:
+		wait, p.high = p.high[0], p.high[1:]
+	case len(p.low) > 0:
+		wait, p.low = p.low[0], p.low[1:]
+	default:
+		c.Release()
+		return
+	}
+
+	wait <- c // The connector stays acquired, changing hands directly
+}
+
+func main() {
+	var connects atomic.Int64
+	pool := newPriorityPool(connectpool.Callbacks{
+		Connect: func() any { return connects.Add(1) },
+		Close:   func(connect any) { fmt.Println("closed connection", connect) },
+	}, 1)
+
+	// Holds the only connector so that the others queue up
+	first := pool.get(false)
+
+	var wg sync.WaitGroup
+	var order []string
+	var orderMutex sync.Mutex
+
+	for _, name := range []string{"low-1", "low-2", "high-1"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			c := pool.get(name[0] == 'h')
+			orderMutex.Lock()
+			order = append(order, name)
+			orderMutex.Unlock()
+			pool.put(c)
+		}()
+
+		time.Sleep(10 * time.Millisecond) // Lets each waiter queue up in turn
+	}
+
+	pool.put(first)
+	wg.Wait()
+
+	fmt.Println("served in order:", order) // high-1 overtakes the low priority waiters
+	pool.set.Close()
+}
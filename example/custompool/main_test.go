@@ -0,0 +1,80 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	connectpool "github.com/HuXin0817/ConnectPool"
+	"github.com/HuXin0817/ConnectPool/connectpooltest"
+)
+
+// suitePool drives a priorityPool through the conformance suite, at low priority.
+type suitePool struct{ *priorityPool }
+
+func (p suitePool) Acquire() *connectpool.Connector  { return p.get(false) }
+func (p suitePool) Release(c *connectpool.Connector) { p.put(c) }
+func (p suitePool) Close()                           { p.set.Close() }
+
+func TestPriorityPool_Conformance(t *testing.T) {
+	connectpooltest.TestConnectorSet(t, func(callbacks connectpool.Callbacks, cap int) connectpooltest.Pool {
+		return suitePool{newPriorityPool(callbacks, cap)}
+	})
+}
+
+func TestPriorityPool_NeverExceedsCap(t *testing.T) {
+	const cap = 2
+
+	var connects atomic.Int64
+	pool := newPriorityPool(connectpool.Callbacks{Connect: func() any {
+		time.Sleep(time.Millisecond) // Widens the window between the cap check and the insertion
+		return connects.Add(1)
+	}}, cap)
+	defer pool.set.Close()
+
+	var wg sync.WaitGroup
+	for range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				pool.put(pool.get(false))
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := connects.Load(); n > cap {
+		t.Fatalf("%d connections created, want at most %d", n, cap)
+	}
+}
+
+func TestPriorityPool_HighFirst(t *testing.T) {
+	pool := newPriorityPool(connectpool.Callbacks{Connect: func() any { return 1 }}, 1)
+	defer pool.set.Close()
+
+	first := pool.get(false)
+
+	served := make(chan string, 2)
+	for _, name := range []string{"low", "high"} {
+		go func() {
+			c := pool.get(name == "high")
+			served <- name
+			pool.put(c)
+		}()
+
+		// Waits for the waiter to queue up before starting the next one
+		for queued := false; !queued; time.Sleep(time.Millisecond) {
+			pool.mutex.Lock()
+			queued = len(pool.low) == 1 && (name == "low" || len(pool.high) == 1)
+			pool.mutex.Unlock()
+		}
+	}
+
+	pool.put(first)
+	if name := <-served; name != "high" {
+		t.Fatalf("%s waiter served first, want high", name)
+	}
+	<-served
+}
@@ -1,9 +1,9 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
-	"runtime"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -79,10 +79,7 @@ func main() {
 
 	wq.Wait() // Wait for all goroutines to complete.
 
-	for pool.Size() > 0 {
-		// Wait for the pool to empty.
-		runtime.Gosched()
-	}
+	_ = pool.(connectpool.Inspector).WaitIdle(context.Background(), true) // Wait for every connection to be returned and evicted.
 
 	printInfo() // Print final pool information.
 }
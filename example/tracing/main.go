@@ -0,0 +1,76 @@
+// Command tracing wraps a pool with a middleware timing how long each connection sat idle before being acquired and
+// how long it was then held, using the advanced AcquireConnector API.
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	connectpool "github.com/HuXin0817/ConnectPool"
+)
+
+// span records the use of one connector.
+type span struct {
+	id      uint64        // Token of the connector
+	age     time.Duration // Age of the connector when acquired
+	idleFor time.Duration // Time the connector sat idle before being acquired
+	heldFor time.Duration // Time the connector was held
+}
+
+// tracedPool hands out connections from pool, reporting a span for each release.
+type tracedPool struct {
+	pool   connectpool.Acquirer
+	report func(span)
+}
+
+// Do runs fn with a connection, timing its use.
+func (t *tracedPool) Do(fn func(conn any) error) error {
+	c, release, err := t.pool.AcquireConnector()
+	if err != nil {
+		return err
+	}
+
+	s := span{id: c.ID(), age: time.Since(c.CreatedAt()), idleFor: c.SinceLastWorkingTime()}
+	start := time.Now()
+
+	defer func() {
+		release()
+
+		s.heldFor = time.Since(start)
+		t.report(s)
+	}()
+
+	return c.DoE(fn)
+}
+
+func main() {
+	var mutex sync.Mutex
+	traced := &tracedPool{
+		pool: connectpool.NewConnectPool(func() any { return new(int) }, connectpool.WithCap(2)).(connectpool.Acquirer),
+		report: func(s span) {
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			fmt.Printf("connector %d: age %v, idle %v, held %v\n", s.id, s.age.Round(time.Millisecond), s.idleFor.Round(time.Millisecond), s.heldFor.Round(time.Millisecond))
+		},
+	}
+	defer traced.pool.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_ = traced.Do(func(conn any) error {
+				time.Sleep(10 * time.Millisecond) // Simulates a query
+				return nil
+			})
+		}()
+
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	wg.Wait()
+}
@@ -0,0 +1,120 @@
+package connectpool
+
+import (
+	"sync"
+	"time"
+
+	"github.com/HuXin0817/ConnectPool/clock"
+)
+
+const (
+	defaultCallbackWorkers      = 4               // Default number of goroutines running asynchronous callbacks
+	defaultCallbackQueueSize    = 1024            // Number of callbacks queued before each runs on a goroutine of its own
+	defaultCallbackDrainTimeout = 5 * time.Second // Default time Close waits for queued callbacks
+)
+
+// executor runs asynchronous user callbacks (close methods after eviction, evict hooks) on a bounded set of
+// goroutines, so their cost is not paid on the clear goroutine or a caller's release path. When the queue is full,
+// a callback runs on a goroutine of its own, still awaited by Close; once the executor is closed, callbacks run inline.
+type executor struct {
+	tasks   chan func()    // Queued callbacks
+	workers sync.WaitGroup // Running worker and overflow goroutines
+	mutex   sync.RWMutex   // Protects closed against concurrent Submit
+	closed  bool           // Whether the tasks channel has been closed
+	clock   clock.Clock    // Times the drain on Close
+}
+
+// newExecutor starts an executor with the given number of workers, returning nil (run inline) if workers is not positive.
+func newExecutor(workers int, clock clock.Clock) *executor {
+	if workers <= 0 {
+		return nil
+	}
+
+	e := &executor{
+		tasks: make(chan func(), defaultCallbackQueueSize),
+		clock: clock,
+	}
+
+	e.workers.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer e.workers.Done()
+
+			for task := range e.tasks {
+				task()
+			}
+		}()
+	}
+
+	return e
+}
+
+// Submit queues task, running it on a goroutine of its own if the executor is saturated, and inline if it is nil or
+// closed. task never runs with the executor's lock held, so it may submit further tasks.
+func (e *executor) Submit(task func()) {
+	if e == nil {
+		task()
+		return
+	}
+
+	e.mutex.RLock()
+
+	if e.closed {
+		e.mutex.RUnlock()
+		task()
+		return
+	}
+
+	select {
+	case e.tasks <- task:
+	default:
+		// Added under the lock, so Close cannot have started waiting yet
+		e.workers.Add(1)
+		go func() {
+			defer e.workers.Done()
+			task()
+		}()
+	}
+
+	e.mutex.RUnlock()
+}
+
+// QueueDepth returns the number of callbacks waiting for a worker.
+func (e *executor) QueueDepth() int {
+	if e == nil {
+		return 0
+	}
+
+	return len(e.tasks)
+}
+
+// Close stops accepting callbacks and waits up to timeout for the queued ones to finish,
+// reporting whether they all did.
+func (e *executor) Close(timeout time.Duration) bool {
+	if e == nil {
+		return true
+	}
+
+	e.mutex.Lock()
+	if !e.closed {
+		e.closed = true
+		close(e.tasks)
+	}
+	e.mutex.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		e.workers.Wait()
+		close(drained)
+	}()
+
+	timer := e.clock.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-drained:
+		return true
+	case <-timer.C():
+		return false
+	}
+}
@@ -0,0 +1,106 @@
+package connectpool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/HuXin0817/ConnectPool/clock"
+)
+
+// within fails the test if fn does not return within timeout.
+func within(t *testing.T, timeout time.Duration, what string, fn func()) {
+	t.Helper()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		t.Fatalf("%s did not return within %v", what, timeout)
+	}
+}
+
+// saturate occupies every worker and queue slot of e with tasks blocked until gate is closed.
+func saturate(e *executor, workers int, gate chan struct{}) {
+	for i := 0; i < workers+defaultCallbackQueueSize; i++ {
+		e.Submit(func() { <-gate })
+	}
+}
+
+func TestExecutor_SaturatedSubmitReturns(t *testing.T) {
+	e := newExecutor(1, clock.Real())
+	gate := make(chan struct{})
+	saturate(e, 1, gate)
+
+	// The overflowing task must not run on the submitting goroutine
+	within(t, time.Second, "Submit on a saturated executor", func() { e.Submit(func() { <-gate }) })
+
+	close(gate)
+
+	if !e.Close(time.Second) {
+		t.Fatal("Close did not drain the tasks")
+	}
+}
+
+func TestExecutor_SubmitFromTaskDuringClose(t *testing.T) {
+	e := newExecutor(1, clock.Real())
+	gate := make(chan struct{})
+	saturate(e, 1, gate)
+
+	submitted := make(chan struct{})
+	e.Submit(func() {
+		<-gate
+		e.Submit(func() {}) // Submits while Close waits for the tasks
+		close(submitted)
+	})
+
+	closed := make(chan bool)
+	go func() { closed <- e.Close(time.Second) }()
+
+	time.Sleep(10 * time.Millisecond) // Lets Close start waiting
+	close(gate)
+
+	within(t, time.Second, "a task submitting during Close", func() { <-submitted })
+
+	if !<-closed {
+		t.Fatal("Close did not drain the tasks")
+	}
+}
+
+func TestExecutor_SaturationLeavesAcquisitionsFast(t *testing.T) {
+	gate := make(chan struct{})
+	defer close(gate)
+
+	p := newTestPool(t, counter(), WithCap(2000), WithCallbackWorkers(1), WithCallbackDrainTimeout(time.Millisecond),
+		WithMaxFreeTime(time.Millisecond), WithAutoClearInterval(time.Hour), WithCloseMethod(func(any) { <-gate }))
+
+	_, release, err := p.TryRegisterN(defaultCallbackQueueSize + 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	time.Sleep(5 * time.Millisecond)
+
+	// Evicts more connections than the executor can queue, each close blocking until the test ends
+	within(t, time.Second, "ClearNow with slow close methods", func() { p.ClearNow() })
+
+	if depth := p.Stats().CallbackQueueDepth; depth == 0 {
+		t.Fatal("the executor is not saturated")
+	}
+
+	var slowest time.Duration
+	for i := 0; i < 100; i++ {
+		start := time.Now()
+		_, cancel := p.Register()
+		cancel()
+		slowest = max(slowest, time.Since(start))
+	}
+
+	if slowest > 50*time.Millisecond {
+		t.Fatalf("an acquisition took %v while the executor was saturated", slowest)
+	}
+}
@@ -0,0 +1,30 @@
+package connectpool
+
+import "fmt"
+
+// Explain describes the pool's current state and the configuration driving its behavior, for logging at startup
+// or in debug handlers.
+func (p *connectPool) Explain() string {
+	size, working := p.Size(), p.WorkingNumber()
+
+	selectionStrategy := "any-free"
+	if p.demoteAfter > 0 {
+		selectionStrategy = fmt.Sprintf("LIFO(hot/cold, demoteAfter=%v)", p.demoteAfter)
+	}
+
+	if p.scheduler != nil {
+		selectionStrategy += ", priorityQueues=enabled"
+	}
+
+	healthCheck := "disabled"
+	hasHealthCheck := p.callbacks.Load().healthCheck != nil
+	switch {
+	case hasHealthCheck && p.healthCheckFraction > 0 && p.healthCheckInterval > 0:
+		healthCheck = fmt.Sprintf("sampled(%v every %v)", p.healthCheckFraction, p.healthCheckInterval)
+	case hasHealthCheck:
+		healthCheck = "enabled"
+	}
+
+	return fmt.Sprintf("Pool '%s' (size=%d/%d, working=%d, idle=%d, minSize=%d, selectionStrategy=%s, healthCheck=%s, maxFreeTime=%v, autoClearInterval=%v)",
+		p.Name(), size, p.Cap(), working, max(size-working, 0), p.MinSize(), selectionStrategy, healthCheck, p.MaxFreeTime(), p.AutoClearInterval())
+}
@@ -0,0 +1,94 @@
+package connectpool
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// fatalPanicStreak is the number of consecutive panics of a callback after which FatalClosePanics and
+// FatalConnectPanics put the pool in the failed state.
+const fatalPanicStreak = 10
+
+// FatalCondition names a runtime condition WithFatalOn may treat as unrecoverable.
+type FatalCondition int
+
+const (
+	FatalClosePanics        FatalCondition = iota // The closeMethod panicked on fatalPanicStreak consecutive calls
+	FatalConnectPanics                            // The connectMethod panicked on fatalPanicStreak consecutive calls
+	FatalInvariantViolation                       // An invariant check failed, see WithInvariantChecks
+	fatalConditionCount
+)
+
+var fatalConditionNames = [fatalConditionCount]string{
+	FatalClosePanics:        "close panics",
+	FatalConnectPanics:      "connect panics",
+	FatalInvariantViolation: "invariant violation",
+}
+
+func (c FatalCondition) String() string {
+	if c < 0 || int(c) >= len(fatalConditionNames) {
+		return "unknown"
+	}
+
+	return fatalConditionNames[c]
+}
+
+// fatalState tracks the conditions leading to the failed state and the sticky error once it is reached.
+type fatalState struct {
+	conditions    [fatalConditionCount]bool // Conditions configured by WithFatalOn
+	onFatal       func(err error)           // Called once when the pool fails, nil if none
+	closePanics   atomic.Int64              // Consecutive closeMethod calls that panicked
+	connectPanics atomic.Int64              // Consecutive connectMethod calls that panicked
+	err           atomic.Pointer[error]     // Sticky fatal error, nil until the pool fails
+}
+
+// Err returns the error that put the pool in the failed state, nil while it is healthy.
+func (p *connectPool) Err() error {
+	if err := p.fatal.err.Load(); err != nil {
+		return *err
+	}
+
+	return nil
+}
+
+// fail puts the pool in the failed state with err, unless it has already failed, and calls the onFatal hook.
+func (p *connectPool) fail(condition FatalCondition, err error) {
+	if !p.fatal.conditions[condition] {
+		return
+	}
+
+	err = fmt.Errorf("%w: %s: %w", ErrPoolFailed, condition, err)
+	if !p.fatal.err.CompareAndSwap(nil, &err) {
+		return
+	}
+
+	p.idleSignal.signal() // Wakes the waiters to fail with the sticky error
+
+	if onFatal := p.fatal.onFatal; onFatal != nil {
+		p.executor.Submit(func() {
+			defer func() {
+				if r := recover(); r != nil {
+					p.handlePanic(r)
+				}
+			}()
+
+			onFatal(err)
+		})
+	}
+}
+
+// trackPanicStreak counts a call of a callback that panicked or returned normally, failing the pool with condition
+// once streak reaches fatalPanicStreak consecutive panics.
+func (p *connectPool) trackPanicStreak(condition FatalCondition, streak *atomic.Int64, panicked bool) {
+	if !panicked {
+		if streak.Load() != 0 {
+			streak.Store(0)
+		}
+
+		return
+	}
+
+	if n := streak.Add(1); n >= fatalPanicStreak {
+		p.fail(condition, fmt.Errorf("panicked on %d consecutive calls", n))
+	}
+}
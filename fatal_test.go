@@ -0,0 +1,66 @@
+package connectpool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/HuXin0817/ConnectPool/clock"
+)
+
+func TestWithFatalOn_ClosePanics(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+
+	var fatal atomic.Int64
+	p := newTestPool(t, counter(),
+		WithClock(fake),
+		WithTestabilityMode(),
+		WithCap(fatalPanicStreak+1),
+		WithMaxFreeTime(time.Minute),
+		WithCloseMethod(func(any) { panic("close failed") }),
+		WithDealPanicMethod(func(any) {}),
+		WithFatalOn(FatalClosePanics),
+		WithOnFatal(func(error) { fatal.Add(1) }),
+	)
+
+	// One holder keeps its connection across the failure
+	_, release := p.Register()
+
+	_, cancel, err := p.RegisterN(context.Background(), fatalPanicStreak)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+
+	// Each idle connection panics when the clear pass closes it
+	fake.Advance(2 * time.Minute)
+	p.ClearNow()
+
+	if err = p.Err(); !errors.Is(err, ErrPoolFailed) {
+		t.Fatalf("Err = %v after %d close panics, want ErrPoolFailed", err, fatalPanicStreak)
+	}
+
+	if _, err = p.Acquire(context.Background()); !errors.Is(err, ErrPoolFailed) {
+		t.Fatalf("Acquire error = %v on a failed pool, want ErrPoolFailed", err)
+	}
+
+	if _, cancel := p.Register(); cancel != nil {
+		t.Fatal("Register handed out a connection of a failed pool")
+	}
+
+	release()
+	if n := p.WorkingNumber(); n != 0 {
+		t.Fatalf("%d connections working after the holder released, want 0", n)
+	}
+
+	p.Close()
+	if n := p.Size(); n != 0 {
+		t.Fatalf("%d connections left after Close, want 0", n)
+	}
+
+	if n := fatal.Load(); n != 1 {
+		t.Fatalf("OnFatal called %d times, want 1", n)
+	}
+}
@@ -1,3 +1,5 @@
 module github.com/HuXin0817/ConnectPool
 
 go 1.22.1
+
+require golang.org/x/time v0.5.0
@@ -0,0 +1,121 @@
+// Package grpcpool pools *grpc.ClientConn connections to a gRPC target with connectpool.
+package grpcpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	connectpool "github.com/HuXin0817/ConnectPool"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+var (
+	ErrNoConn     = errors.New("grpcpool: target failed to provide a connection") // The pool handed out a connection that could not be dialed
+	ErrNotServing = errors.New("grpcpool: target is not serving")                 // The health check found the target not serving
+)
+
+const healthCheckTimeout = 5 * time.Second // Upper bound on a health check call
+
+// NewGRPCPool returns an Acquirer pool of up to maxSize *grpc.ClientConn connections created for target with dialOpts.
+// Connections are closed with (*grpc.ClientConn).Close and health-checked with the standard gRPC health service.
+// It fails if target or dialOpts are invalid.
+func NewGRPCPool(target string, dialOpts []grpc.DialOption, maxSize int) (connectpool.Acquirer, error) {
+	// Creating a client does not connect: the first one validates target and dialOpts, then becomes the first connection
+	first, err := grpc.NewClient(target, dialOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending atomic.Pointer[grpc.ClientConn]
+	pending.Store(first)
+
+	connectMethod := func() any {
+		if conn := pending.Swap(nil); conn != nil {
+			return conn
+		}
+
+		conn, err := grpc.NewClient(target, dialOpts...)
+		if err != nil {
+			return err // Handed out as is, RegisterGRPC reports it and the pool closes it on return
+		}
+
+		return conn
+	}
+
+	return connectpool.NewConnectPool(connectMethod,
+		connectpool.WithCap(maxSize),
+		connectpool.WithCloseMethodE(closeConn, false),
+		connectpool.WithHealthCheck(checkConn),
+		connectpool.WithValidateOnReturn(isConn),
+		connectpool.WithEventHook(func(_ connectpool.ConnectPool, event connectpool.Event) {
+			// The first client is closed with the pool if the pool never needed it
+			if event != connectpool.EventClose {
+				return
+			}
+
+			if conn := pending.Swap(nil); conn != nil {
+				_ = conn.Close()
+			}
+		}),
+	).(connectpool.Acquirer), nil
+}
+
+// RegisterGRPC acquires a connection from pool, a pool returned by NewGRPCPool, waiting until ctx is done. The
+// returned function gives the connection back and must be called once it is no longer used.
+func RegisterGRPC(ctx context.Context, pool connectpool.Acquirer) (*grpc.ClientConn, func(), error) {
+	acquired, err := pool.Acquire(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	conn, ok := acquired.Conn().(*grpc.ClientConn)
+	if !ok {
+		_ = acquired.Close()
+
+		if err, ok := acquired.Conn().(error); ok {
+			return nil, nil, fmt.Errorf("%w: %w", ErrNoConn, err)
+		}
+		return nil, nil, ErrNoConn
+	}
+
+	return conn, func() { _ = acquired.Close() }, nil
+}
+
+func closeConn(connect any) error {
+	if conn, ok := connect.(*grpc.ClientConn); ok {
+		return conn.Close()
+	}
+
+	return nil // A client that could not be created holds nothing
+}
+
+// isConn reports whether connect is a client, rather than the error of a client that could not be created.
+func isConn(connect any) bool {
+	_, ok := connect.(*grpc.ClientConn)
+	return ok
+}
+
+func checkConn(connect any) error {
+	conn, ok := connect.(*grpc.ClientConn)
+	if !ok {
+		return ErrNoConn
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	defer cancel()
+
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		return err
+	}
+
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		return ErrNotServing
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package grpcpool
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// serve starts an in-process gRPC server with the health service, returning the options dialing it.
+func serve(t *testing.T) []grpc.DialOption {
+	t.Helper()
+
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	healthpb.RegisterHealthServer(server, health.NewServer())
+
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	return []grpc.DialOption{
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	}
+}
+
+func TestNewGRPCPool(t *testing.T) {
+	pool, err := NewGRPCPool("passthrough:///bufnet", serve(t), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn, release, err := RegisterGRPC(context.Background(), pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A round-trip through the pooled connection
+	if err = checkConn(conn); err != nil {
+		t.Fatalf("health check failed: %v", err)
+	}
+
+	release()
+
+	pool.Close()
+	if state := conn.GetState(); state != connectivity.Shutdown {
+		t.Fatalf("connection %v after Close, want %v", state, connectivity.Shutdown)
+	}
+}
+
+func TestNewGRPCPool_InvalidOptions(t *testing.T) {
+	// Without transport credentials the client cannot be created
+	if _, err := NewGRPCPool("passthrough:///bufnet", nil, 1); err == nil {
+		t.Fatal("NewGRPCPool succeeded without transport credentials")
+	}
+}
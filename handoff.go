@@ -0,0 +1,84 @@
+package connectpool
+
+import "context"
+
+// HandoffReport counts what Handoff did with the idle connections of the old pool.
+type HandoffReport struct {
+	Transferred int // Number of connections adopted by the new pool
+	Closed      int // Number of connections closed instead
+}
+
+// Handoff replaces old by next without dropping connections: it stops the acquisitions of old, which then fail with
+// ErrPoolDraining, waits up to ctx for its holders to release their connections, moves each idle connection adoptable
+// accepts into next while next has room, closes the others and closes old. Connections still held when ctx is done
+// are closed once released, and ctx's error is returned with the report. A nil adoptable transfers nothing; a panic of
+// adoptable is handled by old's panic handler and closes the connection.
+func Handoff(ctx context.Context, old, next ConnectPool, adoptable func(conn any) bool) (HandoffReport, error) {
+	from, ok := old.(*connectPool)
+	to, nextOK := next.(*connectPool)
+	if !ok || !nextOK {
+		return HandoffReport{}, ErrForeignPool
+	}
+
+	from.draining.Store(true)
+	from.idleSignal.signal() // Wakes the waiters to fail with ErrPoolDraining
+	err := from.WaitIdle(ctx, false)
+
+	var report HandoffReport
+	var rest []connector
+
+	for _, c := range from.pool.TakeIdle() {
+		if from.adoptable(c, adoptable) && to.adopt(c.GetConnect()) {
+			report.Transferred++
+			from.emit(EventEvict)
+			continue
+		}
+
+		rest = append(rest, c)
+	}
+
+	from.closeInOrder(rest, CloseShutdown)
+	report.Closed = len(rest)
+	for range rest {
+		from.emit(EventEvict)
+	}
+
+	from.Close()
+	return report, err
+}
+
+// adoptable reports whether adoptable accepts the connection of c, handling its panic as a refusal.
+func (p *connectPool) adoptable(c connector, adoptable func(conn any) bool) (accepted bool) {
+	if adoptable == nil || c.GetConnect() == nil {
+		return false
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			accepted = false
+
+			p.handlePanic(r)
+		}
+	}()
+
+	return adoptable(c.GetConnect())
+}
+
+// adopt adds connect, a connection created elsewhere, to the pool as an idle connection, reporting whether the pool
+// had room for it.
+func (p *connectPool) adopt(connect any) bool {
+	if p.Err() != nil || p.draining.Load() || p.Size() >= p.Cap() {
+		return false
+	}
+
+	c := p.pool.AdoptConnector(connect)
+	if c == nil {
+		return false // The pool was closed
+	}
+
+	p.labelConnector(c)
+	p.sampleSize(c)
+	p.emit(EventCreate)
+	c.ReleaseUnused()
+	return true
+}
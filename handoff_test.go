@@ -0,0 +1,85 @@
+package connectpool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHandoff_LiveWorkload(t *testing.T) {
+	old := newTestPool(t, counter(), WithCap(4))
+	next := newTestPool(t, counter(), WithCap(8)) // Room for the workers' connections and the transferred ones
+
+	var current atomic.Pointer[connectPool]
+	current.Store(old)
+
+	var acquired, failed atomic.Int64
+	stop := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+
+				ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+				conn, err := current.Load().Acquire(ctx)
+				cancel()
+
+				switch {
+				case errors.Is(err, ErrPoolDraining) || errors.Is(err, ErrPoolClosed):
+					continue // The pointer was swapped after it was loaded, the next load sees the new pool
+				case err != nil:
+					failed.Add(1)
+					t.Error(err)
+					continue
+				}
+
+				time.Sleep(100 * time.Microsecond) // Simulates a query
+				_ = conn.Close()
+				acquired.Add(1)
+			}
+		}()
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	current.Store(next)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	report, err := Handoff(ctx, old, next, func(any) bool { return true })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before := acquired.Load()
+	time.Sleep(20 * time.Millisecond)
+	close(stop)
+	wg.Wait()
+
+	if failed.Load() > 0 {
+		t.Fatalf("%d acquisitions failed", failed.Load())
+	}
+
+	if acquired.Load() == before {
+		t.Fatal("no acquisition succeeded on the new pool")
+	}
+
+	if report.Transferred == 0 || uint64(report.Transferred+report.Closed) != old.Stats().TotalCreated {
+		t.Fatalf("report %+v, want every connection of the old pool accounted for, some transferred", report)
+	}
+
+	if size := old.Size(); size != 0 {
+		t.Fatalf("old pool still holds %d connectors", size)
+	}
+}
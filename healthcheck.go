@@ -0,0 +1,69 @@
+package connectpool
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// checkHealth runs the healthCheck method against c, treating a panic as a failure.
+func (p *connectPool) checkHealth(c connector) (err error) {
+	healthCheck := p.callbacks.Load().healthCheck
+	if healthCheck == nil {
+		return nil
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", ErrHealthCheckPanicked, r)
+
+			p.handlePanic(r)
+		}
+	}()
+
+	return healthCheck(c.GetConnect())
+}
+
+// sampledHealthCheck checks a random fraction of the idle connectors every interval until the pool is closed,
+// evicting the ones that fail. A connector checked in one pass is skipped for the next ceil(1/fraction)-1 passes,
+// so every idle connector is checked within about 1/fraction intervals.
+func (p *connectPool) sampledHealthCheck(fraction float64, interval time.Duration) {
+	skip := uint64(math.Ceil(1/fraction)) - 1
+
+	for pass := uint64(1); p.sleep(interval, nil); pass++ {
+		// Reads the callbacks on each pass, so the pass closes with the current closeMethod
+		closeMethod := p.closeMethodFor(CloseUnhealthy, p.callbacks.Load().closeMethod)
+
+		// Claims the sample so that it cannot be handed out while it is being checked
+		sample := p.pool.ClaimIdleSample(fraction, func(c connector) bool {
+			last := c.HealthCheckPass()
+			return last == 0 || pass-last > skip
+		})
+
+		for _, c := range sample {
+			c.SetHealthCheckPass(pass)
+
+			if p.checkHealth(c) != nil && p.pool.RemoveConnector(c, closeMethod) {
+				p.emit(EventEvict)
+				continue
+			}
+
+			c.RestoreIdle()
+		}
+	}
+}
+
+// TestConnector runs the health check against the connector holding conn, which may be in use. A failure is
+// reported as ErrUnhealthy wrapping the health check's error. Without WithHealthCheck every connection passes.
+func (p *connectPool) TestConnector(conn any) error {
+	c := p.findConnector(conn)
+	if c == nil {
+		return ErrConnectNotFound
+	}
+
+	if err := p.checkHealth(c); err != nil {
+		return fmt.Errorf("%w: %w", ErrUnhealthy, err)
+	}
+
+	return nil
+}
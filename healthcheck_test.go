@@ -0,0 +1,175 @@
+package connectpool
+
+import (
+	"math/rand"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HuXin0817/ConnectPool/clock"
+)
+
+func TestSampledHealthCheck_Coverage(t *testing.T) {
+	const (
+		idle     = 10
+		fraction = 0.25
+		interval = time.Minute
+	)
+
+	fake := clock.NewFake(time.Unix(0, 0))
+
+	var (
+		mutex     sync.Mutex
+		firstPass = make(map[any]uint64) // Pass that first checked each connection
+		p         *connectPool
+	)
+	p = newTestPool(t, counter(), WithClock(fake), WithMaxFreeTime(time.Hour), WithSampledHealthCheck(fraction, interval), WithHealthCheck(func(connect any) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if _, ok := firstPass[connect]; !ok {
+			firstPass[connect] = p.findConnector(connect).HealthCheckPass()
+		}
+		return nil
+	}))
+
+	working, cancelWorking := p.Register()
+	defer cancelWorking()
+
+	cancels := make([]func(), 0, idle)
+	for range idle {
+		_, cancel := p.Register()
+		cancels = append(cancels, cancel)
+	}
+	for _, cancel := range cancels {
+		cancel()
+	}
+
+	checked := func() int {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		return len(firstPass)
+	}
+
+	for deadline := time.Now().Add(time.Second); checked() < idle; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d of %d idle connectors checked", checked(), idle)
+		}
+
+		fake.Advance(interval)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if _, ok := firstPass[working]; ok {
+		t.Fatal("working connector sampled")
+	}
+
+	for connect, pass := range firstPass {
+		if pass > 4 {
+			t.Fatalf("connection %v first checked in pass %d, want within 1/fraction = 4 passes", connect, pass)
+		}
+	}
+}
+
+func TestSampledHealthCheck_EvictsWithCurrentCloseMethod(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	p := newTestPool(t, counter(), WithClock(fake), WithMaxFreeTime(time.Hour), WithSampledHealthCheck(1, time.Minute), WithHealthCheck(func(any) error { return ErrUnhealthy }))
+
+	_, cancel := p.Register()
+	cancel()
+
+	// Replaces closeMethod after the health check started
+	closed := make(chan any, 1)
+	p.callbacks.update(func(next *callbacks) { next.closeMethod = func(connect any) { closed <- connect } })
+
+	for deadline := time.Now().Add(time.Second); p.Size() > 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("unhealthy connector not evicted")
+		}
+
+		fake.Advance(time.Minute)
+	}
+
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("evicted connector not closed with the current closeMethod")
+	}
+}
+
+func TestWithRandSource_ReproducibleSamples(t *testing.T) {
+	const (
+		idle   = 10
+		passes = 6
+	)
+
+	// samples returns the connections each pass of the sampled health check checked, in the order it checked them
+	samples := func(seed int64) [][]any {
+		fake := clock.NewFake(time.Unix(0, 0))
+
+		var (
+			mutex   sync.Mutex
+			checked [][]any
+		)
+		p := newTestPool(t, counter(), WithTestabilityMode(), WithClock(fake), WithMaxFreeTime(time.Hour),
+			WithDeterministicOrdering(true), WithRandSource(rand.NewSource(seed)),
+			WithSampledHealthCheck(0.3, time.Minute), WithHealthCheck(func(connect any) error {
+				mutex.Lock()
+				defer mutex.Unlock()
+
+				checked[len(checked)-1] = append(checked[len(checked)-1], connect)
+				return nil
+			}))
+		defer p.Close()
+
+		cancels := make([]func(), 0, idle)
+		for range idle {
+			_, cancel := p.Register()
+			cancels = append(cancels, cancel)
+		}
+		for _, cancel := range cancels {
+			cancel()
+		}
+
+		waitUntil(t, "the health check sleeps", func() bool { return fake.Pending() > 0 })
+		pending := fake.Pending()
+
+		for range passes {
+			mutex.Lock()
+			checked = append(checked, nil)
+			mutex.Unlock()
+
+			// The pass is over once the health check sleeps again
+			fake.Advance(time.Minute)
+			waitUntil(t, "the pass ends", func() bool { return fake.Pending() == pending })
+		}
+
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		return checked
+	}
+
+	first := samples(42)
+	if again := samples(42); !reflect.DeepEqual(first, again) {
+		t.Fatalf("seed 42 sampled %v, then %v", first, again)
+	}
+
+	// Each pass draws ceil(0.3 × 10) = 3 connectors among those not checked in the last 3 passes
+	sizes := make([]int, 0, passes)
+	for _, sample := range first {
+		sizes = append(sizes, len(sample))
+	}
+	if want := []int{3, 3, 3, 1, 3, 3}; !reflect.DeepEqual(sizes, want) {
+		t.Fatalf("passes checked %v connections, want %v", sizes, want)
+	}
+
+	// The decisions come from the source, not from the order of the connectors
+	if other := samples(7); reflect.DeepEqual(first, other) {
+		t.Fatalf("seeds 42 and 7 both sampled %v", first)
+	}
+}
@@ -0,0 +1,108 @@
+package connectpool
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/HuXin0817/ConnectPool/clock"
+)
+
+// Bucket counts what happened to the pool during one slice of time recorded by WithHistory.
+type Bucket struct {
+	Start           time.Time                // Time the bucket begins
+	Creations       uint64                   // Connectors created successfully
+	Evictions       [closeReasonCount]uint64 // Connections closed, indexed by CloseReason
+	AcquireTimeouts uint64                   // Acquisitions that gave up because their deadline passed
+	PeakWorking     int64                    // Largest number of connections in use after a hand-out during the bucket
+}
+
+// EvictionsByReason returns the connections closed during the bucket per reason name.
+func (b Bucket) EvictionsByReason() map[string]uint64 {
+	evictions := make(map[string]uint64, closeReasonCount)
+	for reason := range closeReasonCount {
+		evictions[reason.String()] = b.Evictions[reason]
+	}
+
+	return evictions
+}
+
+// historyBucket is a Bucket along with the index of the slice of time it currently counts.
+type historyBucket struct {
+	index int64 // Index of the slice of time, bucket width apart since the Unix epoch
+	Bucket
+}
+
+// history keeps the most recent buckets in a fixed ring, so its memory stays constant however long the pool runs.
+type history struct {
+	mutex   sync.Mutex
+	width   time.Duration   // Length of each bucket
+	buckets []historyBucket // Ring of buckets, indexed by slice index modulo its length
+	clock   clock.Clock     // Clock placing events in buckets
+}
+
+func newHistory(width time.Duration, buckets int, clock clock.Clock) *history {
+	h := &history{width: width, buckets: make([]historyBucket, buckets), clock: clock}
+
+	// Marks the slots unused, as index 0 is the valid slice of time starting at the Unix epoch
+	for i := range h.buckets {
+		h.buckets[i].index = math.MinInt64
+	}
+
+	return h
+}
+
+// record changes the bucket of the current time with change. A nil history records nothing.
+func (h *history) record(change func(b *Bucket)) {
+	if h == nil {
+		return
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	index := h.clock.Now().UnixNano() / int64(h.width)
+
+	// Reuses the slot of the bucket that fell out of the ring
+	bucket := h.slot(index)
+	if bucket.index != index {
+		*bucket = historyBucket{index: index, Bucket: Bucket{Start: time.Unix(0, index*int64(h.width))}}
+	}
+
+	change(&bucket.Bucket)
+}
+
+// slot returns the ring slot of the slice of time index. It must be called with the mutex held.
+func (h *history) slot(index int64) *historyBucket {
+	n := int64(len(h.buckets))
+	return &h.buckets[(index%n+n)%n]
+}
+
+// Buckets returns the buckets still inside the ring, oldest first, including empty ones.
+func (h *history) Buckets() []Bucket {
+	if h == nil {
+		return nil
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	last := h.clock.Now().UnixNano() / int64(h.width)
+	buckets := make([]Bucket, 0, len(h.buckets))
+
+	for index := last - int64(len(h.buckets)) + 1; index <= last; index++ {
+		bucket := *h.slot(index)
+		if bucket.index != index {
+			bucket = historyBucket{Bucket: Bucket{Start: time.Unix(0, index*int64(h.width))}}
+		}
+
+		buckets = append(buckets, bucket.Bucket)
+	}
+
+	return buckets
+}
+
+// History returns the buckets recorded by WithHistory, oldest first, nil without it.
+func (p *connectPool) History() []Bucket {
+	return p.history.Buckets()
+}
@@ -0,0 +1,95 @@
+package connectpool
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HuXin0817/ConnectPool/clock"
+)
+
+func TestHistory_FillsBuckets(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	p := newTestPool(t, counter(), WithClock(fake), WithTestabilityMode(), WithCap(2), WithHistory(time.Minute, 3))
+
+	// First bucket: two creations held together, then a timed out acquisition on the full pool
+	_, cancelFirst := p.Register()
+	_, cancelSecond := p.Register()
+	if _, _, err := p.AcquireWithTimeout(time.Millisecond, 0); err == nil {
+		t.Fatal("AcquireWithTimeout succeeded on a full pool")
+	}
+	cancelFirst()
+	cancelSecond()
+
+	// Second bucket: both connections evicted on demand
+	fake.Advance(time.Minute)
+	if n := p.Flush(); n != 2 {
+		t.Fatalf("Flush closed %d connections, want 2", n)
+	}
+
+	// Third bucket: one creation
+	fake.Advance(time.Minute)
+	_, cancel := p.Register()
+	cancel()
+
+	want := []Bucket{
+		{Start: time.Unix(0, 0), Creations: 2, AcquireTimeouts: 1, PeakWorking: 2},
+		{Start: time.Unix(60, 0)},
+		{Start: time.Unix(120, 0), Creations: 1, PeakWorking: 1},
+	}
+	want[1].Evictions[CloseEvicted] = 2
+	assertHistory(t, p.History(), want)
+
+	// A bucket later the oldest one leaves the ring and an empty one starts
+	fake.Advance(time.Minute)
+	assertHistory(t, p.History(), append(want[1:], Bucket{Start: time.Unix(180, 0)}))
+}
+
+func TestHistory_ConcurrentRollover(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	p := newTestPool(t, counter(), WithClock(fake), WithTestabilityMode(), WithHistory(time.Second, 10))
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				_, cancel := p.Register()
+				cancel()
+				p.Flush() // Forces a creation on the next Register
+			}
+		}()
+	}
+
+	// Rolls the buckets over while they are being incremented, staying within the ring
+	for range 9 {
+		fake.Advance(time.Second)
+	}
+	wg.Wait()
+
+	var creations uint64
+	for _, b := range p.History() {
+		creations += b.Creations
+	}
+
+	if total := p.Stats().TotalCreated; creations != total {
+		t.Fatalf("buckets count %d creations, want %d", creations, total)
+	}
+}
+
+// assertHistory fails the test unless got matches want bucket by bucket.
+func assertHistory(t *testing.T, got, want []Bucket) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("%d buckets, want %d", len(got), len(want))
+	}
+
+	for i := range want {
+		if !got[i].Start.Equal(want[i].Start) || got[i].Creations != want[i].Creations || got[i].Evictions != want[i].Evictions ||
+			got[i].AcquireTimeouts != want[i].AcquireTimeouts || got[i].PeakWorking != want[i].PeakWorking {
+			t.Fatalf("bucket %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
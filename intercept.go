@@ -0,0 +1,117 @@
+package connectpool
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+)
+
+// closerProxy hands out an io.Closer whose Close discards the connector instead of closing the connection in use.
+type closerProxy struct {
+	io.Closer
+	release *release
+}
+
+func (c *closerProxy) Close() error {
+	return c.release.discard()
+}
+
+// netConnProxy hands out a net.Conn whose Close discards the connector, preserving the full net.Conn interface.
+type netConnProxy struct {
+	net.Conn
+	release *release
+}
+
+func (c *netConnProxy) Close() error {
+	return c.release.discard()
+}
+
+// intercept returns the connection variable to hand out for r, wrapped in a proxy when close interception is enabled.
+func (p *connectPool) intercept(r *release) any {
+	connect := r.connector.GetConnect()
+	if !p.closeInterception || connect == nil {
+		return connect
+	}
+
+	switch conn := connect.(type) {
+	case net.Conn:
+		return &netConnProxy{Conn: conn, release: r}
+	case io.Closer:
+		return &closerProxy{Closer: conn, release: r}
+	}
+
+	return connect
+}
+
+// checkInterception validates the first connection handed out by a pool with close interception, returning the
+// validation error then and on every later call. The pool's panic handler is told once.
+func (p *connectPool) checkInterception(c connector) error {
+	if !p.closeInterception {
+		return nil
+	}
+
+	if checked := p.interception.Load(); checked != nil {
+		return *checked
+	}
+
+	connect := c.GetConnect()
+	if connect == nil {
+		return nil // A failed creation tells nothing about the connection type
+	}
+
+	err := interceptable(connect)
+	if p.interception.CompareAndSwap(nil, &err) && err != nil {
+		p.handlePanic(err)
+	}
+
+	return *p.interception.Load()
+}
+
+// interceptable reports whether a proxy can stand for connect without breaking its holders' type assertions. Callers
+// can only type-assert the proxy to net.Conn or io.Closer, so interception is limited to factories declared as
+// returning one of those interfaces: a connection with methods beyond Close that is not a net.Conn is rejected, as its
+// holders would assert it to its concrete type.
+func interceptable(connect any) error {
+	switch connect.(type) {
+	case net.Conn:
+		return nil
+	case io.Closer:
+		if reflect.TypeOf(connect).NumMethod() > 1 {
+			return fmt.Errorf("%w: %T", ErrConcreteConn, connect)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("%w: %T", ErrNotCloser, connect)
+}
+
+// discard marks the released connector so that the pool closes it instead of reusing it once it is released.
+func (r *release) discard() error {
+	if r.released.Load() {
+		return ErrConnectReleased
+	}
+
+	r.connector.Discard()
+	return nil
+}
+
+// closeCloser closes connect if it implements io.Closer.
+func closeCloser(connect any) {
+	if closer, ok := connect.(io.Closer); ok {
+		_ = closer.Close()
+	}
+}
+
+// findConnector returns the connector holding connect, which may be a proxy handed out by intercept, or nil.
+func (p *connectPool) findConnector(connect any) connector {
+	switch proxy := connect.(type) {
+	case *closerProxy:
+		return proxy.release.connector
+	case *netConnProxy:
+		return proxy.release.connector
+	}
+
+	return p.pool.FindConnector(connect)
+}
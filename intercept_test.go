@@ -0,0 +1,121 @@
+package connectpool
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+)
+
+// pipes returns a connectMethod creating net.Pipe connections, keeping the far end of each to observe its closing.
+func pipes(far *[]net.Conn) func() any {
+	return func() any {
+		near, end := net.Pipe()
+		*far = append(*far, end)
+		return near
+	}
+}
+
+func TestCloseInterception_NetConn(t *testing.T) {
+	var far []net.Conn
+	p := newTestPool(t, pipes(&far), WithCloseInterception(true), WithCallbackWorkers(0))
+
+	conn, cancel := p.Register()
+
+	// The proxy preserves the full net.Conn interface
+	netConn, ok := conn.(net.Conn)
+	if !ok {
+		t.Fatalf("%T is not a net.Conn", conn)
+	}
+
+	if err := netConn.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The underlying connection stays open until the holder releases it
+	go far[0].Read(make([]byte, 1))
+	if _, err := netConn.Write([]byte{1}); err != nil {
+		t.Fatalf("the connection was closed while held: %v", err)
+	}
+
+	cancel()
+
+	if size := p.Size(); size != 0 {
+		t.Fatalf("Size = %d, the discarded connection was kept", size)
+	}
+
+	if _, err := far[0].Write([]byte{1}); !errors.Is(err, io.ErrClosedPipe) {
+		t.Fatalf("the discarded connection was not closed: %v", err)
+	}
+
+	if err := netConn.Close(); !errors.Is(err, ErrConnectReleased) {
+		t.Fatalf("Close after release = %v, want %v", err, ErrConnectReleased)
+	}
+
+	next, cancel := p.Register()
+	defer cancel()
+
+	if next.(net.Conn) == netConn {
+		t.Fatal("the closed connection was handed out again")
+	}
+}
+
+// closer is an io.Closer with no other method, which a proxy can stand for.
+type closer struct{ closed atomic.Bool }
+
+func (c *closer) Close() error {
+	c.closed.Store(true)
+	return nil
+}
+
+// client is a concrete connection type its holders would type-assert to, like *redis.Client.
+type client struct{ closer }
+
+func (c *client) Get(string) string { return "" }
+
+func TestCloseInterception_Closer(t *testing.T) {
+	p := newTestPool(t, func() any { return new(closer) }, WithCloseInterception(true))
+
+	conn, cancel := p.Register()
+	defer cancel()
+
+	if _, ok := conn.(io.Closer); !ok {
+		t.Fatalf("%T is not an io.Closer", conn)
+	}
+}
+
+func TestCloseInterception_RejectedTypes(t *testing.T) {
+	for name, test := range map[string]struct {
+		connectMethod func() any
+		err           error
+	}{
+		"concrete": {func() any { return new(client) }, ErrConcreteConn},
+		"noCloser": {func() any { return 1 }, ErrNotCloser},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var reported atomic.Int64
+			p := newTestPool(t, test.connectMethod, WithCloseInterception(true), WithDealPanicMethod(func(any) { reported.Add(1) }))
+
+			for i := 0; i < 3; i++ {
+				if _, err := p.Acquire(context.Background()); !errors.Is(err, test.err) {
+					t.Fatalf("Acquire error = %v, want %v", err, test.err)
+				}
+
+				if conn, cancel := p.Register(); conn != nil || cancel != nil {
+					t.Fatalf("Register handed out %v", conn)
+				}
+			}
+
+			// The type is checked once, not reported on every acquisition
+			if n := reported.Load(); n != 1 {
+				t.Fatalf("reported %d times, want 1", n)
+			}
+
+			if working := p.WorkingNumber(); working != 0 {
+				t.Fatalf("WorkingNumber = %d after the rejected acquisitions", working)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package connectpool
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// checkInvariants validates the pool's counters and connectors after a state transition. A violation panics with a
+// state dump under the connectpool_invariants build tag and is counted in Stats otherwise.
+func (p *connectPool) checkInvariants(event Event) {
+	errs := p.pool.CheckInvariants()
+
+	if n := p.inUse.Load(); n < 0 {
+		errs = append(errs, fmt.Errorf("connectpool: %d connections in use", n))
+	}
+
+	if n := p.waiters.Load(); n < 0 {
+		errs = append(errs, fmt.Errorf("connectpool: %d waiters", n))
+	}
+
+	if n := p.borrowCount.Load(); n < 0 {
+		errs = append(errs, fmt.Errorf("connectpool: %d connections handed out in total", n))
+	}
+
+	if n := p.creationBudget.Load(); n < -1 {
+		errs = append(errs, fmt.Errorf("connectpool: creation budget of %d", n))
+	}
+
+	if len(errs) == 0 {
+		return
+	}
+
+	p.invariantViolations.Add(1)
+	p.fail(FatalInvariantViolation, fmt.Errorf("after %s event: %w", event, errors.Join(errs...)))
+
+	if strictInvariants {
+		panic(fmt.Sprintf("connectpool: invariant violated after %s event: %v\n%s", event, errors.Join(errs...), p.dumpState()))
+	}
+}
+
+// dumpState describes the pool's statistics and every connector, for invariant violation reports.
+func (p *connectPool) dumpState() string {
+	var dump strings.Builder
+
+	fmt.Fprintf(&dump, "%+v\n", p.StatsFresh())
+	for _, c := range p.Snapshot() {
+		fmt.Fprintf(&dump, "  %+v\n", c)
+	}
+
+	return dump.String()
+}
@@ -0,0 +1,7 @@
+//go:build !connectpool_invariants
+
+package connectpool
+
+// strictInvariants is false without the connectpool_invariants build tag: the checks only run for pools created
+// WithInvariantChecks, and a violation is only counted.
+const strictInvariants = false
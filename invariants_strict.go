@@ -0,0 +1,6 @@
+//go:build connectpool_invariants
+
+package connectpool
+
+// strictInvariants enables the invariant checks of every pool and makes a violation panic with a state dump.
+const strictInvariants = true
@@ -0,0 +1,91 @@
+package connectpool
+
+// labelConnector records the label WithConnLabeler gives to c's connection.
+func (p *connectPool) labelConnector(c connector) {
+	if p.connLabeler == nil || c.Err() != nil {
+		return
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			p.handlePanic(r)
+		}
+	}()
+
+	c.SetLabel(p.connLabeler(c.GetConnect()))
+}
+
+// EvictByLabel removes every connection labeled label by WithConnLabeler, returning how many were removed.
+// Idle connections are closed at once, connections in use are closed when released, and pinned ones are kept.
+func (p *connectPool) EvictByLabel(label string) int {
+	idle, evicted := p.pool.RemoveMatching(func(c connector) bool {
+		return c.Label() == label
+	})
+
+	p.closeInOrder(idle, CloseEvicted)
+
+	for i := 0; i < evicted; i++ {
+		p.emit(EventEvict)
+	}
+
+	return evicted
+}
+
+// topUpLabels dials, off the calling goroutine, a connection per missing idle connection of each WithMinIdlePerLabel
+// label below its floor, as long as the pool is under its cap. A top-up already running is not doubled.
+func (p *connectPool) topUpLabels() {
+	if p.minIdlePerLabel == nil || p.labelDialer == nil || !p.labelTopUp.CompareAndSwap(false, true) {
+		return
+	}
+
+	go func() {
+		defer p.labelTopUp.Store(false)
+
+		idle := p.pool.IdleByLabel()
+		for label, floor := range p.minIdlePerLabel {
+			for n := idle[label]; n < floor; n++ {
+				if p.isClosed() || p.Size() >= p.Cap() || !p.dialForLabel(label) {
+					break
+				}
+			}
+		}
+	}()
+}
+
+// dialForLabel adds an idle connection created by the label dialer for label, reporting whether it was added.
+func (p *connectPool) dialForLabel(label string) (added bool) {
+	if p.Err() != nil || p.draining.Load() || !p.takeCreationBudget() {
+		return false
+	}
+
+	connect := p.dialLabel(label)
+	if connect == nil {
+		p.creations.record(false, p.executor.Submit)
+		return false
+	}
+
+	c := p.pool.AdoptConnector(connect)
+	if c == nil {
+		return false // The pool was closed
+	}
+
+	p.recordCreation(c)
+	c.SetLabel(label)
+	p.sampleSize(c)
+	p.emit(EventCreate)
+	c.ReleaseUnused()
+	return true
+}
+
+// dialLabel calls the label dialer for label, handling its panic as a failed creation.
+func (p *connectPool) dialLabel(label string) (connect any) {
+	defer func() {
+		if r := recover(); r != nil {
+			connect = nil
+
+			p.handlePanic(r)
+		}
+	}()
+
+	return p.labelDialer(label)
+}
@@ -0,0 +1,122 @@
+package connectpool
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/HuXin0817/ConnectPool/clock"
+)
+
+// labeledConn is a connection to the backend named label.
+type labeledConn struct {
+	label string
+	n     int64
+}
+
+func TestMinIdlePerLabel_ColdLabelKeepsFloor(t *testing.T) {
+	const floor = 2
+
+	var n, coldDials atomic.Int64
+	dial := func(label string) any {
+		if label == "cold" {
+			coldDials.Add(1)
+		}
+		return &labeledConn{label: label, n: n.Add(1)}
+	}
+
+	fake := clock.NewFake(time.Unix(0, 0))
+	p := newTestPool(t, func() any { return dial("hot") },
+		WithClock(fake),
+		WithTestabilityMode(),
+		WithMaxFreeTime(time.Minute),
+		WithConnLabeler(func(connect any) string { return connect.(*labeledConn).label }),
+		WithMinIdlePerLabel(map[string]int{"cold": floor}, dial),
+	)
+
+	coldIdle := func() int { return p.Stats().IdlePerLabel["cold"] }
+
+	// Skewed traffic: the hot connection is in use all the time, while the cold connections only idle and expire
+	hot, cancel := p.Register()
+	defer cancel()
+
+	p.ClearNow()
+	waitUntil(t, "the cold label was topped up", func() bool { return coldIdle() == floor })
+
+	for range 5 {
+		fake.Advance(time.Minute)
+		p.ClearNow()
+		waitUntil(t, "the cold label was topped up again", func() bool { return coldIdle() >= floor })
+	}
+
+	if p.findConnector(hot).Label() != "hot" {
+		t.Fatalf("held connection labeled %q, want hot", p.findConnector(hot).Label())
+	}
+
+	if idle := coldIdle(); idle < floor {
+		t.Fatalf("%d idle cold connections, want at least %d", idle, floor)
+	}
+
+	// The floor was kept by replacing the expired cold connections, not by the first ones surviving
+	if dials := coldDials.Load(); dials <= floor {
+		t.Fatalf("%d cold connections dialed, want replacements beyond the first %d", dials, floor)
+	}
+}
+
+func TestEvictByLabel_RemovesExactlyMatching(t *testing.T) {
+	var n atomic.Int64
+	connect := func() any {
+		i := n.Add(1)
+		return &labeledConn{label: map[bool]string{true: "a", false: "b"}[i%2 == 1], n: i}
+	}
+
+	var closed []*labeledConn
+	p := newTestPool(t, connect, WithTestabilityMode(), WithMinSize(2),
+		WithConnLabeler(func(connect any) string { return connect.(*labeledConn).label }),
+		WithCloseMethod(func(connect any) { closed = append(closed, connect.(*labeledConn)) }))
+
+	// Labels both pre-warmed and adopted connections
+	if errs := drain(t, p.Warm(context.Background())); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	for _, label := range []string{"a", "b"} {
+		if !p.adopt(&labeledConn{label: label, n: 100}) {
+			t.Fatalf("adopting a %s connection failed", label)
+		}
+	}
+
+	// Holds the four idle connections and a new one, labeled a, which stays held
+	var cancels []func()
+	for range 5 {
+		_, cancel := p.Register()
+		cancels = append(cancels, cancel)
+	}
+	for _, cancel := range cancels[:4] {
+		cancel()
+	}
+
+	if evicted := p.EvictByLabel("a"); evicted != 3 {
+		t.Fatalf("EvictByLabel evicted %d connections, want the 3 labeled a", evicted)
+	}
+	if size := p.Size(); size != 2 {
+		t.Fatalf("Size = %d after evicting a, want the 2 labeled b", size)
+	}
+	for _, snapshot := range p.Snapshot() {
+		if snapshot.Label != "b" {
+			t.Fatalf("connection labeled %q kept, want only b", snapshot.Label)
+		}
+	}
+
+	// The held connection is closed once released
+	cancels[4]()
+
+	if len(closed) != 3 {
+		t.Fatalf("%d connections closed, want 3", len(closed))
+	}
+	for _, c := range closed {
+		if c.label != "a" {
+			t.Fatalf("connection %d labeled %s closed, want only a", c.n, c.label)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package connectpool
+
+import "log/slog"
+
+// logStart logs the pool's effective configuration if lifecycle logging is enabled.
+func (p *connectPool) logStart() {
+	if p.lifecycleLogger == nil {
+		return
+	}
+
+	config := p.ExportConfig()
+
+	p.lifecycleLogger.Info("connectpool: pool started",
+		"cap", config.MaxSize,
+		"minSize", config.MinSize,
+		"maxFreeTime", config.MaxFreeTime,
+		"autoClearInterval", config.AutoClearInterval,
+		"policies", p.enabledPolicies(),
+	)
+}
+
+// logClose logs the pool's final statistics if lifecycle logging is enabled. It must run once the close completed.
+func (p *connectPool) logClose() {
+	if p.lifecycleLogger == nil {
+		return
+	}
+
+	stats := p.StatsFresh()
+
+	var closed uint64
+	for _, n := range stats.ClosedByReason {
+		closed += n
+	}
+
+	p.lifecycleLogger.Info("connectpool: pool closed",
+		"totalCreated", stats.TotalCreated,
+		"totalClosed", closed,
+		"closedByReason", stats.ClosedByReason,
+		"maxWaiters", stats.MaxWaiters,
+		"uptime", stats.Uptime,
+	)
+}
+
+// enabledPolicies lists the optional behaviors the pool was configured with.
+func (p *connectPool) enabledPolicies() (policies []string) {
+	enabled := []struct {
+		name string
+		on   bool
+	}{
+		{"healthCheck", p.callbacks.Load().healthCheck != nil},
+		{"sampledHealthCheck", p.healthCheckFraction > 0 && p.healthCheckInterval > 0},
+		{"validateOnReturn", p.validateOnReturn != nil},
+		{"resetMethod", p.callbacks.Load().resetMethod != nil},
+		{"priorityQueues", p.scheduler != nil},
+		{"idleSegmentation", p.demoteAfter > 0},
+		{"closeInterception", p.closeInterception},
+		{"creationBudget", p.creationBudget.Load() >= 0},
+		{"maxIdleBytes", p.maxIdleBytes > 0},
+		{"globalIDs", p.globalIDs},
+	}
+
+	for _, policy := range enabled {
+		if policy.on {
+			policies = append(policies, policy.name)
+		}
+	}
+
+	return policies
+}
+
+// lifecycleLoggerFor returns the logger used for lifecycle logging: the diagnostics logger if any, or the default one.
+func (p *connectPool) lifecycleLoggerFor() *slog.Logger {
+	if p.diagnostics != nil {
+		return p.diagnostics.logger // Already carries the pool's name
+	}
+
+	return slog.Default().With("pool", p.name)
+}
@@ -0,0 +1,79 @@
+package connectpool
+
+import (
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLifecycleLogging_Fields(t *testing.T) {
+	logs := new(logBuffer)
+	p := newTestPool(t, counter(), WithTestabilityMode(), WithName("orders"), WithCap(3),
+		WithMaxFreeTime(time.Minute), WithAutoClearInterval(10*time.Second),
+		WithValidateOnReturn(func(connect any) bool { return connect != int64(1) }),
+		WithLifecycleLogging(true), WithDiagnostics(slog.New(slog.NewJSONHandler(logs, nil))))
+
+	// The connection 1 fails validation and is discarded, the connection 2 is closed by Close
+	_, first := p.Register()
+	_, second := p.Register()
+	first()
+	second()
+
+	p.Close()
+	p.Close() // Closing again logs nothing more
+
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(logs.buffer.String()), "\n") {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("%v in log line %s", err, line)
+		}
+
+		if msg := record["msg"].(string); strings.HasPrefix(msg, "connectpool: pool ") {
+			records = append(records, record)
+		}
+	}
+
+	if len(records) != 2 || records[0]["msg"] != "connectpool: pool started" || records[1]["msg"] != "connectpool: pool closed" {
+		t.Fatalf("lifecycle records %v, want one started then one closed", records)
+	}
+
+	started, closed := records[0], records[1]
+	for key, want := range map[string]any{
+		"pool":              "orders",
+		"cap":               float64(3),
+		"minSize":           float64(0),
+		"maxFreeTime":       float64(time.Minute),
+		"autoClearInterval": float64(10 * time.Second),
+	} {
+		if started[key] != want {
+			t.Fatalf("started record %s = %v, want %v", key, started[key], want)
+		}
+	}
+	if policies, _ := started["policies"].([]any); !slices.Contains(policies, any("validateOnReturn")) {
+		t.Fatalf("started record policies = %v, want validateOnReturn listed", started["policies"])
+	}
+
+	// The numbers are final: both closes happened before the record
+	for key, want := range map[string]any{
+		"pool":         "orders",
+		"totalCreated": float64(2),
+		"totalClosed":  float64(2),
+		"maxWaiters":   float64(1), // The acquiring caller itself
+	} {
+		if closed[key] != want {
+			t.Fatalf("closed record %s = %v, want %v", key, closed[key], want)
+		}
+	}
+
+	byReason, _ := closed["closedByReason"].(map[string]any)
+	if byReason["discarded"] != float64(1) || byReason["shutdown"] != float64(1) {
+		t.Fatalf("closed record closedByReason = %v, want one discarded and one shutdown", closed["closedByReason"])
+	}
+	if uptime, _ := closed["uptime"].(float64); uptime <= 0 {
+		t.Fatalf("closed record uptime = %v, want it positive", closed["uptime"])
+	}
+}
@@ -0,0 +1,50 @@
+package connectpool
+
+// MetricsSink receives the pool's metrics, decoupling the pool from any specific metrics library.
+type MetricsSink interface {
+	GaugeSet(name string, value float64, tags map[string]string)   // Sets a gauge to value
+	CounterAdd(name string, delta float64, tags map[string]string) // Adds delta to a counter
+}
+
+// NoOpSink is a MetricsSink discarding every metric.
+type NoOpSink struct{}
+
+func (NoOpSink) GaugeSet(string, float64, map[string]string) {}
+
+func (NoOpSink) CounterAdd(string, float64, map[string]string) {}
+
+// metricCounters maps the events counted by reportMetrics to their metric names.
+var metricCounters = map[Event]string{
+	EventAcquire:       "pool.acquired",
+	EventRelease:       "pool.released",
+	EventCreate:        "pool.created",
+	EventEvict:         "pool.evicted",
+	EventDeadOnArrival: "pool.dead_on_arrival",
+}
+
+// reportMetrics is the event hook sending the pool's metrics to its metricsSink.
+func (p *connectPool) reportMetrics(pool ConnectPool, event Event) {
+	name, ok := metricCounters[event]
+	if !ok {
+		return
+	}
+
+	p.metricsSink.CounterAdd(name, 1, p.metricTags)
+	p.metricsSink.GaugeSet("pool.size", float64(pool.Size()), p.metricTags)
+	p.metricsSink.GaugeSet("pool.working", float64(pool.WorkingNumber()), p.metricTags)
+}
+
+// reportPanics wraps dealPanicMethod so every handled panic is counted by the metricsSink.
+func (p *connectPool) reportPanics(dealPanicMethod func(panicInfo any)) func(panicInfo any) {
+	if _, noOp := p.metricsSink.(NoOpSink); noOp || p.metricsSink == nil {
+		return dealPanicMethod
+	}
+
+	return func(panicInfo any) {
+		p.metricsSink.CounterAdd("pool.panics", 1, p.metricTags)
+
+		if dealPanicMethod != nil {
+			dealPanicMethod(panicInfo)
+		}
+	}
+}
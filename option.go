@@ -1,35 +1,394 @@
 package connectpool
 
-import "time"
+import (
+	"log/slog"
+	"maps"
+	"math/rand"
+	"sync"
+	"time"
 
-type option func(*connectPool)
+	"github.com/HuXin0817/ConnectPool/clock"
+)
 
-func WithCap(cap int) option {
+// Option configures a connectPool during construction.
+type Option func(*connectPool)
+
+func WithCap(cap int) Option {
+	return func(pool *connectPool) {
+		pool.config.update(func(next *runtimeConfig) { next.maxSize = cap })
+	}
+}
+
+func WithMaxFreeTime(maxFreeTime time.Duration) Option {
+	return func(pool *connectPool) {
+		pool.config.update(func(next *runtimeConfig) { next.maxFreeTime = maxFreeTime })
+	}
+}
+
+func WithAutoClearInterval(autoClearInterval time.Duration) Option {
+	return func(pool *connectPool) {
+		pool.config.update(func(next *runtimeConfig) { next.autoClearInterval = autoClearInterval })
+	}
+}
+
+func WithDealPanicMethod(dealPanicMethod func(panicInfo any)) Option {
+	return func(pool *connectPool) {
+		pool.callbacks.update(func(next *callbacks) { next.dealPanicMethod = dealPanicMethod })
+	}
+}
+
+func WithCloseMethod(closeMethod func(connect any)) Option {
+	return func(pool *connectPool) {
+		pool.callbacks.update(func(next *callbacks) { next.closeMethod = closeMethod })
+	}
+}
+
+// WithCloseMethodE is WithCloseMethod for a close method that can fail. Failures are counted in Stats, the last
+// one is kept there, and each is also handed to the panic handler if reportToPanicHandler is true.
+func WithCloseMethodE(closeMethod func(connect any) error, reportToPanicHandler bool) Option {
+	return func(pool *connectPool) {
+		pool.callbacks.update(func(next *callbacks) {
+			next.closeMethod = func(connect any) {
+				if err := closeMethod(connect); err != nil {
+					pool.recordCloseError(err, reportToPanicHandler)
+				}
+			}
+		})
+	}
+}
+
+func WithEventHook(eventHook func(pool ConnectPool, event Event)) Option {
+	return func(pool *connectPool) {
+		pool.eventHooks = append(pool.eventHooks, eventHook)
+	}
+}
+
+// WithEventHookFactory creates an event hook for each pool the option is applied to, once the other options are, so
+// that an option reused across pools, as by CloneWith, shares no state between them. An error from newHook is handed
+// to the panic handler and the pool runs without the hook.
+func WithEventHookFactory(newHook func(pool ConnectPool) (eventHook func(pool ConnectPool, event Event), err error)) Option {
+	return func(pool *connectPool) {
+		pool.eventHookFactories = append(pool.eventHookFactories, newHook)
+	}
+}
+
+func WithConnSize(connSize func(connect any) int64) Option {
+	return func(pool *connectPool) {
+		pool.connSize = connSize
+	}
+}
+
+func WithMaxIdleBytes(maxIdleBytes int64) Option {
+	return func(pool *connectPool) {
+		pool.maxIdleBytes = maxIdleBytes
+	}
+}
+
+func WithDiagnostics(logger *slog.Logger) Option {
+	return func(pool *connectPool) {
+		if logger == nil {
+			logger = slog.Default()
+		}
+
+		pool.diagnostics = &diagnostics{logger: logger}
+	}
+}
+
+func WithValidateOnReturn(validateOnReturn func(connect any) bool) Option {
+	return func(pool *connectPool) {
+		pool.validateOnReturn = validateOnReturn
+	}
+}
+
+func WithMinSize(minSize int) Option {
+	return func(pool *connectPool) {
+		pool.config.update(func(next *runtimeConfig) { next.minSize = minSize })
+	}
+}
+
+func WithCallbackWorkers(callbackWorkers int) Option {
+	return func(pool *connectPool) {
+		pool.callbackWorkers = callbackWorkers
+	}
+}
+
+func WithCallbackDrainTimeout(drainTimeout time.Duration) Option {
+	return func(pool *connectPool) {
+		pool.drainTimeout = drainTimeout
+	}
+}
+
+// WithCloseInterception hands out net.Conn and io.Closer connections behind a proxy whose Close discards the
+// connection instead of letting the pool reuse it. The first connection is checked: one that is not an io.Closer, or
+// has methods beyond Close without being a net.Conn, fails the acquisitions with ErrNotCloser or ErrConcreteConn.
+func WithCloseInterception(closeInterception bool) Option {
+	return func(pool *connectPool) {
+		pool.closeInterception = closeInterception
+	}
+}
+
+func WithPriorityQueues(highShare float64) Option {
+	return func(pool *connectPool) {
+		pool.scheduler = newPriorityScheduler(highShare)
+	}
+}
+
+func WithResetMethod(resetMethod func(connect any) error) Option {
+	return func(pool *connectPool) {
+		pool.callbacks.update(func(next *callbacks) { next.resetMethod = resetMethod })
+	}
+}
+
+func WithHealthCheck(healthCheck func(connect any) error) Option {
+	return func(pool *connectPool) {
+		pool.callbacks.update(func(next *callbacks) { next.healthCheck = healthCheck })
+	}
+}
+
+func WithSampledHealthCheck(fraction float64, interval time.Duration) Option {
+	return func(pool *connectPool) {
+		pool.healthCheckFraction = min(fraction, 1)
+		pool.healthCheckInterval = interval
+	}
+}
+
+func WithCreationWindow(window time.Duration) Option {
+	return func(pool *connectPool) {
+		pool.creations.window = window
+	}
+}
+
+func WithCreationFailureAlert(threshold float64, alert func(rate float64)) Option {
+	return func(pool *connectPool) {
+		pool.creations.threshold = threshold
+		pool.creations.alert = alert
+	}
+}
+
+func WithCreationBudget(n int64) Option {
+	return func(pool *connectPool) {
+		pool.creationBudget.Store(max(n, 0))
+	}
+}
+
+func WithConnLabeler(connLabeler func(connect any) string) Option {
+	return func(pool *connectPool) {
+		pool.connLabeler = connLabeler
+	}
+}
+
+// WithConnectLabel is WithConnLabeler: fn labels each new connection, as shown in Snapshot.
+func WithConnectLabel(fn func(conn any) string) Option {
+	return WithConnLabeler(fn)
+}
+
+func WithIdleSegmentation(demoteAfter, demoteInterval time.Duration) Option {
+	return func(pool *connectPool) {
+		pool.demoteAfter = demoteAfter
+		pool.demoteInterval = demoteInterval
+	}
+}
+
+func WithSlowCloseThreshold(threshold time.Duration, slowClose func(duration time.Duration, reason CloseReason)) Option {
+	return func(pool *connectPool) {
+		pool.slowCloseThreshold = threshold
+		pool.slowClose = slowClose
+	}
+}
+
+func WithGlobalIDs(globalIDs bool) Option {
+	return func(pool *connectPool) {
+		pool.globalIDs = globalIDs
+	}
+}
+
+func WithLifecycleLogging(lifecycleLogging bool) Option {
+	return func(pool *connectPool) {
+		pool.lifecycleLogging = lifecycleLogging
+	}
+}
+
+func WithUnusedGracePeriod(unusedGrace time.Duration) Option {
+	return func(pool *connectPool) {
+		pool.unusedGrace = unusedGrace
+	}
+}
+
+func WithAffinityWait(affinityWait time.Duration) Option {
+	return func(pool *connectPool) {
+		pool.affinityWait = affinityWait
+	}
+}
+
+func WithStatsCache(maxStaleness time.Duration) Option {
+	return func(pool *connectPool) {
+		pool.statsMaxStaleness = maxStaleness
+	}
+}
+
+// WithDeterministicOrdering makes the pool visit its connectors in creation order wherever it would otherwise depend
+// on map iteration order: choosing an idle connector, ordering eviction candidates and listing snapshots. It is
+// meant for tests, at the cost of sorting the connectors on every visit.
+func WithDeterministicOrdering(deterministic bool) Option {
+	return func(pool *connectPool) {
+		pool.deterministic = deterministic
+	}
+}
+
+// WithRandSource makes the pool draw its random decisions from src instead of a source of its own seeded with the
+// time, so a seeded source, together with WithDeterministicOrdering, makes them reproducible. Only the samples of
+// WithSampledHealthCheck consume it, always under the pool's lock, so src needs no locking unless it is shared by
+// several pools. A nil src keeps the default.
+func WithRandSource(src rand.Source) Option {
+	return func(pool *connectPool) {
+		if src != nil {
+			pool.randSource = src
+		}
+	}
+}
+
+func WithWarmupRamp(warmupRamp time.Duration) Option {
+	return func(pool *connectPool) {
+		pool.warmupRamp = warmupRamp
+	}
+}
+
+// WithKeyHasher stores connectors under keyHasher applied to their sequential token instead of the token itself, so
+// Snapshot reports the hashed values. keyHasher should be a bijection, such as a mixing function; a colliding key
+// makes connector creation retry with the next token.
+func WithKeyHasher(keyHasher func(uint64) uint64) Option {
+	return func(pool *connectPool) {
+		pool.keyHasher = keyHasher
+	}
+}
+
+func WithInvariantChecks(invariantChecks bool) Option {
+	return func(pool *connectPool) {
+		pool.invariantChecks = invariantChecks
+	}
+}
+
+func WithMetricsSink(metricsSink MetricsSink) Option {
+	return func(pool *connectPool) {
+		pool.metricsSink = metricsSink
+	}
+}
+
+func WithName(name string) Option {
+	return func(pool *connectPool) {
+		pool.name = name
+	}
+}
+
+func WithWarmConcurrency(warmConcurrency int) Option {
+	return func(pool *connectPool) {
+		pool.warmLimiter = NewWarmLimiter(warmConcurrency)
+	}
+}
+
+func WithWarmLimiter(warmLimiter *WarmLimiter) Option {
+	return func(pool *connectPool) {
+		pool.warmLimiter = warmLimiter
+	}
+}
+
+// WithDialerPerPool gives the pool its own connection factory, built by factoryFactory from the pool's name on the
+// first connection attempt. Pools built from the same factoryFactory then never contend on a shared factory.
+func WithDialerPerPool(factoryFactory func(poolName string) func() any) Option {
+	return func(pool *connectPool) {
+		var once sync.Once
+		var factory func() any
+
+		pool.callbacks.update(func(next *callbacks) {
+			next.connectMethod = func() any {
+				once.Do(func() { factory = factoryFactory(pool.name) })
+				return factory()
+			}
+		})
+	}
+}
+
+func WithCloseOrder(closeOrder CloseOrder) Option {
+	return func(pool *connectPool) {
+		pool.closeOrder = closeOrder
+	}
+}
+
+// WithFatalOn makes each of conditions put the pool in a failed state when it occurs at runtime: Err then returns a
+// sticky error wrapping ErrPoolFailed, new acquisitions fail fast with it, connections already held can still be
+// released and Close still cleans the pool up. No condition is fatal by default.
+func WithFatalOn(conditions ...FatalCondition) Option {
+	return func(pool *connectPool) {
+		for _, condition := range conditions {
+			if condition >= 0 && condition < fatalConditionCount {
+				pool.fatal.conditions[condition] = true
+			}
+		}
+	}
+}
+
+// WithOnFatal sets a hook called once, asynchronously, with the sticky error when the pool fails.
+func WithOnFatal(onFatal func(err error)) Option {
+	return func(pool *connectPool) {
+		pool.fatal.onFatal = onFatal
+	}
+}
+
+// WithClock sets the time source of the clear passes and of the connectors' timestamps and timers, so a fake clock
+// such as clock.Fake drives idle eviction. A nil clock keeps the real one.
+func WithClock(c clock.Clock) Option {
+	return func(pool *connectPool) {
+		if c != nil {
+			pool.clock = c
+		}
+	}
+}
+
+// WithHistory records the creations, evictions by reason, acquire timeouts and peak working count of the last buckets
+// slices of time, each bucket long, in a fixed ring retrieved by History. The buckets follow the WithClock clock.
+func WithHistory(bucket time.Duration, buckets int) Option {
 	return func(pool *connectPool) {
-		pool.cap = cap
+		pool.historyWidth = bucket
+		pool.historyBuckets = buckets
 	}
 }
 
-func WithMaxFreeTime(maxFreeTime time.Duration) option {
+// WithConnectMethodTimeout limits the time spent inside connectMethod, such as a dial or a TLS handshake, to
+// connectMethodTimeout; it is unrelated to the time spent waiting for a free connector. connectMethod then runs in its
+// own goroutine: when it overruns, the creation fails with ErrConnectTimeout, which is also passed to the panic
+// handler, and the connection it eventually returns is closed with closeMethod. 0 means no limit.
+func WithConnectMethodTimeout(connectMethodTimeout time.Duration) Option {
 	return func(pool *connectPool) {
-		pool.maxFreeTime = maxFreeTime
+		pool.callbacks.update(func(next *callbacks) { next.connectTimeout = connectMethodTimeout })
 	}
 }
 
-func WithAutoClearInterval(autoClearInterval time.Duration) option {
+// WithOnRelease sets a hook called with the connection and the cause each time a hand-out is released, by its
+// holder's cancelFunc or by the deadline of RegisterWithTimeLimit or AcquireWithTimeout. A cancelFunc called after its
+// deadline fired reports nothing more.
+func WithOnRelease(onRelease func(conn any, cause ReleaseCause)) Option {
 	return func(pool *connectPool) {
-		pool.autoClearInterval = autoClearInterval
+		pool.onRelease = onRelease
 	}
 }
 
-func WithDealPanicMethod(dealPanicMethod func(panicInfo any)) option {
+// WithMinIdlePerLabel keeps at least minIdle[label] idle connections of each label, as given by WithConnLabeler: after
+// every clear pass, labels below their floor are topped up with connections created by dial, which are labeled with
+// the label they were created for. The floors only apply under the cap; labels not in minIdle only count towards
+// MinSize. Stats().IdlePerLabel reports the idle connections per label.
+func WithMinIdlePerLabel(minIdle map[string]int, dial func(label string) any) Option {
 	return func(pool *connectPool) {
-		pool.dealPanicMethod = dealPanicMethod
+		pool.minIdlePerLabel = maps.Clone(minIdle)
+		pool.labelDialer = dial
 	}
 }
 
-func WithCloseMethod(closeMethod func(connect any)) option {
+// WithTestabilityMode starts no background goroutine: there is no autoClear goroutine, so connections are only evicted
+// by ClearNow, which runs the clear pass on the caller's goroutine, and callbacks run inline rather than on callback
+// workers. Tests then control the pool's state without sleeping or racing a clear pass. Options starting their own
+// background work, such as WithSampledHealthCheck, still do.
+func WithTestabilityMode() Option {
 	return func(pool *connectPool) {
-		pool.closeMethod = closeMethod
+		pool.testability = true
 	}
 }
@@ -1,127 +1,788 @@
 package connectpool
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"log/slog"
+	"math/rand"
 	"runtime"
+	"runtime/debug"
+	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/HuXin0817/ConnectPool/clock"
+	"golang.org/x/time/rate"
 )
 
 const (
 	defaultMaxFreeTime       = 3 * time.Second // Default maximum idle wait time
 	defaultAutoCleanInterval = 2 * time.Second // Default auto-clean cycle execution
 	defaultCap               = 1000            // Default pool cap
+	defaultMinSize           = 0               // Default number of connectors Warm fills the pool to
+	defaultName              = "default"       // Default name distinguishing the pool in logs and metrics
 )
 
 var defaultDealPanicMethod = func(panicInfo any) {
 	log.Println(panicInfo) // Default method for handling panic by logging the panicInfo
 }
 
+// ConnectPool is a pool of connections. The pools NewConnectPool returns also implement Acquirer, Inspector, Resizer,
+// Evictor and Reconfigurer, which a caller reaches with a type assertion.
 type ConnectPool interface {
-	Register() (newConnect any, cancelFunc func())                                    // Registers a connection
-	RegisterWithTimeLimit(deadLine time.Duration) (newConnect any, cancelFunc func()) // Registers a connection with a deadline
-	WorkingNumber() int                                                               // Gets the number of active connections
-	Size() int                                                                        // Gets the pool's cap
-	Cap() int                                                                         // Gets the pool's maximum size
-	MaxFreeTime() time.Duration                                                       // Gets the maximum idle time for connectors
-	AutoClearInterval() time.Duration                                                 // Gets the interval for auto-clearing
-	Close()                                                                           // Closes the pool
+	Register(options ...RegisterOption) (newConnect any, cancelFunc func())                                      // Registers a connection
+	RegisterWithTimeLimit(deadLine time.Duration, options ...RegisterOption) (newConnect any, cancelFunc func()) // Registers a connection with a deadline
+	WorkingNumber() int                                                                                          // Gets the number of active connections
+	Size() int                                                                                                   // Gets the pool's cap
+	Cap() int                                                                                                    // Gets the pool's maximum size
+	MaxFreeTime() time.Duration                                                                                  // Gets the maximum idle time for connectors
+	AutoClearInterval() time.Duration                                                                            // Gets the interval for auto-clearing
+	Close()                                                                                                      // Closes the pool
+}
+
+// Acquirer is a ConnectPool handing out connections beyond Register.
+type Acquirer interface {
+	ConnectPool
+	Acquire(ctx context.Context, options ...RegisterOption) (AcquiredConn, error)                                                        // Acquires a connection released by its Close method
+	AcquireWithTimeout(waitTimeout, holdTimeout time.Duration, options ...RegisterOption) (newConnect any, cancelFunc func(), err error) // Waits up to waitTimeout for a connection held up to holdTimeout, 0 meaning no limit
+	AcquireConnector(options ...RegisterOption) (*Connector, func(), error)                                                              // Acquires a connection's Connector, for advanced integrations
+	RegisterForKey(key uint64, options ...RegisterOption) (newConnect any, cancelFunc func())                                            // Registers the connection key hashes onto, falling back to any connection if it stays busy
+	RegisterN(ctx context.Context, k int, options ...RegisterOption) (newConnects []any, cancelFunc func(), err error)                   // Registers k connections at once, all or nothing
+	TryRegisterN(k int, options ...RegisterOption) (newConnects []any, cancelFunc func(), err error)                                     // Registers up to k connections without waiting
+	DoWithRetry(ctx context.Context, fn func(connect any) error, maxRetries int) error                                                   // Runs fn, retrying on a fresh connection when its connection was reclaimed or broken
+	DoShared(key string, fn func(conn any) (any, error)) (any, error)                                                                    // Runs fn with one connection for all concurrent callers of key, sharing its result
+	LimitConcurrency(fn func(any) error, maxConcurrent int) error                                                                        // Runs fn with a connection, at most maxConcurrent such calls at once
+}
+
+// Inspector is a ConnectPool describing its state and waiting for it to change.
+type Inspector interface {
+	ConnectPool
+	Name() string                                                 // Gets the pool's name
+	Err() error                                                   // Gets the error that put the pool in the failed state, nil while it is healthy
+	CountByState() map[string]int                                 // Gets the number of connectors working, idle, expired, unhealthy and pending close
+	AllFree() []any                                               // Gets the connection variables of every idle connection
+	ForEachWorking(fn func(conn any, heldFor time.Duration) bool) // Calls fn with every working connection and how long it has been working until fn returns false
+	BorrowCount() int64                                           // Gets the number of times a connection was handed out since the pool was created
+	Pressure() float64                                            // Scores how loaded the pool is between 0 and 1, reading atomics only
+	PressureComponents() PressureComponents                       // Gets the inputs of Pressure
+	CapChannel() <-chan struct{}                                  // Gets a channel closed the first time the size reaches the cap
+	AtCapCount() int64                                            // Gets the number of times the size grew to the cap
+	MinSize() int                                                 // Gets the number of connectors Warm fills the pool to
+	Ready() bool                                                  // Reports whether the WithWarmupRamp warm-up has finished, always true without one
+	Stats() PoolStats                                             // Gets a point-in-time summary of the pool
+	StatsFresh() PoolStats                                        // Gets a point-in-time summary of the pool, bypassing the WithStatsCache cache
+	GetByToken(token uint64) (any, bool)                          // Gets the connection of the connector stored under token
+	Snapshot() []ConnectorSnapshot                                // Gets a description of every connector
+	Tuning() TuningReport                                         // Reports which configuration limit closes the connections
+	ResetTuning()                                                 // Starts a new Tuning window
+	History() []Bucket                                            // Gets the WithHistory buckets, oldest first
+	SynctestSnapshot() SynctestSnapshot                           // Gets a deterministic description of the pool, timed by its clock
+	ExportConfig() PoolConfig                                     // Gets the pool's current configuration as a serializable struct
+	Explain() string                                              // Describes the pool's current state and configuration in one line
+	Validate() []error                                            // Checks the pool's invariants, returning one error per violation
+	TestConnector(conn any) error                                 // Runs the health check against a specific connection
+	WatchSize(ch chan<- int) CancelFunc                           // Sends the new size on ch whenever it changes
+	WaitIdle(ctx context.Context, untilEmpty bool) error          // Blocks until no connection is in use, or also until the pool is empty
+	WaitForDrain(ctx context.Context) error                       // Blocks until the pool holds no connector or ctx is done
+}
+
+// Resizer is a ConnectPool whose size and acquisition rate can be changed while it runs.
+type Resizer interface {
+	ConnectPool
+	SetMaxSize(n int)                                                                                // Changes the pool's maximum size, evicting or warming connectors at once to converge to it
+	ShrinkTo(n int, deadline time.Duration) <-chan int                                               // Shrinks to n connectors, force-evicting the remainder after deadline
+	ScheduledShrink(targetSize int, after time.Duration) CancelFunc                                  // Calls ShrinkTo after a delay unless the returned CancelFunc is called first
+	Warm(ctx context.Context) <-chan error                                                           // Asynchronously creates connectors until MinSize, reporting each failure
+	StaggeredWarmUp(n int, interval time.Duration) <-chan error                                      // Asynchronously creates n connectors one at a time, interval apart, reporting each failure
+	AutoSizeToLoad(targetUtilization float64, minCap, maxCap int, interval time.Duration) CancelFunc // Adjusts the cap to the utilization every interval
+	AutoGrow(triggerUtilization float64, step int) CancelFunc                                        // Raises the cap while the full pool's utilization is above triggerUtilization
+	Throttle(maxPerSecond float64) CancelFunc                                                        // Limits acquisitions to maxPerSecond until the returned CancelFunc is called
+	AddCreationBudget(delta int64)                                                                   // Tops up the creation budget set by WithCreationBudget
+}
+
+// Evictor is a ConnectPool whose connections can be evicted or pinned on demand.
+type Evictor interface {
+	ConnectPool
+	ForceEvictAll() int                                                           // Closes every connection at once, in use ones included, returning how many were closed
+	Flush() int                                                                   // Closes every idle connection, returning how many were closed
+	DisableAutoClear()                                                            // Pauses the background clear passes until EnableAutoClear
+	EnableAutoClear()                                                             // Resumes the background clear passes
+	ClearNow() int                                                                // Runs a clear pass immediately, returning the number of evicted connectors
+	EvictByLabel(label string) int                                                // Removes every connection labeled label by WithConnLabeler
+	EvictIf(predicate func(conn any, isWorking bool, age time.Duration) bool) int // Removes every connection predicate matches, closing those in use once released
+	BorrowForever(connect any) error                                              // Pins a connection so it is never reused or cleared
+	UnBorrowForever(connect any) error                                            // Releases a connection pinned by BorrowForever
 }
 
+// Reconfigurer is a ConnectPool whose configuration can be changed while it runs.
+type Reconfigurer interface {
+	ConnectPool
+	SetMaxFreeTime(maxFreeTime time.Duration)                   // Sets the maximum idle time, honored from the next clear pass
+	SetAutoClearInterval(autoClearInterval time.Duration) error // Sets the interval for auto-clearing, restarting the current wait
+	SetPanicHandler(dealPanicMethod func(panicInfo any))        // Replaces the method handling panics, safe to call while the pool is in use
+	WrapCloseMethod(fn func(connect any))                       // Adds fn before the current closeMethod, keeping it
+	Reconfigure(opts ...Option) error                           // Validates and applies the runtime options together
+	CloneWith(opts ...Option) (ConnectPool, error)              // Creates a new pool with the same connectMethod and options, overridden by opts
+}
+
+// The pools NewConnectPool returns implement every optional interface
+var _ interface {
+	Acquirer
+	Inspector
+	Resizer
+	Evictor
+	Reconfigurer
+} = (*connectPool)(nil)
+
 type connectPool struct {
-	autoClearInterval time.Duration       // Interval for auto-clearing cycles
-	maxFreeTime       time.Duration       // Maximum idle wait time
-	cap               int                 // Maximum number of connections
-	pool              connectorSet        // Pool of connectors
-	connectMethod     func() any          // Method for creating connections
-	dealPanicMethod   func(panicInfo any) // Method for handling panic
-	closeMethod       func(connect any)   // Method to execute before closing a connection
+	name                string                                           // Name distinguishing the pool in logs and metrics
+	createdAt           time.Time                                        // Time the pool was created
+	metricTags          map[string]string                                // Tags attached to every metric sent to metricsSink
+	config              configBundle                                     // Cap, min size, maxFreeTime and autoClearInterval, replaced as a whole
+	globalIDs           bool                                             // Whether connector tokens are drawn from a counter shared by every pool
+	deterministic       bool                                             // Whether connectors are visited in creation order rather than map order
+	randSource          rand.Source                                      // Source of the pool's random decisions
+	keyHasher           func(uint64) uint64                              // Spreads the connector tokens, identity if nil
+	clock               clock.Clock                                      // Time source of the clear passes and connector timestamps
+	unusedGrace         time.Duration                                    // Extra idle time before a connector never used yet is evicted
+	warmLimiter         *WarmLimiter                                     // Bounds the concurrent creations of Warm, nil means unbounded
+	warmupRamp          time.Duration                                    // Duration over which the initial connectors are created, 0 disables the warm-up
+	warmupWarmed        atomic.Int64                                     // Number of connectors created by the warm-up so far
+	warmupTarget        atomic.Int64                                     // Number of connectors the warm-up aims for
+	warmupDone          atomic.Bool                                      // Whether the warm-up reached its target or gave up
+	maxIdleBytes        int64                                            // Budget for the memory held by idle connections, 0 means unlimited
+	connSize            func(connect any) int64                          // Method for measuring the memory held by a connection
+	connLabeler         func(connect any) string                         // Method labeling a new connection with its backend
+	pool                connectorSet                                     // Pool of connectors
+	callbacks           callbackBundle                                   // Creation, reset, close, panic and health check methods, replaced as a whole
+	options             []Option                                         // Options the pool was created with, inherited by CloneWith
+	closeDurations      closeDurations                                   // Durations of the closeMethod calls
+	closedByReason      [closeReasonCount]atomic.Uint64                  // Number of connections closed per reason
+	tuning              tuningCounters                                   // Figures of the Tuning report
+	draining            atomic.Bool                                      // Whether Handoff stopped the acquisitions
+	reconfigure         sync.Mutex                                       // Serializes Reconfigure calls
+	minIdlePerLabel     map[string]int                                   // Number of idle connections kept per label, nil if none
+	labelDialer         func(label string) any                           // Method creating a connection for a label below its floor
+	labelTopUp          atomic.Bool                                      // Whether a top-up of the labels below their floor is running
+	testability         bool                                             // Whether clear passes only run on ClearNow, without background goroutine
+	closeErrors         atomic.Uint64                                    // Number of errors returned by a WithCloseMethodE close method
+	lastCloseError      atomic.Pointer[error]                            // Last error returned by a WithCloseMethodE close method
+	slowCloseThreshold  time.Duration                                    // Duration from which a closeMethod call is reported to slowClose, 0 disables it
+	slowClose           func(duration time.Duration, reason CloseReason) // Called with each closeMethod call slower than slowCloseThreshold
+	validateOnReturn    func(connect any) bool                           // Method deciding whether a returned connection may be reused
+	healthCheckFraction float64                                          // Fraction of idle connectors checked per background pass
+	healthCheckInterval time.Duration                                    // Interval between background health check passes
+	demoteAfter         time.Duration                                    // Idle time after which a connector is demoted to the cold segment, 0 disables the segments
+	demoteInterval      time.Duration                                    // Interval between demotion passes
+	closeInterception   bool                                             // Whether io.Closer connections are handed out behind a Close-intercepting proxy
+	interception        atomic.Pointer[error]                            // Result of the close interception check of the first connection, nil until then
+	closeOrder          CloseOrder                                       // Order in which Close, Flush and ShrinkTo close idle connections
+	eventHooks          []func(pool ConnectPool, event Event)            // Hooks notified on each pool event
+	eventHookFactories  []eventHookFactory                               // Create event hooks once the options are applied
+	metricsSink         MetricsSink                                      // Receives the pool's metrics
+	executor            *executor                                        // Runs asynchronous callbacks
+	callbackWorkers     int                                              // Number of goroutines running asynchronous callbacks
+	drainTimeout        time.Duration                                    // Time Close waits for queued callbacks
+	diagnostics         *diagnostics                                     // Misuse detection, nil unless enabled
+	invariantChecks     bool                                             // Whether invariants are checked after every state transition
+	invariantViolations atomic.Uint64                                    // Number of state transitions that broke an invariant
+	fatal               fatalState                                       // Conditions failing the pool and the sticky error once it has failed
+	statsMaxStaleness   time.Duration                                    // Age up to which Stats returns cached statistics, 0 to never cache
+	statsCache          atomic.Pointer[statsCache]                       // Statistics last computed by Stats, nil until then
+	statsRefresh        sync.Mutex                                       // Serializes the refreshes of statsCache
+	lifecycleLogging    bool                                             // Whether the configuration and the final statistics are logged when the pool starts and closes
+	lifecycleLogger     *slog.Logger                                     // Logger of the lifecycle logging, nil if disabled
+	sizeWatchers        sizeWatchers                                     // Goroutines started by WatchSize
+	idleSignal          broadcast                                        // Signaled whenever a connector stops working or the size changes
+	shared              sharedCalls                                      // DoShared calls in flight
+	concurrency         concurrencyLimits                                // Semaphores of LimitConcurrency
+	capReached          chan struct{}                                    // Closed the first time the size reaches the cap
+	capReachedOnce      sync.Once                                        // Guards the closing of capReached
+	atCap               atomic.Bool                                      // Whether the size is currently at the cap
+	atCapCount          atomic.Int64                                     // Number of times the size grew to the cap
+	batchTurn           chan struct{}                                    // Held by one RegisterN caller at a time, so partial holds cannot deadlock each other
+	done                chan struct{}                                    // Closed when the pool is closed, stopping background goroutines
+	closeOnce           sync.Once                                        // Guards the closing of done
+	scheduler           *priorityScheduler                               // Orders waiters by priority class, nil unless enabled
+	creations           *creationTracker                                 // Sliding window of connector creation outcomes
+	shrinkTarget        atomic.Int64                                     // Size an in-progress ShrinkTo is shrinking to, -1 if none
+	creationBudget      atomic.Int64                                     // Creations left before ErrBudgetExhausted, -1 if unlimited
+	throttle            atomic.Pointer[rate.Limiter]                     // Limiter installed by Throttle, nil if acquisitions are not throttled
+	forceEvicted        atomic.Uint64                                    // Number of connectors force-evicted by ShrinkTo
+	clearSweeps         atomic.Uint64                                    // Number of automatic clear passes
+	evictedConnectors   atomic.Uint64                                    // Number of connectors removed by automatic clear passes
+	borrowCount         atomic.Int64                                     // Number of times a connector was handed out
+	totalCreated        atomic.Uint64                                    // Number of connectors created successfully
+	inUse               atomic.Int64                                     // Number of connectors handed out and not yet released
+	waiters             atomic.Int64                                     // Number of goroutines searching for a connector
+	maxWaiters          atomic.Int64                                     // Largest number of goroutines that were searching for a connector at once
+	maxHoldTime         atomic.Int64                                     // Longest time a connection was held before being released, in nanoseconds
+	affinityWait        time.Duration                                    // Time RegisterForKey waits for a busy affine connector before falling back
+	affinityHits        atomic.Uint64                                    // Number of RegisterForKey calls served by the key's connector
+	affinityMisses      atomic.Uint64                                    // Number of RegisterForKey calls that fell back to any connector
+	releases            releaseCounts                                    // Number of hand-outs released per cause
+	onRelease           func(conn any, cause ReleaseCause)               // Called with each released hand-out and its cause, nil if none
+	doaWindow           time.Duration                                    // Age under which a discarded connection counts as dead on arrival, 0 disables it
+	onDeadOnArrival     func(conn any, age time.Duration)                // Called with each connection dead on arrival, nil if none
+	deadOnArrival       atomic.Uint64                                    // Number of connections dead on arrival
+	timeoutRate         atomic.Uint64                                    // Exponentially weighted acquire timeout rate, stored as float64 bits
+	historyWidth        time.Duration                                    // Length of each WithHistory bucket, 0 disables the history
+	historyBuckets      int                                              // Number of buckets kept by WithHistory
+	history             *history                                         // Time-bucketed counters, nil unless enabled
 }
 
-// NewConnectPool creates a new connection pool with a specified maximum size and connection creation method.
-func NewConnectPool(connectMethod func() any, options ...option) ConnectPool {
+// NewConnectPool creates a new connection pool with a specified maximum size and connection creation method. It panics
+// with ErrNilConnectMethod if connectMethod is nil and no option such as WithDialerPerPool provides one.
+func NewConnectPool(connectMethod func() any, options ...Option) ConnectPool {
 	// Initially use default values, which can be modified using Set methods
 	pool := &connectPool{
-		connectMethod:     connectMethod,
-		autoClearInterval: defaultAutoCleanInterval,
-		maxFreeTime:       defaultMaxFreeTime,
-		cap:               defaultCap,
-		dealPanicMethod:   defaultDealPanicMethod,
+		name:            defaultName,
+		invariantChecks: strictInvariants,
+		affinityWait:    defaultAffinityWait,
+		options:         options,
+		capReached:      make(chan struct{}),
+		batchTurn:       make(chan struct{}, 1),
+		done:            make(chan struct{}),
+		callbackWorkers: defaultCallbackWorkers,
+		drainTimeout:    defaultCallbackDrainTimeout,
+		creations:       newCreationTracker(defaultCreationWindow),
+		metricsSink:     NoOpSink{},
 	}
 
+	pool.callbacks.update(func(next *callbacks) { next.connectMethod = connectMethod })
+	pool.randSource = rand.NewSource(time.Now().UnixNano())
+	pool.clock = clock.Real()
+	pool.config.current.Store(pool.config.Load()) // The defaults
+	pool.SetPanicHandler(defaultDealPanicMethod)
+	pool.creationBudget.Store(-1)
+
 	for _, op := range options {
 		op(pool)
 	}
 
-	pool.pool = newConnectorSet(&pool.autoClearInterval, &pool.maxFreeTime, &pool.closeMethod, &pool.dealPanicMethod)
+	// Without a connectMethod every Register would hand out nil, failing far from the cause
+	if pool.callbacks.Load().connectMethod == nil {
+		panic(ErrNilConnectMethod)
+	}
+
+	pool.metricTags = map[string]string{"pool": pool.name}
+	pool.creations.now = pool.clock.Now
+	pool.createdAt = pool.clock.Now()
+
+	if pool.scheduler != nil {
+		pool.scheduler.clock = pool.clock
+	}
+
+	if pool.historyWidth > 0 && pool.historyBuckets > 0 {
+		pool.history = newHistory(pool.historyWidth, pool.historyBuckets, pool.clock)
+	}
+
+	if pool.diagnostics != nil {
+		pool.diagnostics.logger = pool.diagnostics.logger.With("pool", pool.name)
+	}
+
+	if _, noOp := pool.metricsSink.(NoOpSink); !noOp && pool.metricsSink != nil {
+		pool.SetPanicHandler(pool.callbacks.Load().dealPanicMethod) // Count panics before they reach the configured dealPanicMethod
+		pool.eventHooks = append(pool.eventHooks, pool.reportMetrics)
+	}
+
+	for _, newHook := range pool.eventHookFactories {
+		eventHook, err := newHook(pool)
+		if err != nil {
+			pool.handlePanic(err)
+			continue
+		}
+
+		pool.eventHooks = append(pool.eventHooks, eventHook)
+	}
+
+	// Testability mode runs callbacks inline, whatever WithCallbackWorkers asked for
+	if pool.testability {
+		pool.callbackWorkers = 0
+	}
+
+	pool.executor = newExecutor(pool.callbackWorkers, pool.clock)
+	pool.callbacks.update(func(next *callbacks) {
+		next.idleCloseMethod = pool.closeMethodFor(CloseIdle, next.closeMethod)
+		next.onIdleEvict = pool.observeIdleEviction
+	})
+
+	pool.shrinkTarget.Store(-1)
+	pool.pool = newConnectorSet(connectorSetOptions{
+		globalIDs:     pool.globalIDs,
+		maxIdleBytes:  &pool.maxIdleBytes,
+		callbacks:     &pool.callbacks,
+		unusedGrace:   pool.unusedGrace,
+		segmented:     pool.demoteAfter > 0,
+		deterministic: pool.deterministic,
+		recycle:       true,
+		manual:        pool.testability,
+		config:        &pool.config,
+		random:        rand.New(pool.randSource),
+		keyHasher:     pool.keyHasher,
+		clock:         pool.clock,
+		onStop:        pool.connectorStopped,
+		afterClear:    pool.afterClear,
+		executor:      pool.executor,
+	})
+
+	if pool.healthCheckFraction > 0 && pool.healthCheckInterval > 0 {
+		go pool.sampledHealthCheck(pool.healthCheckFraction, pool.healthCheckInterval) // Starts the background health checks
+	}
+
+	if pool.demoteAfter > 0 {
+		go pool.demoteIdle() // Starts the background demotion to the cold idle segment
+	}
+
+	if pool.lifecycleLogging {
+		pool.lifecycleLogger = pool.lifecycleLoggerFor()
+		pool.logStart()
+	}
+
+	if pool.warmupRamp > 0 {
+		go pool.rampUp() // Starts the warm-up ramp
+	}
+
 	return pool
 }
 
-// searchConnector finds a connector in the connectPool.
-func (p *connectPool) searchConnector() (Connect connector) {
+// afterClear is invoked by the connectorSet after each automatic clear pass.
+func (p *connectPool) afterClear(evicted int) {
+	p.clearSweeps.Add(1)
+	p.evictedConnectors.Add(uint64(evicted))
+	p.topUpLabels()
 
-	freeConnect := p.pool.GetFreeConnector() // Try to get a free connector from the existing pool
-	if freeConnect != nil {
-		Connect = freeConnect // If there is a free connector in the pool, use it directly
+	// Evict hooks are user code, keep them off the clear goroutine
+	if evicted > 0 {
+		p.executor.Submit(func() {
+			for i := 0; i < evicted; i++ {
+				p.emit(EventEvict)
+			}
+		})
+	}
+}
+
+// searchConnector finds a connector in the connectPool, waiting until one is available or ctx is done.
+func (p *connectPool) searchConnector(ctx context.Context, priority Priority) (Connect connector, err error) {
+	p.trackWaiters(p.waiters.Add(1))
+	defer p.waiters.Add(-1)
+	defer func() { p.recordAcquireOutcome(err) }()
+
+	// Waits for the Throttle limit first, if any
+	if err = p.waitThrottle(ctx); err != nil {
+		return nil, err
+	}
+
+	// With priority queues, waiters take turns according to their class
+	if p.scheduler != nil {
+		return p.scheduler.acquire(ctx, priority, p.tryAcquire, &p.idleSignal)
 	}
 
 	for {
-		// If Connect is not nil, return it
-		if Connect != nil {
+		// If a connector is available, return it
+		if Connect, err = p.tryAcquire(); Connect != nil || err != nil {
 			return
 		}
 
-		maxSize := p.Cap() // Get the maximum number of connections in the pool
-
-		// Check if the pool has reached its maximum size, if not, create a new Connector
-		if p.Size() < maxSize {
-			return p.pool.AddConnector(&p.connectMethod, &p.dealPanicMethod) // Create and return a new Connector in the pool
+		if err = ctx.Err(); err != nil {
+			return
 		}
 
 		runtime.Gosched() // Yield the processor to allow other goroutines to run
 	}
 }
 
-func (p *connectPool) Register() (newConnect any, cancelFunc func()) {
-	c := p.searchConnector()
+// tryConnector makes a single attempt to find a connector, returning nil if the pool is busy.
+func (p *connectPool) tryConnector() connector {
+	if p.Err() != nil {
+		return nil // The pool has failed
+	}
+
+	freeConnect := p.pool.GetFreeConnector() // Try to get a free connector from the existing pool
+	if freeConnect != nil {
+		return freeConnect // If there is a free connector in the pool, use it directly
+	}
+
+	maxSize := p.Cap() // Get the maximum number of connections in the pool
+
+	// Check if the pool has reached its maximum size, if not, create a new Connector
+	if p.Size() < maxSize && p.takeCreationBudget() {
+		Connect := p.pool.AddConnector() // Create and return a new Connector in the pool
+		if Connect == nil {
+			return nil // The pool was closed
+		}
+
+		p.recordCreation(Connect)
+		p.tuning.dialed.Add(1)
+		p.labelConnector(Connect)
+		p.sampleSize(Connect)
+		p.emit(EventCreate)
+		return Connect
+	}
+
+	return nil
+}
+
+func (p *connectPool) Register(options ...RegisterOption) (newConnect any, cancelFunc func()) {
+	config := newRegisterConfig(options)
+
+	c, _ := p.searchConnector(context.Background(), config.priority)
 	if c == nil {
 		return nil, nil
 	}
 
-	c.StartWorking()
-	return c.GetConnect(), c.StopWorking
+	config.startWorking(c)
+	return p.handOut(c)
 }
 
-func (p *connectPool) RegisterWithTimeLimit(deadLine time.Duration) (newConnect any, cancelFunc func()) {
-	c := p.searchConnector()
+func (p *connectPool) RegisterWithTimeLimit(deadLine time.Duration, options ...RegisterOption) (newConnect any, cancelFunc func()) {
+	c, _ := p.searchConnector(context.Background(), newRegisterConfig(options).priority)
 	if c == nil {
 		return nil, nil
 	}
 
+	if deadLine <= 0 && p.diagnostics != nil {
+		p.diagnostics.warn(misuseInvalidDeadline, debug.Stack())
+	}
+
 	c.StartTimingWork(deadLine)
-	return c.GetConnect(), c.StopWorking
+	return p.handOut(c)
+}
+
+// handOut gives c to a caller, returning its connection variable and the cancelFunc which stops c working and reports
+// the release. The returned cancelFunc is idempotent, so a repeated call can never release a connector reacquired by someone else.
+func (p *connectPool) handOut(c connector) (newConnect any, cancelFunc func()) {
+	r := new(release)
+	p.track(r, c)
+	return p.intercept(r), r.cancel
+}
+
+// track records r as the hand-out of c and counts the acquisition.
+func (p *connectPool) track(r *release, c connector) {
+	r.pool, r.connector, r.generation, r.work = p, c, c.Generation(), c.WorkID()
+
+	// In diagnostics mode, remember where the connection was registered to report a forgotten cancelFunc
+	if p.diagnostics != nil {
+		r.stack = debug.Stack()
+		runtime.SetFinalizer(r, (*release).finalize)
+	}
+
+	p.borrowCount.Add(1)
+	working := p.inUse.Add(1)
+	p.history.record(func(b *Bucket) { b.PeakWorking = max(b.PeakWorking, working) })
+	p.emit(EventAcquire)
+}
+
+// release tracks a single hand-out of a connector.
+type release struct {
+	pool       *connectPool  // Pool the connector belongs to
+	connector  connector     // Connector handed out
+	generation uint64        // Generation of the connector when it was handed out
+	work       uint64        // Work of the connector this hand-out started
+	released   atomic.Bool   // Whether the cancelFunc has been called
+	lease      atomic.Uint64 // Number of the Lease holding the release, advanced when the release is reused
+	stack      []byte        // Stack of the registration, recorded in diagnostics mode only
+}
+
+func (r *release) cancel() {
+	if !r.released.CompareAndSwap(false, true) {
+		if r.pool.diagnostics != nil {
+			r.pool.diagnostics.warn(misuseCancelTwice, debug.Stack())
+		}
+		return
+	}
+
+	r.pool.inUse.Add(-1)
+
+	// The connector's work ended on its own and its shell was recycled since, so it is no longer this hand-out's
+	if r.connector.Generation() != r.generation {
+		r.pool.emit(EventRelease)
+		return
+	}
+
+	// ForceEvictAll closed the connector while it was handed out
+	if r.connector.Retired() {
+		r.pool.emit(EventRelease)
+		return
+	}
+
+	// The deadline already released this hand-out, and the connector may be working for another holder now
+	if r.connector.WorkEnded(r.work) {
+		removed := r.connector.IsDiscarded() && r.pool.discard(r.connector)
+		r.pool.emit(EventRelease)
+
+		if removed {
+			r.pool.emit(EventEvict)
+		}
+
+		return
+	}
+
+	r.pool.trackHoldTime(r.connector.HoldDuration())
+	r.pool.reportRelease(r.connector, ReleaseCallerCancelled)
+
+	// Closes the connector instead of reusing it if the caller closed it, or it fails validation and cannot be reset
+	if r.connector.IsDiscarded() || (!r.pool.validOnReturn(r.connector) && r.connector.Reset() != nil) {
+		removed := r.pool.discard(r.connector)
+		r.connector.StopWorking()
+		r.pool.emit(EventRelease)
+
+		if removed {
+			r.pool.emit(EventEvict)
+		}
+
+		return
+	}
+
+	r.pool.sampleSize(r.connector) // Refresh the size before the connector becomes idle
+	r.connector.StopWorking()
+	r.pool.emit(EventRelease)
+}
+
+// finalize reports a cancelFunc that became unreachable without being called.
+func (r *release) finalize() {
+	if !r.released.Load() {
+		r.pool.diagnostics.warn(misuseForgottenCancel, r.stack)
+	}
+}
+
+// discard removes c from the pool and closes it with closeMethod, or with its own Close method
+// if no closeMethod is configured and c was discarded by its user or a forced eviction. It reports whether c was
+// still in the set.
+func (p *connectPool) discard(c connector) (removed bool) {
+	closeMethod := p.callbacks.Load().closeMethod
+	if closeMethod == nil && c.IsDiscarded() {
+		closeMethod = closeCloser
+	}
+
+	closeMethod = p.closeMethodFor(CloseDiscarded, closeMethod)
+	p.checkDeadOnArrival(c)
+
+	// A discarded connector already detached from the set by a forced eviction is closed now that its holder is done
+	removed = p.pool.RemoveConnector(c, closeMethod)
+	if !removed && c.IsDiscarded() {
+		p.executor.Submit(func() { c.Do(closeMethod, &p.callbacks) })
+	}
+
+	return removed
+}
+
+// validOnReturn runs the validateOnReturn method against c, treating a panic as invalid.
+// Connectors that have already been removed from the pool are not validated.
+func (p *connectPool) validOnReturn(c connector) (valid bool) {
+	if p.validateOnReturn == nil || !p.pool.Contains(c) {
+		return true
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			valid = false
+
+			p.handlePanic(r)
+		}
+	}()
+
+	return p.validateOnReturn(c.GetConnect())
+}
+
+// sampleSize records the memory held by c's connection variable if a connSize method is configured.
+func (p *connectPool) sampleSize(c connector) {
+	if p.connSize == nil {
+		return
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			p.handlePanic(r)
+		}
+	}()
+
+	c.SetMemorySize(p.connSize(c.GetConnect()))
 }
 
 func (p *connectPool) WorkingNumber() int {
 	return int(p.pool.WorkingNumber())
 }
 
+func (p *connectPool) Name() string {
+	return p.name
+}
+
 func (p *connectPool) Cap() int {
-	return p.cap
+	return p.config.Load().maxSize
+}
+
+func (p *connectPool) MinSize() int {
+	return p.config.Load().minSize
 }
 
 func (p *connectPool) MaxFreeTime() time.Duration {
-	return time.Duration(atomic.LoadInt64((*int64)(&p.maxFreeTime)))
+	return p.config.Load().maxFreeTime
 }
 
 func (p *connectPool) AutoClearInterval() time.Duration {
-	return time.Duration(atomic.LoadInt64((*int64)(&p.autoClearInterval)))
+	return p.config.Load().autoClearInterval
+}
+
+func (p *connectPool) SetMaxFreeTime(maxFreeTime time.Duration) {
+	p.config.update(func(next *runtimeConfig) { next.maxFreeTime = maxFreeTime })
+}
+
+// SetAutoClearInterval sets the interval between two automatic clear passes. The pass currently awaited is rescheduled
+// autoClearInterval from now, so lowering the interval takes effect at once. A non-positive interval is rejected with
+// ErrInvalidConfig.
+func (p *connectPool) SetAutoClearInterval(autoClearInterval time.Duration) error {
+	if autoClearInterval <= 0 {
+		return fmt.Errorf("%w: autoClearInterval %v is not positive", ErrInvalidConfig, autoClearInterval)
+	}
+
+	p.config.update(func(next *runtimeConfig) { next.autoClearInterval = autoClearInterval })
+	p.pool.RearmAutoClear()
+	return nil
 }
 
 func (p *connectPool) Size() int {
 	return p.pool.Size()
 }
 
+// GetByToken returns the connection of the connector stored under token, as reported by Snapshot, and whether the
+// pool holds such a connector.
+func (p *connectPool) GetByToken(token uint64) (any, bool) {
+	c := p.pool.GetConnector(token)
+	if c == nil {
+		return nil, false
+	}
+
+	return c.GetConnect(), true
+}
+
+func (p *connectPool) BorrowForever(connect any) error {
+	c := p.pool.FindConnector(connect)
+	if c == nil {
+		return ErrConnectNotFound
+	}
+
+	c.SetPermanentlyWorking(true)
+	return nil
+}
+
+func (p *connectPool) UnBorrowForever(connect any) error {
+	c := p.pool.FindConnector(connect)
+	if c == nil {
+		return ErrConnectNotFound
+	}
+
+	c.SetPermanentlyWorking(false)
+	p.idleSignal.signal()
+	return nil
+}
+
+// Close closes the pool gracefully: idle connections are closed at once in the configured close order, and
+// connections in use are closed when their holders release them.
 func (p *connectPool) Close() {
-	p.pool.Close() // Close the pool
+	var first bool
+	p.closeOnce.Do(func() { close(p.done); first = true }) // Stop the pool's background goroutines
+	idle := p.pool.TakeIdle()                              // Take the idle connectors out before closing the set
+	p.pool.Close()                                         // Close the pool
+	p.closeInOrder(idle, CloseShutdown)                    // Close the idle connections
+	p.executor.Close(p.drainTimeout)                       // Wait for queued callbacks to finish
+	p.emit(EventClose)
+
+	// Logged last so the statistics are final, and once
+	if first {
+		p.logClose()
+	}
+}
+
+func (p *connectPool) ClearNow() int {
+	return p.pool.ClearNow()
+}
+
+// DisableAutoClear pauses the background clear passes until EnableAutoClear, so that no connection is evicted for
+// idling; ClearNow still runs a pass on demand.
+func (p *connectPool) DisableAutoClear() {
+	p.pool.PauseAutoClear()
+}
+
+// EnableAutoClear resumes the clear passes paused by DisableAutoClear.
+func (p *connectPool) EnableAutoClear() {
+	p.pool.ResumeAutoClear()
+}
+
+// SetPanicHandler replaces the method handling panics raised by callbacks; it is safe to call while the pool is in use.
+func (p *connectPool) SetPanicHandler(dealPanicMethod func(panicInfo any)) {
+	dealPanicMethod = p.reportPanics(dealPanicMethod)
+	p.callbacks.update(func(next *callbacks) { next.dealPanicMethod = dealPanicMethod })
+}
+
+// WrapCloseMethod composes fn with the current closeMethod: fn is called first, then the previous closeMethod, which
+// still runs if fn panics. It is safe to call while the pool is in use. In a pool created without a closeMethod, fn is
+// followed by the io.Closer fallback, closing every connection implementing it.
+func (p *connectPool) WrapCloseMethod(fn func(connect any)) {
+	p.callbacks.update(func(next *callbacks) {
+		closeMethod := next.closeMethod
+		if closeMethod == nil {
+			closeMethod = closeCloser
+		}
+
+		next.closeMethod = func(connect any) {
+			defer closeMethod(connect)
+			fn(connect)
+		}
+
+		next.idleCloseMethod = p.closeMethodFor(CloseIdle, next.closeMethod)
+	})
+}
+
+// handlePanic handles panicInfo with the current dealPanicMethod.
+func (p *connectPool) handlePanic(panicInfo any) {
+	p.callbacks.handlePanic(panicInfo)
+}
+
+// isClosed reports whether Close has been called.
+func (p *connectPool) isClosed() bool {
+	select {
+	case <-p.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// sleep waits d on the pool's clock, returning false if stop is closed or the pool is closed first. stop may be nil.
+func (p *connectPool) sleep(d time.Duration, stop <-chan struct{}) bool {
+	timer := p.clock.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C():
+		return true
+	case <-stop:
+		return false
+	case <-p.done:
+		return false
+	}
+}
+
+func (p *connectPool) BorrowCount() int64 {
+	return p.borrowCount.Load()
+}
+
+// CountByState returns the number of connectors in each of the "working", "idle", "expired", "unhealthy" and
+// "pendingClose" states.
+func (p *connectPool) CountByState() map[string]int {
+	return p.pool.CountByState(p.MaxFreeTime())
+}
+
+func (p *connectPool) AllFree() []any {
+	return p.pool.FreeConnects()
+}
+
+// ForEachWorking calls fn with every working connection and how long it has been working, stopping once fn returns
+// false. fn runs under the pool's read lock, so it must not create or evict connections.
+func (p *connectPool) ForEachWorking(fn func(conn any, heldFor time.Duration) bool) {
+	p.pool.ForEachWorking(func(c connector) bool {
+		return fn(c.GetConnect(), c.HoldDuration())
+	})
 }
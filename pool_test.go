@@ -0,0 +1,355 @@
+package connectpool
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/HuXin0817/ConnectPool/clock"
+)
+
+// newTestPool creates a pool of the connections connectMethod creates, checking its invariants after every state
+// transition and validating it after every event. The test fails if either found a violation by the time the pool is
+// closed at its end, the first invariant violation being kept as the pool's error.
+func newTestPool(t testing.TB, connectMethod func() any, options ...Option) *connectPool {
+	t.Helper()
+
+	var invalid atomic.Pointer[error] // First error Validate reported, nil if none
+	validate := WithEventHook(func(pool ConnectPool, event Event) {
+		if errs := pool.(*connectPool).Validate(); len(errs) > 0 {
+			err := fmt.Errorf("after %s event: %w", event, errors.Join(errs...))
+			invalid.CompareAndSwap(nil, &err)
+		}
+	})
+
+	p := NewConnectPool(connectMethod, append([]Option{WithInvariantChecks(true), WithFatalOn(FatalInvariantViolation), validate}, options...)...).(*connectPool)
+	t.Cleanup(func() {
+		p.Close()
+
+		if n := p.invariantViolations.Load(); n > 0 {
+			t.Errorf("%d invariant violations: %v", n, p.Err())
+		}
+
+		if err := invalid.Load(); err != nil {
+			t.Errorf("Validate failed %v", *err)
+		}
+	})
+	return p
+}
+
+// counter returns a connectMethod creating the connections 1, 2, 3 and so on.
+func counter() func() any {
+	var n atomic.Int64
+	return func() any { return n.Add(1) }
+}
+
+func TestSetAutoClearInterval_Rearms(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	p := newTestPool(t, counter(), WithClock(fake), WithAutoClearInterval(time.Hour), WithMaxFreeTime(time.Hour))
+
+	_, cancel := p.Register()
+	cancel()
+
+	// Lowering both values must be honored by the next pass, without waiting out the hour-long timer
+	if err := p.SetAutoClearInterval(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	p.SetMaxFreeTime(time.Millisecond)
+
+	for deadline := time.Now().Add(time.Second); p.Size() > 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("the idle connection was not evicted after %v of fake time", fake.Since(time.Unix(0, 0)))
+		}
+
+		fake.Advance(time.Second)
+	}
+}
+
+func TestSetAutoClearInterval_RejectsNonPositive(t *testing.T) {
+	p := newTestPool(t, counter(), WithAutoClearInterval(time.Minute))
+
+	for _, interval := range []time.Duration{0, -time.Second} {
+		if err := p.SetAutoClearInterval(interval); !errors.Is(err, ErrInvalidConfig) {
+			t.Fatalf("SetAutoClearInterval(%v) error = %v, want %v", interval, err, ErrInvalidConfig)
+		}
+	}
+
+	if interval := p.AutoClearInterval(); interval != time.Minute {
+		t.Fatalf("AutoClearInterval = %v after rejected changes", interval)
+	}
+}
+
+// foreignPool implements ConnectPool only, as a pool written outside this package would. It holds one connection.
+type foreignPool struct {
+	held     atomic.Bool  // Whether the connection is registered
+	released atomic.Int64 // Number of times the connection was given back
+	closed   atomic.Bool  // Whether Close was called
+}
+
+func (f *foreignPool) Register(...RegisterOption) (any, func()) {
+	if f.closed.Load() || !f.held.CompareAndSwap(false, true) {
+		return nil, nil
+	}
+
+	return "conn", func() {
+		f.released.Add(1)
+		f.held.Store(false)
+	}
+}
+
+func (f *foreignPool) RegisterWithTimeLimit(_ time.Duration, options ...RegisterOption) (any, func()) {
+	return f.Register(options...)
+}
+
+func (f *foreignPool) WorkingNumber() int {
+	if f.held.Load() {
+		return 1
+	}
+	return 0
+}
+
+func (f *foreignPool) Size() int                        { return 1 }
+func (f *foreignPool) Cap() int                         { return 1 }
+func (f *foreignPool) MaxFreeTime() time.Duration       { return 0 }
+func (f *foreignPool) AutoClearInterval() time.Duration { return 0 }
+func (f *foreignPool) Close()                           { f.closed.Store(true) }
+
+func TestForeignPool_Helpers(t *testing.T) {
+	pool := new(foreignPool)
+
+	got, err := RegisterFunc(pool, func(connect any) (any, error) { return connect, nil })
+	if err != nil || got != "conn" {
+		t.Fatalf("RegisterFunc = %v, %v, want conn", got, err)
+	}
+
+	classic := Classic(pool)
+	conn, err := classic.Get()
+	if err != nil || conn != "conn" {
+		t.Fatalf("Classic Get = %v, %v, want conn", conn, err)
+	}
+
+	if err = classic.Put(conn, nil); err != nil {
+		t.Fatalf("Classic Put: %v", err)
+	}
+
+	if n := pool.released.Load(); n != 2 || pool.held.Load() {
+		t.Fatalf("connection given back %d times, held: %v, want 2 and not held", n, pool.held.Load())
+	}
+
+	pool.Close()
+	if _, err = RegisterFunc(pool, func(connect any) (any, error) { return connect, nil }); !errors.Is(err, ErrPoolClosed) {
+		t.Fatalf("RegisterFunc error = %v after Close, want ErrPoolClosed", err)
+	}
+}
+
+func TestWithGlobalIDs_NoOverlap(t *testing.T) {
+	first := newTestPool(t, counter(), WithGlobalIDs(true))
+	second := newTestPool(t, counter(), WithGlobalIDs(true))
+
+	tokens := make(map[uint64]bool)
+	for _, p := range []*connectPool{first, second, first, second} {
+		_, cancel := p.Register()
+		defer cancel()
+	}
+
+	for _, p := range []*connectPool{first, second} {
+		for _, s := range p.Snapshot() {
+			if tokens[s.Token] {
+				t.Fatalf("token %d used by both pools", s.Token)
+			}
+			tokens[s.Token] = true
+		}
+	}
+
+	if len(tokens) != 4 {
+		t.Fatalf("%d distinct tokens, want 4", len(tokens))
+	}
+}
+
+func TestWrapCloseMethod_KeepsCloserFallback(t *testing.T) {
+	conn := new(closer)
+	p := newTestPool(t, func() any { return conn }, WithTestabilityMode())
+
+	var wrapped atomic.Int64
+	p.WrapCloseMethod(func(connect any) {
+		if conn.closed.Load() {
+			t.Error("io.Closer fallback ran before the wrapping function")
+		}
+		wrapped.Add(1)
+	})
+
+	_, cancel := p.Register()
+	cancel()
+
+	if n := p.Flush(); n != 1 {
+		t.Fatalf("Flush closed %d connections, want 1", n)
+	}
+
+	if wrapped.Load() != 1 || !conn.closed.Load() {
+		t.Fatalf("wrapping function called %d times, Close called: %v", wrapped.Load(), conn.closed.Load())
+	}
+}
+
+func TestNew_TestabilityMode(t *testing.T) {
+	for name, options := range map[string][]Option{
+		"alone": {WithTestabilityMode()},
+		// Testability mode wins over callback workers, whichever option comes last
+		"callback workers after":  {WithTestabilityMode(), WithCallbackWorkers(8)},
+		"callback workers before": {WithCallbackWorkers(8), WithTestabilityMode()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			before := runtime.NumGoroutine()
+			p := newTestPool(t, counter(), options...)
+
+			if after := runtime.NumGoroutine(); after > before {
+				t.Fatalf("%d goroutines started by NewConnectPool, want none", after-before)
+			}
+
+			if p.executor != nil {
+				t.Fatal("callbacks run on workers, want inline")
+			}
+		})
+	}
+}
+
+func TestConstructors_RejectNilConnectMethod(t *testing.T) {
+	for name, construct := range map[string]func(){
+		"NewConnectPool":  func() { NewConnectPool(nil) },
+		"NewConnector":    func() { NewConnector(Callbacks{}) },
+		"NewConnectorSet": func() { NewConnectorSet(Callbacks{}, time.Minute, time.Minute) },
+		"NewPool":         func() { NewPool[int](nil) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r != ErrNilConnectMethod {
+					t.Fatalf("%s panicked with %v, want ErrNilConnectMethod", name, r)
+				}
+			}()
+
+			construct()
+		})
+	}
+}
+
+func TestDisableAutoClear_NoEvictions(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	p := newTestPool(t, counter(), WithClock(fake), WithAutoClearInterval(time.Second), WithMaxFreeTime(time.Minute))
+
+	_, cancel := p.Register()
+	cancel()
+
+	// Pauses once the first pass is done and the loop waits for its timer
+	waitUntil(t, "the first clear pass", func() bool { return p.clearSweeps.Load() == 1 })
+	p.DisableAutoClear()
+
+	for range 120 {
+		fake.Advance(time.Second)
+	}
+	time.Sleep(10 * time.Millisecond) // Leaves a pass that should not run the time to run
+
+	if sweeps, size := p.clearSweeps.Load(), p.Size(); sweeps != 1 || size != 1 {
+		t.Fatalf("%d clear passes, size %d while disabled, want 1 and 1", sweeps, size)
+	}
+
+	p.EnableAutoClear()
+	waitUntil(t, "the expired connection is evicted", func() bool { return p.Size() == 0 })
+}
+
+func TestSetMaxFreeTime_DuringAutoClear(t *testing.T) {
+	p := newTestPool(t, counter(), WithAutoClearInterval(time.Millisecond), WithMaxFreeTime(time.Millisecond))
+
+	// Keeps the clear passes busy with connections to check while both values change under them
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 500; i++ {
+			if _, cancel := p.Register(); cancel != nil {
+				cancel()
+			}
+		}
+	}()
+
+	for i := 1; i <= 500; i++ {
+		p.SetMaxFreeTime(time.Duration(i) * time.Microsecond)
+		if err := p.SetAutoClearInterval(time.Duration(i) * time.Microsecond); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-done
+
+	if maxFreeTime, interval := p.MaxFreeTime(), p.AutoClearInterval(); maxFreeTime != 500*time.Microsecond || interval != 500*time.Microsecond {
+		t.Fatalf("MaxFreeTime %v, AutoClearInterval %v, want both 500µs", maxFreeTime, interval)
+	}
+}
+
+func TestValidateOnReturn_ClosesAndRedials(t *testing.T) {
+	var closed []any
+	var panics atomic.Int64
+	p := newTestPool(t, counter(), WithTestabilityMode(),
+		WithCloseMethod(func(connect any) { closed = append(closed, connect) }),
+		WithDealPanicMethod(func(any) { panics.Add(1) }),
+		WithValidateOnReturn(func(connect any) bool {
+			switch connect {
+			case int64(1):
+				return false
+			case int64(2):
+				panic("validate")
+			}
+			return true
+		}))
+
+	// The first connection fails validation, the second panics in it; both are closed and redialed
+	for _, want := range []int64{1, 2, 3} {
+		connect, cancel := p.Register()
+		if connect != want {
+			t.Fatalf("Register returned connection %v, want a fresh dial, %d", connect, want)
+		}
+		cancel()
+	}
+
+	if len(closed) != 2 || closed[0] != int64(1) || closed[1] != int64(2) {
+		t.Fatalf("closed connections %v, want [1 2]", closed)
+	}
+	if n := panics.Load(); n != 1 {
+		t.Fatalf("%d panics handled, want 1", n)
+	}
+
+	// The valid connection is reused
+	connect, cancel := p.Register()
+	defer cancel()
+
+	if connect != int64(3) {
+		t.Fatalf("Register returned connection %v, want the valid 3 reused", connect)
+	}
+}
+
+func TestSetPanicHandler_DuringPanics(t *testing.T) {
+	var handled atomic.Int64
+	p := newTestPool(t, counter(), WithTestabilityMode(), WithCloseMethod(func(any) { panic("close") }),
+		WithDealPanicMethod(func(any) { handled.Add(1) }))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 1000 {
+			p.SetPanicHandler(func(any) { handled.Add(1) })
+		}
+	}()
+
+	// Each flush closes a connection with the panicking closeMethod while the handler is being replaced
+	const flushes = 1000
+	for range flushes {
+		_, cancel := p.Register()
+		cancel()
+		p.Flush()
+	}
+	<-done
+
+	// Every panic reached whichever handler was current
+	if n := handled.Load(); n != flushes {
+		t.Fatalf("%d panics handled, want %d", n, flushes)
+	}
+}
@@ -0,0 +1,93 @@
+package connectpool
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	pressureUtilizationWeight = 0.5  // Weight of the utilization in Pressure
+	pressureWaitersWeight     = 0.25 // Weight of the waiters in Pressure
+	pressureTimeoutWeight     = 0.25 // Weight of the acquire timeout rate in Pressure
+	timeoutRateDecay          = 0.05 // Weight of the latest acquisition in the timeout rate, so about the last 20 count
+)
+
+// PressureComponents are the inputs of Pressure, each between 0 and 1.
+type PressureComponents struct {
+	Utilization float64 // Connections handed out relative to the cap
+	Waiters     float64 // Goroutines acquiring a connection relative to the cap, capped at 1
+	TimeoutRate float64 // Exponentially weighted fraction of recent acquisitions that timed out
+}
+
+// PressureComponents returns the inputs of Pressure. Like Pressure it only reads atomics.
+func (p *connectPool) PressureComponents() PressureComponents {
+	capacity := float64(max(p.Cap(), 1))
+
+	return PressureComponents{
+		Utilization: min(float64(p.inUse.Load())/capacity, 1),
+		Waiters:     min(float64(p.waiters.Load())/capacity, 1),
+		TimeoutRate: math.Float64frombits(p.timeoutRate.Load()),
+	}
+}
+
+// Pressure scores how loaded the pool is between 0 (idle) and 1 (saturated), as
+//
+//	0.5*Utilization + 0.25*Waiters + 0.25*TimeoutRate
+//
+// so that a fully used pool is at 0.5 and only climbs further once callers queue up or time out. It only reads
+// atomics, so it is cheap enough for admission control on every request.
+func (p *connectPool) Pressure() float64 {
+	c := p.PressureComponents()
+	return pressureUtilizationWeight*c.Utilization + pressureWaitersWeight*c.Waiters + pressureTimeoutWeight*c.TimeoutRate
+}
+
+// trackWaiters raises the recorded maximum of waiters to waiters if needed.
+func (p *connectPool) trackWaiters(waiters int64) {
+	for {
+		most := p.maxWaiters.Load()
+		if waiters <= most || p.maxWaiters.CompareAndSwap(most, waiters) {
+			return
+		}
+	}
+}
+
+// trackHoldTime raises the recorded longest hold of a connection to hold if needed.
+func (p *connectPool) trackHoldTime(hold time.Duration) {
+	for {
+		longest := p.maxHoldTime.Load()
+		if int64(hold) <= longest || p.maxHoldTime.CompareAndSwap(longest, int64(hold)) {
+			return
+		}
+	}
+}
+
+// recordAcquireOutcome folds the outcome of an acquisition into the timeout rate. Cancellations and closed pools
+// say nothing about the load, so they are not counted.
+func (p *connectPool) recordAcquireOutcome(err error) {
+	var timedOut float64
+	switch {
+	case err == nil:
+	case errors.Is(err, context.DeadlineExceeded):
+		timedOut = 1
+		p.history.record(func(b *Bucket) { b.AcquireTimeouts++ })
+	default:
+		return
+	}
+
+	updateRate(&p.timeoutRate, timedOut)
+}
+
+// updateRate moves the exponentially weighted rate stored as float64 bits in rate towards sample.
+func updateRate(rate *atomic.Uint64, sample float64) {
+	for {
+		old := rate.Load()
+		next := math.Float64frombits(old)*(1-timeoutRateDecay) + sample*timeoutRateDecay
+
+		if rate.CompareAndSwap(old, math.Float64bits(next)) {
+			return
+		}
+	}
+}
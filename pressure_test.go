@@ -0,0 +1,65 @@
+package connectpool
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+)
+
+// assertPressure fails the test unless p's Pressure is want.
+func assertPressure(t *testing.T, p *connectPool, state string, want float64) {
+	t.Helper()
+
+	if got := p.Pressure(); math.Abs(got-want) > 1e-9 {
+		t.Fatalf("Pressure = %v for %s, components %+v, want %v", got, state, p.PressureComponents(), want)
+	}
+}
+
+func TestPressure_SyntheticStates(t *testing.T) {
+	p := newTestPool(t, counter(), WithCap(2))
+
+	_, first := p.Register()
+	_, second := p.Register()
+	first()
+	second()
+	assertPressure(t, p, "an idle pool", 0)
+
+	_, first = p.Register()
+	assertPressure(t, p, "a half used pool", 0.25)
+
+	// Two callers queue up behind the saturated pool
+	_, second = p.Register()
+	ctx, cancel := context.WithCancel(context.Background())
+	waited := make(chan error, 2)
+	for range 2 {
+		go func() {
+			_, err := p.Acquire(ctx)
+			waited <- err
+		}()
+	}
+	waitUntil(t, "both callers wait", func() bool { return p.waiters.Load() == 2 })
+	assertPressure(t, p, "a saturated pool with a waiter per connection", 0.75)
+
+	// Cancelled acquisitions say nothing about the load
+	cancel()
+	for range 2 {
+		if err := <-waited; !errors.Is(err, context.Canceled) {
+			t.Fatalf("Acquire error = %v, want %v", err, context.Canceled)
+		}
+	}
+	assertPressure(t, p, "a saturated pool", 0.5)
+
+	// Twenty timed-out acquisitions bring the timeout rate to 1-0.95^20
+	for range 20 {
+		ctx, cancel := context.WithTimeout(context.Background(), 0)
+		if _, err := p.Acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Acquire error = %v, want %v", err, context.DeadlineExceeded)
+		}
+		cancel()
+	}
+	assertPressure(t, p, "a saturated pool timing out", 0.5+0.25*(1-math.Pow(0.95, 20)))
+
+	first()
+	second()
+}
@@ -0,0 +1,313 @@
+package connectpool
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/HuXin0817/ConnectPool/clock"
+)
+
+// Callbacks are the methods a Connector is created, reset and closed with, for building custom pools from the
+// package's primitives. Any of them may be nil; a nil DealPanic drops panics.
+type Callbacks struct {
+	Connect   func() any              // Creates a connection
+	Reset     func(connect any) error // Resets a connection's protocol state
+	Close     func(connect any)       // Closes a connection
+	DealPanic func(panicInfo any)     // Handles a panic of the other callbacks
+}
+
+// bundle stores the callbacks in a callbackBundle.
+func (cb Callbacks) bundle() *callbackBundle {
+	bundle := new(callbackBundle)
+	bundle.update(func(next *callbacks) {
+		next.connectMethod = cb.Connect
+		next.resetMethod = cb.Reset
+		next.closeMethod = cb.Close
+		next.idleCloseMethod = cb.Close
+		next.dealPanicMethod = cb.DealPanic
+	})
+
+	return bundle
+}
+
+// Connector is a single connection with the lifecycle the pool manages: acquired and released through lock-free
+// transitions, with optional timed work, exported for building custom pools.
+type Connector struct {
+	c         connector       // Underlying connector
+	callbacks *callbackBundle // Callbacks the connector was created with
+	pooled    bool            // Whether the Connector was handed out by a ConnectPool, which owns its state
+}
+
+// NewConnector creates a released Connector, calling callbacks.Connect right away. Err reports a failed creation. It
+// panics with ErrNilConnectMethod if callbacks.Connect is nil.
+func NewConnector(callbacks Callbacks) *Connector {
+	if callbacks.Connect == nil {
+		panic(ErrNilConnectMethod)
+	}
+
+	bundle := callbacks.bundle()
+
+	c := newConnector(bundle, clock.Real(), nil)
+	c.ReleaseUnused()
+	return &Connector{c: c, callbacks: bundle}
+}
+
+// Conn returns the connection.
+func (c *Connector) Conn() any {
+	return c.c.GetConnect()
+}
+
+// Err returns the error of a failed creation, if any.
+func (c *Connector) Err() error {
+	return c.c.Err()
+}
+
+// ID returns the token the Connector is stored under in its set, 0 if it is in none.
+func (c *Connector) ID() uint64 {
+	return c.c.ID()
+}
+
+// CreatedAt returns the time the Connector was created.
+func (c *Connector) CreatedAt() time.Time {
+	return c.c.CreatedAt()
+}
+
+// TryAcquire marks the Connector as working if it is free, reporting whether it did. Only one of concurrent callers
+// succeeds. It always fails on a Connector handed out by AcquireConnector.
+func (c *Connector) TryAcquire() bool {
+	return !c.pooled && c.c.TryStartWorking()
+}
+
+// AcquireFor is TryAcquire releasing the Connector automatically after d.
+func (c *Connector) AcquireFor(d time.Duration) bool {
+	if !c.TryAcquire() {
+		return false
+	}
+
+	c.c.StartTimingWork(d)
+	return true
+}
+
+// Release marks the Connector as free again. It does nothing on a Connector handed out by AcquireConnector, which is
+// released by the function returned alongside it.
+func (c *Connector) Release() {
+	if !c.pooled {
+		c.c.StopWorking()
+	}
+}
+
+// IsFree reports whether the Connector can be acquired.
+func (c *Connector) IsFree() bool {
+	return c.c.IsFree()
+}
+
+// IdleFor returns the time since the Connector was last released, 0 while it is working.
+func (c *Connector) IdleFor() time.Duration {
+	return c.c.SinceLastWorkingTime()
+}
+
+// SinceLastWorkingTime returns the time since the Connector last worked, even while it is working. Read right after
+// AcquireConnector, it is how long the connection sat idle before being acquired.
+func (c *Connector) SinceLastWorkingTime() time.Duration {
+	return c.c.Age() - c.c.LastWorkingTime().Sub(c.c.CreatedAt()) // Age follows the connector's clock
+}
+
+// DoE calls f with the connection, returning its error. A panic of f is handled with callbacks.DealPanic and returned
+// as ErrConnectorFuncPanicked.
+func (c *Connector) DoE(f func(connect any) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", ErrConnectorFuncPanicked, r)
+
+			c.callbacks.handlePanic(r)
+		}
+	}()
+
+	return f(c.c.GetConnect())
+}
+
+// Reset resets the connection with callbacks.Reset.
+func (c *Connector) Reset() error {
+	return c.c.Reset()
+}
+
+// Close closes the connection with callbacks.Close, handling a panic with callbacks.DealPanic. It does nothing on a
+// Connector handed out by AcquireConnector; discard it through its pool instead.
+func (c *Connector) Close() {
+	if c.pooled {
+		return
+	}
+
+	c.c.Do(c.callbacks.Load().closeMethod, c.callbacks)
+}
+
+// AcquireConnector is an advanced API for integrations such as tracing wrappers that need the connector's metadata
+// at acquisition time. It waits for a connection like Register and returns its Connector, along with the function
+// releasing it, which behaves like Register's cancelFunc. The pool owns the Connector's state: its TryAcquire,
+// AcquireFor, Release and Close do nothing, so only the returned release function gives it back, after which the
+// Connector should no longer be used. A late call still reads the connection it held, as its shell is never recycled
+// for another one.
+func (p *connectPool) AcquireConnector(options ...RegisterOption) (*Connector, func(), error) {
+	config := newRegisterConfig(options)
+
+	c, err := p.searchConnector(context.Background(), config.priority)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	config.startWorking(c)
+	c.KeepShell() // The caller may keep the Connector past its release
+	_, release := p.handOut(c)
+
+	return &Connector{c: c, callbacks: &p.callbacks, pooled: true}, release, nil
+}
+
+// ConnectorSet is a set of Connectors whose idle ones are closed by a background clear pass, exported for building
+// custom pools. Close callbacks run on the clear pass's goroutine.
+type ConnectorSet struct {
+	set       connectorSet    // Underlying set
+	callbacks *callbackBundle // Callbacks of every Connector in the set
+	config    configBundle    // Cap, idle time after which the clear pass closes a Connector and interval between passes
+	addMutex  sync.Mutex      // Guards adding, so that concurrent Adds cannot together exceed the cap
+	adding    int             // Number of Connectors being created, not yet counted by Size
+}
+
+// NewConnectorSet creates an empty ConnectorSet whose Connectors are created with callbacks and closed once idle for
+// longer than maxFreeTime, checked every clearInterval. It panics with ErrNilConnectMethod if callbacks.Connect is nil.
+func NewConnectorSet(callbacks Callbacks, maxFreeTime, clearInterval time.Duration) *ConnectorSet {
+	if callbacks.Connect == nil {
+		panic(ErrNilConnectMethod)
+	}
+
+	s := &ConnectorSet{callbacks: callbacks.bundle()}
+	s.config.current.Store(&runtimeConfig{maxFreeTime: maxFreeTime, autoClearInterval: clearInterval})
+
+	s.set = newConnectorSet(connectorSetOptions{
+		callbacks: s.callbacks,
+		config:    &s.config,
+		random:    rand.New(rand.NewSource(time.Now().UnixNano())),
+		clock:     clock.Real(),
+	})
+	return s
+}
+
+// wrap exports c, nil if c is nil.
+func (s *ConnectorSet) wrap(c connector) *Connector {
+	if c == nil {
+		return nil
+	}
+
+	return &Connector{c: c, callbacks: s.callbacks}
+}
+
+// Add creates a Connector in the set and returns it acquired, or nil if the set is closed or full. A Connector whose
+// creation failed, as reported by its Err, should be removed.
+func (s *ConnectorSet) Add() *Connector {
+	if s.reserve(1) == 0 {
+		return nil
+	}
+	defer s.unreserve(1)
+
+	return s.wrap(s.set.AddConnector())
+}
+
+// SetCap limits the set to n Connectors, beyond which Add returns nil; 0, the default, sets no limit. Connectors
+// already in the set are kept.
+func (s *ConnectorSet) SetCap(n int) {
+	s.config.update(func(next *runtimeConfig) { next.maxSize = max(n, 0) })
+}
+
+// Cap returns the limit set by SetCap, 0 if none.
+func (s *ConnectorSet) Cap() int {
+	return s.config.Load().maxSize
+}
+
+// remaining returns how many more Connectors the set can hold, counting those being created. addMutex is held.
+func (s *ConnectorSet) remaining() int {
+	maxSize := s.config.Load().maxSize
+	if maxSize == 0 {
+		return math.MaxInt
+	}
+
+	return max(maxSize-s.set.Size()-s.adding, 0)
+}
+
+// reserve counts up to n Connectors as being created, as many as the set has room for, and returns that number.
+func (s *ConnectorSet) reserve(n int) int {
+	s.addMutex.Lock()
+	defer s.addMutex.Unlock()
+
+	n = max(min(n, s.remaining()), 0)
+	s.adding += n
+	return n
+}
+
+// unreserve uncounts n Connectors counted by reserve, once created and counted by Size.
+func (s *ConnectorSet) unreserve(n int) {
+	s.addMutex.Lock()
+	s.adding -= n
+	s.addMutex.Unlock()
+}
+
+// AddConnectors creates up to n Connectors in the set concurrently, one goroutine each, and returns them acquired once
+// all have finished. It creates none if n is not positive and no more than the cap leaves room for; fewer are also
+// returned if the set is closed meanwhile.
+func (s *ConnectorSet) AddConnectors(n int) []*Connector {
+	if n = s.reserve(n); n == 0 {
+		return nil
+	}
+	defer s.unreserve(n)
+
+	created := make([]*Connector, n)
+
+	var wg sync.WaitGroup
+	wg.Add(n)
+
+	for i := range created {
+		go func(i int) {
+			defer wg.Done()
+			created[i] = s.wrap(s.set.AddConnector()) // Already reserved
+		}(i)
+	}
+
+	wg.Wait()
+
+	// Drops the Connectors the closed set refused
+	added := created[:0]
+	for _, c := range created {
+		if c != nil {
+			added = append(added, c)
+		}
+	}
+
+	return added
+}
+
+// AcquireFree acquires a free Connector of the set, or returns nil if none is free.
+func (s *ConnectorSet) AcquireFree() *Connector {
+	return s.wrap(s.set.GetFreeConnector())
+}
+
+// Remove removes c from the set and closes it, reporting whether it was in the set.
+func (s *ConnectorSet) Remove(c *Connector) bool {
+	return s.set.RemoveConnector(c.c, s.callbacks.Load().closeMethod)
+}
+
+// Size returns the number of Connectors in the set.
+func (s *ConnectorSet) Size() int {
+	return s.set.Size()
+}
+
+// Close closes the free Connectors and stops the clear pass. Connectors still acquired are left to their holders.
+func (s *ConnectorSet) Close() {
+	closeMethod := s.callbacks.Load().closeMethod
+	for _, c := range s.set.TakeIdle() {
+		c.Do(closeMethod, s.callbacks)
+	}
+
+	s.set.Close()
+}
@@ -0,0 +1,170 @@
+package connectpool
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// dialer returns a Connect callback taking delay per connection, like a network dial.
+func dialer(delay time.Duration) Callbacks {
+	connect := counter()
+	return Callbacks{Connect: func() any {
+		time.Sleep(delay)
+		return connect()
+	}}
+}
+
+func TestConnectorSet_AddConnectors(t *testing.T) {
+	s := NewConnectorSet(dialer(0), time.Minute, time.Minute)
+	defer s.Close()
+
+	added := s.AddConnectors(10)
+	if len(added) != 10 || s.Size() != 10 {
+		t.Fatalf("AddConnectors added %d connectors, set size %d, want 10", len(added), s.Size())
+	}
+
+	seen := make(map[uint64]bool)
+	for _, c := range added {
+		if seen[c.ID()] {
+			t.Fatalf("connector %d returned twice", c.ID())
+		}
+		seen[c.ID()] = true
+	}
+}
+
+func TestConnectorSet_AddConnectorsBounds(t *testing.T) {
+	s := NewConnectorSet(dialer(0), time.Minute, time.Minute)
+	defer s.Close()
+
+	for _, n := range []int{-1, 0} {
+		if added := s.AddConnectors(n); added != nil || s.Size() != 0 {
+			t.Fatalf("AddConnectors(%d) added %d connectors, set size %d, want none", n, len(added), s.Size())
+		}
+	}
+
+	s.SetCap(3)
+	s.Add()
+
+	if added := s.AddConnectors(10); len(added) != 2 || s.Size() != 3 {
+		t.Fatalf("AddConnectors(10) under cap 3 added %d connectors, set size %d, want 2 and 3", len(added), s.Size())
+	}
+	if c := s.Add(); c != nil {
+		t.Fatal("Add succeeded on a full set")
+	}
+}
+
+// BenchmarkAddConnectors creates 100 connectors taking 100µs each, one after the other or all at once.
+func BenchmarkAddConnectors(b *testing.B) {
+	const n = 100
+
+	b.Run("Sequential", func(b *testing.B) {
+		for range b.N {
+			s := NewConnectorSet(dialer(100*time.Microsecond), time.Minute, time.Minute)
+			for range n {
+				s.Add()
+			}
+			s.Close()
+		}
+	})
+
+	b.Run("Parallel", func(b *testing.B) {
+		for range b.N {
+			s := NewConnectorSet(dialer(100*time.Microsecond), time.Minute, time.Minute)
+			s.AddConnectors(n)
+			s.Close()
+		}
+	})
+}
+
+// releaseObservation is what a release scenario observed of a pool.
+type releaseObservation struct {
+	WorkingAfterRelease int           // Working number after the first release
+	Reused              bool          // Whether the next acquisition got the released connection back
+	WorkingAfterRepeat  int           // Working number after repeating the first release while the connection is reacquired
+	Closed              []any         // Connections closed, in order
+	Size                int           // Size once everything was released
+	Events              map[Event]int // Events emitted
+}
+
+// observeRelease runs a release scenario on a new pool, acquiring connections with acquire.
+func observeRelease(t *testing.T, acquire func(p *connectPool) (any, func())) releaseObservation {
+	var (
+		mutex       sync.Mutex
+		observation = releaseObservation{Events: make(map[Event]int)}
+	)
+	p := newTestPool(t, counter(), WithTestabilityMode(),
+		WithValidateOnReturn(func(connect any) bool { return connect != int64(2) }),
+		WithCloseMethod(func(connect any) {
+			mutex.Lock()
+			observation.Closed = append(observation.Closed, connect)
+			mutex.Unlock()
+		}),
+		WithEventHook(func(_ ConnectPool, event Event) {
+			mutex.Lock()
+			observation.Events[event]++
+			mutex.Unlock()
+		}))
+
+	first, release := acquire(p)
+	release()
+	observation.WorkingAfterRelease = p.WorkingNumber()
+
+	again, releaseAgain := acquire(p)
+	observation.Reused = again == first
+
+	// A repeated release must not give back the connection now held by someone else
+	release()
+	observation.WorkingAfterRepeat = p.WorkingNumber()
+
+	// Connection 2 fails validation, so its release closes it
+	_, releaseSecond := acquire(p)
+	releaseSecond()
+	releaseAgain()
+	p.Close()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	observation.Size = p.Size()
+	return observation
+}
+
+func TestAcquireConnector_ReleaseMatchesRegister(t *testing.T) {
+	registered := observeRelease(t, func(p *connectPool) (any, func()) {
+		return p.Register()
+	})
+
+	acquired := observeRelease(t, func(p *connectPool) (any, func()) {
+		c, release, err := p.AcquireConnector()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Misusing the Connector's own state changes is harmless
+		c.Release()
+		c.Close()
+		if c.TryAcquire() || c.AcquireFor(time.Minute) {
+			t.Fatal("acquired a Connector handed out by AcquireConnector")
+		}
+
+		return c.Conn(), release
+	})
+
+	if !reflect.DeepEqual(acquired, registered) {
+		t.Fatalf("AcquireConnector's release observed %+v, Register's %+v", acquired, registered)
+	}
+
+	want := releaseObservation{
+		WorkingAfterRelease: 0,
+		Reused:              true,
+		WorkingAfterRepeat:  1,
+		Closed:              []any{int64(2), int64(1)},
+		Size:                0,
+		Events:              map[Event]int{EventCreate: 2, EventAcquire: 3, EventRelease: 3, EventEvict: 1, EventClose: 1},
+	}
+	if !reflect.DeepEqual(registered, want) {
+		t.Fatalf("Register's release observed %+v, want %+v", registered, want)
+	}
+}
@@ -0,0 +1,204 @@
+package connectpool
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/HuXin0817/ConnectPool/clock"
+)
+
+// Priority is the class an acquirer waits in when priority queues are enabled.
+type Priority int
+
+const (
+	Low  Priority = iota // Batch traffic, the default class
+	High                 // Interactive traffic
+)
+
+// RegisterOption configures a single Register call.
+type RegisterOption func(*registerConfig)
+
+type registerConfig struct {
+	priority       Priority      // Class the caller waits in
+	resetOnClose   bool          // Whether AcquiredConn.Close resets the connection before releasing it
+	workingTimeout time.Duration // Time after which the clear pass evicts the connection if still held, 0 for none
+}
+
+// WithPriority declares the class a Register call waits in when the pool was built WithPriorityQueues. A value other
+// than Low and High is clamped to the nearest of the two.
+func WithPriority(priority Priority) RegisterOption {
+	return func(config *registerConfig) {
+		config.priority = min(max(priority, Low), High)
+	}
+}
+
+// WithWorkingTimeout has the pool's clear pass evict the connection handed out by Register or Acquire if it is still
+// held after workingTimeout; it is then closed when released. Unlike RegisterWithTimeLimit no goroutine is started,
+// so the timeout is only enforced with the granularity of the autoClear interval.
+func WithWorkingTimeout(workingTimeout time.Duration) RegisterOption {
+	return func(config *registerConfig) {
+		config.workingTimeout = workingTimeout
+	}
+}
+
+// startWorking marks c as working for a caller configured by config.
+func (config registerConfig) startWorking(c connector) {
+	c.StartWorking()
+
+	if config.workingTimeout > 0 {
+		c.SetWorkingTimeout(config.workingTimeout)
+	}
+}
+
+// newRegisterConfig applies options over the defaults.
+func newRegisterConfig(options []RegisterOption) (config registerConfig) {
+	for _, op := range options {
+		op(&config)
+	}
+
+	return
+}
+
+// ClassStats summarizes the waits of one priority class.
+type ClassStats struct {
+	Waiting   int           // Number of callers currently queued
+	Acquired  uint64        // Number of connectors handed to the class
+	TotalWait time.Duration // Summed time callers waited for a connector
+	MaxWait   time.Duration // Longest time a caller waited for a connector
+}
+
+// waiter is a caller queued for a connector.
+type waiter struct {
+	priority Priority  // Class of the waiter
+	since    time.Time // Time the waiter was queued
+}
+
+// priorityScheduler decides which class receives the next connector while callers wait. When both classes have
+// waiters, the high class gets at least highShare of the hand-offs; otherwise the whole share flows to the class
+// that is waiting. Within a class, waiters are served in arrival order.
+type priorityScheduler struct {
+	mutex      sync.Mutex
+	highShare  float64       // Minimum fraction of contended hand-offs given to the high class
+	queues     [2][]*waiter  // FIFO queues of waiters per class
+	contended  [2]uint64     // Hand-offs per class made while both classes were waiting
+	classStats [2]ClassStats // Wait metrics per class
+	turn       broadcast     // Signaled whenever the heads of the queues change
+	clock      clock.Clock   // Times the waits
+}
+
+func newPriorityScheduler(highShare float64) *priorityScheduler {
+	return &priorityScheduler{highShare: min(max(highShare, 0), 1), clock: clock.Real()}
+}
+
+// acquire waits until it is priority's turn and try yields a connector, or until ctx is done. Between attempts it
+// blocks until the queues' heads change or available is signaled.
+func (s *priorityScheduler) acquire(ctx context.Context, priority Priority, try func() (connector, error), available *broadcast) (connector, error) {
+	w := &waiter{priority: priority, since: s.clock.Now()}
+
+	s.mutex.Lock()
+	s.queues[priority] = append(s.queues[priority], w)
+	s.mutex.Unlock()
+	s.turn.signal() // The new waiter may change the class entitled to the next connector
+
+	for {
+		// Takes the signals before checking, so a change in between is never missed
+		turn, freed := s.turn.wait(), available.wait()
+
+		if s.isTurn(w) {
+			c, err := try()
+			if err != nil {
+				s.leave(w)
+				return nil, err
+			}
+
+			if c != nil {
+				s.served(w)
+				return c, nil
+			}
+		}
+
+		select {
+		case <-turn:
+		case <-freed:
+		case <-ctx.Done():
+			s.leave(w)
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// leave removes w, which gave up waiting, from its queue.
+func (s *priorityScheduler) leave(w *waiter) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	defer s.turn.signal()
+
+	queue := s.queues[w.priority]
+	for i, v := range queue {
+		if v == w {
+			s.queues[w.priority] = append(queue[:i:i], queue[i+1:]...)
+			return
+		}
+	}
+}
+
+// isTurn reports whether w is at the head of the class entitled to the next connector.
+func (s *priorityScheduler) isTurn(w *waiter) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	queue := s.queues[s.nextClass()]
+	return len(queue) > 0 && queue[0] == w
+}
+
+// nextClass returns the class entitled to the next connector. It must be called with the mutex held.
+func (s *priorityScheduler) nextClass() Priority {
+	switch {
+	case len(s.queues[High]) == 0:
+		return Low
+	case len(s.queues[Low]) == 0:
+		return High
+	}
+
+	// Both classes wait: the high class is served until it has its share of the contended hand-offs
+	total := s.contended[High] + s.contended[Low]
+	if float64(s.contended[High]) <= s.highShare*float64(total) {
+		return High
+	}
+
+	return Low
+}
+
+// served removes w, which received a connector, from its queue and records its wait.
+func (s *priorityScheduler) served(w *waiter) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	defer s.turn.signal()
+
+	if len(s.queues[High]) > 0 && len(s.queues[Low]) > 0 {
+		s.contended[w.priority]++
+	}
+
+	s.queues[w.priority] = s.queues[w.priority][1:]
+
+	wait := s.clock.Since(w.since)
+	stats := &s.classStats[w.priority]
+	stats.Acquired++
+	stats.TotalWait += wait
+	stats.MaxWait = max(stats.MaxWait, wait)
+}
+
+// stats returns the wait metrics of priority. It is safe to call on a nil scheduler.
+func (s *priorityScheduler) stats(priority Priority) ClassStats {
+	if s == nil {
+		return ClassStats{}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stats := s.classStats[priority]
+	stats.Waiting = len(s.queues[priority])
+	return stats
+}
@@ -0,0 +1,62 @@
+package connectpool
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPriority_BoundedInteractiveWait(t *testing.T) {
+	p := newTestPool(t, counter(), WithCap(4), WithPriorityQueues(0.8))
+
+	// Batch load keeps every connector busy and more callers queued than the pool holds
+	stop := make(chan struct{})
+	var batch sync.WaitGroup
+	for range 16 {
+		batch.Add(1)
+		go func() {
+			defer batch.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+
+				_, cancel := p.Register(WithPriority(Low))
+				time.Sleep(time.Millisecond)
+				cancel()
+			}
+		}()
+	}
+	defer func() {
+		close(stop)
+		batch.Wait()
+	}()
+
+	waits := make([]time.Duration, 0, 100)
+	for range cap(waits) {
+		start := time.Now()
+		_, cancel := p.Register(WithPriority(High))
+		waits = append(waits, time.Since(start))
+		cancel()
+	}
+
+	slices.Sort(waits)
+	if p99 := waits[len(waits)*99/100]; p99 > 100*time.Millisecond {
+		t.Fatalf("interactive p99 wait %v under saturating batch load", p99)
+	}
+}
+
+func TestPriority_InvalidClamped(t *testing.T) {
+	p := newTestPool(t, counter(), WithCap(1), WithPriorityQueues(0.5))
+
+	for _, priority := range []Priority{-1, 7} {
+		if _, cancel := p.Register(WithPriority(priority)); cancel == nil {
+			t.Fatalf("Register with priority %d failed", priority)
+		} else {
+			cancel()
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package connectpool
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const maxWarmupFailures = 10 // Failed creations after which a warm-up ramp gives up and the pool is ready anyway
+
+// rampUp creates connectors one at a time until the pool holds min(MinSize, Cap) of them, spreading the creations
+// evenly over the WithWarmupRamp duration. It gives up after maxWarmupFailures failures, and cancels the creation
+// in progress when the pool is closed.
+func (p *connectPool) rampUp() {
+	defer p.warmupDone.Store(true)
+
+	target := min(p.MinSize(), p.Cap())
+	p.warmupTarget.Store(int64(target))
+	if target <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		select {
+		case <-p.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	interval := p.warmupRamp / time.Duration(target)
+	for failures := 0; ; {
+		if !p.warmLimiter.acquire(ctx) {
+			return
+		}
+
+		err := p.warmConnector()
+		p.warmLimiter.release()
+
+		switch {
+		case err == nil:
+			if p.warmupWarmed.Add(1) >= int64(target) {
+				return
+			}
+		case errors.Is(err, ErrPoolClosed) || errors.Is(err, ErrBudgetExhausted):
+			return
+		default:
+			if failures++; failures >= maxWarmupFailures {
+				return
+			}
+		}
+
+		if !p.sleep(max(interval, 1), ctx.Done()) {
+			return
+		}
+	}
+}
+
+// Ready reports whether the WithWarmupRamp warm-up has reached its target or given up after repeated failures. A
+// pool without a warm-up ramp is always ready.
+func (p *connectPool) Ready() bool {
+	return p.warmupRamp <= 0 || p.warmupDone.Load()
+}
@@ -0,0 +1,107 @@
+package connectpool
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HuXin0817/ConnectPool/clock"
+)
+
+// rampDialer returns a connectMethod recording the fake time of each call, and a function listing them.
+func rampDialer(fake *clock.Fake, connectMethod func() any) (func() any, func() []time.Duration) {
+	var (
+		mutex sync.Mutex
+		dials []time.Duration
+	)
+
+	dial := func() any {
+		mutex.Lock()
+		dials = append(dials, fake.Since(time.Unix(0, 0)))
+		mutex.Unlock()
+
+		return connectMethod()
+	}
+
+	return dial, func() []time.Duration {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		return append([]time.Duration(nil), dials...)
+	}
+}
+
+func TestWarmupRamp_Schedule(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	dial, dials := rampDialer(fake, counter())
+	p := newTestPool(t, dial, WithClock(fake), WithTestabilityMode(), WithMinSize(4), WithWarmupRamp(4*time.Minute))
+
+	// The 4 dials are spread a minute apart over the 4 minute ramp, and the pool is ready after the last one
+	for warmed := 1; warmed < 4; warmed++ {
+		waitUntil(t, "the ramp sleeps", func() bool { return fake.Pending() == 1 })
+		if stats := p.Stats(); stats.WarmupWarmed != int64(warmed) || stats.WarmupTarget != 4 || p.Ready() {
+			t.Fatalf("warmed %d of %d, ready %v, want %d of 4 and not ready",
+				stats.WarmupWarmed, stats.WarmupTarget, p.Ready(), warmed)
+		}
+
+		fake.Advance(time.Minute - time.Second)
+		if got := len(dials()); got != warmed {
+			t.Fatalf("%d dials before the interval elapsed, want %d", got, warmed)
+		}
+
+		fake.Advance(time.Second)
+	}
+
+	waitUntil(t, "the pool is ready", p.Ready)
+
+	want := []time.Duration{0, time.Minute, 2 * time.Minute, 3 * time.Minute}
+	if got := dials(); !slices.Equal(got, want) {
+		t.Fatalf("dialed at %v, want %v", got, want)
+	}
+
+	if warmed := p.Stats().WarmupWarmed; warmed != 4 || p.Size() != 4 || fake.Pending() != 0 {
+		t.Fatalf("warmed %d, size %d, %d timers pending after the ramp, want 4, 4 and 0", warmed, p.Size(), fake.Pending())
+	}
+}
+
+func TestWarmupRamp_GivesUpAfterFailures(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	dial, dials := rampDialer(fake, func() any { panic("backend down") })
+	p := newTestPool(t, dial, WithClock(fake), WithTestabilityMode(), WithMinSize(2), WithWarmupRamp(10*time.Minute),
+		WithDealPanicMethod(func(any) {}))
+
+	// Each failure waits out the 5 minute interval, and the pool is ready anyway after maxWarmupFailures of them
+	for failures := 1; failures < maxWarmupFailures; failures++ {
+		waitUntil(t, "the ramp sleeps", func() bool { return fake.Pending() == 1 })
+		if got := len(dials()); got != failures || p.Ready() {
+			t.Fatalf("%d dials, ready %v, want %d and not ready", got, p.Ready(), failures)
+		}
+
+		fake.Advance(5 * time.Minute)
+	}
+
+	waitUntil(t, "the pool is ready", p.Ready)
+
+	if got, warmed := len(dials()), p.Stats().WarmupWarmed; got != maxWarmupFailures || warmed != 0 {
+		t.Fatalf("%d dials warmed %d connectors, want %d and 0", got, warmed, maxWarmupFailures)
+	}
+}
+
+func TestWarmupRamp_CloseCancels(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	dial, dials := rampDialer(fake, counter())
+	p := newTestPool(t, dial, WithClock(fake), WithTestabilityMode(), WithMinSize(4), WithWarmupRamp(4*time.Minute))
+
+	waitUntil(t, "the ramp sleeps", func() bool { return fake.Pending() == 1 })
+	p.Close()
+
+	// The ramp stops sleeping and gives up, so no later interval dials
+	waitUntil(t, "the ramp ends", p.Ready)
+	waitUntil(t, "the ramp's timer is stopped", func() bool { return fake.Pending() == 0 })
+
+	fake.Advance(time.Hour)
+	if got := len(dials()); got != 1 {
+		t.Fatalf("%d dials after Close during the ramp, want 1", got)
+	}
+}
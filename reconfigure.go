@@ -0,0 +1,154 @@
+package connectpool
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+	"time"
+)
+
+// runtimeConfig is the part of the configuration that can change while the pool runs. It is immutable and replaced
+// as a whole, so an operation loading it once sees a consistent configuration.
+type runtimeConfig struct {
+	maxSize           int           // Maximum number of connections
+	minSize           int           // Number of connectors Warm fills the pool to
+	maxFreeTime       time.Duration // Idle time after which a connector is cleared
+	autoClearInterval time.Duration // Interval between two automatic clear passes
+}
+
+// configBundle holds the current runtimeConfig, replaced copy-on-write like callbackBundle.
+type configBundle struct {
+	current atomic.Pointer[runtimeConfig] // Never nil once the pool is created
+}
+
+// Load returns the current configuration, the defaults if none was stored.
+func (b *configBundle) Load() *runtimeConfig {
+	if b != nil {
+		if current := b.current.Load(); current != nil {
+			return current
+		}
+	}
+
+	return &runtimeConfig{maxSize: defaultCap, minSize: defaultMinSize, maxFreeTime: defaultMaxFreeTime, autoClearInterval: defaultAutoCleanInterval}
+}
+
+// update replaces the current configuration with a copy changed by change, retrying if it was replaced meanwhile.
+// It returns the configuration it replaced and the one it stored.
+func (b *configBundle) update(change func(next *runtimeConfig)) (previous, next runtimeConfig) {
+	for {
+		current := b.current.Load()
+
+		previous = *b.Load()
+		next = previous
+		change(&next)
+
+		if b.current.CompareAndSwap(current, &next) {
+			return previous, next
+		}
+	}
+}
+
+// validate checks the configuration as a whole, returning one error per broken rule joined together.
+func (c runtimeConfig) validate() error {
+	var errs []error
+
+	if c.maxSize <= 0 {
+		errs = append(errs, fmt.Errorf("cap %d is not positive", c.maxSize))
+	}
+
+	if c.minSize < 0 || c.minSize > c.maxSize {
+		errs = append(errs, fmt.Errorf("min size %d is outside [0, cap %d]", c.minSize, c.maxSize))
+	}
+
+	if c.maxFreeTime <= 0 {
+		errs = append(errs, fmt.Errorf("maxFreeTime %v is not positive", c.maxFreeTime))
+	}
+
+	if c.autoClearInterval <= 0 {
+		errs = append(errs, fmt.Errorf("autoClearInterval %v is not positive", c.autoClearInterval))
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %w", ErrInvalidConfig, errors.Join(errs...))
+}
+
+// Reconfigure applies opts together: it computes the configuration they result in and validates it as a whole before
+// changing anything, so the pool never runs with a combination such as a cap below the min size. Only WithCap,
+// WithMinSize, WithMaxFreeTime and WithAutoClearInterval can be applied to a running pool; any other option is
+// rejected with ErrNotReconfigurable. A lowered cap shrinks the pool, a lowered maxFreeTime runs a clear pass at once
+// and a raised min size warms the pool. CloneWith still starts from the options the pool was created with.
+func (p *connectPool) Reconfigure(opts ...Option) error {
+	p.reconfigure.Lock()
+	defer p.reconfigure.Unlock()
+
+	// Swaps the whole configuration at once, so no operation sees part of it applied
+	var current, next runtimeConfig
+	for {
+		loaded := p.config.current.Load()
+		current = *p.config.Load()
+
+		var err error
+		if next, err = current.with(opts); err != nil {
+			return err
+		}
+
+		if err = next.validate(); err != nil {
+			return err
+		}
+
+		if p.config.current.CompareAndSwap(loaded, &next) {
+			break
+		}
+	}
+
+	if next.autoClearInterval != current.autoClearInterval {
+		p.pool.RearmAutoClear()
+	}
+
+	if next.maxSize < current.maxSize {
+		p.forceEvictDownTo(next.maxSize) // Shrinks the pool to the lowered cap
+	}
+
+	p.trackCap()
+	p.warmToMinSize()
+
+	if next.maxFreeTime < current.maxFreeTime {
+		p.ClearNow()
+	}
+
+	p.idleSignal.signal() // Wakes the waiters to look at the new configuration
+	return nil
+}
+
+// with returns the configuration opts result in from c. It applies them to a scratch pool holding only c, and rejects
+// opts if they set anything else.
+func (c runtimeConfig) with(opts []Option) (next runtimeConfig, err error) {
+	scratch := &connectPool{}
+	scratch.config.current.Store(&c)
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: an option panicked on the running pool: %v", ErrNotReconfigurable, r)
+		}
+	}()
+
+	for _, op := range opts {
+		op(scratch)
+	}
+
+	next = *scratch.config.current.Swap(nil)
+
+	// With the runtime settings cleared, any field still set was changed by an option
+	fields := reflect.ValueOf(scratch).Elem()
+	for i := range fields.NumField() {
+		if !fields.Field(i).IsZero() {
+			return runtimeConfig{}, fmt.Errorf("%w: an option sets %s", ErrNotReconfigurable, fields.Type().Field(i).Name)
+		}
+	}
+
+	return next, nil
+}
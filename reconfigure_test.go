@@ -0,0 +1,92 @@
+package connectpool
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// runtimeConfigOf returns the settings of p that Reconfigure can change.
+func runtimeConfigOf(p *connectPool) runtimeConfig {
+	return *p.config.Load()
+}
+
+func TestReconfigure_InvalidChangesNothing(t *testing.T) {
+	p := newTestPool(t, counter(), WithCap(10), WithMaxFreeTime(time.Hour), WithAutoClearInterval(time.Minute))
+	before := runtimeConfigOf(p)
+
+	// Each option is valid on its own, but the min size would exceed the lowered cap
+	err := p.Reconfigure(WithCap(2), WithMinSize(5), WithMaxFreeTime(time.Second), WithAutoClearInterval(time.Second))
+	if !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("Reconfigure error = %v, want ErrInvalidConfig", err)
+	}
+
+	if after := runtimeConfigOf(p); after != before {
+		t.Fatalf("configuration changed to %+v by an invalid combination, was %+v", after, before)
+	}
+
+	// An option a running pool cannot apply is rejected along with the valid ones
+	if err = p.Reconfigure(WithCap(5), WithName("renamed")); !errors.Is(err, ErrNotReconfigurable) {
+		t.Fatalf("Reconfigure error = %v, want ErrNotReconfigurable", err)
+	}
+
+	if after := runtimeConfigOf(p); after != before || p.Name() == "renamed" {
+		t.Fatalf("configuration changed to %+v by a rejected option, was %+v", after, before)
+	}
+}
+
+func TestReconfigure_ValidAppliesEverything(t *testing.T) {
+	p := newTestPool(t, counter(), WithCap(10), WithMaxFreeTime(time.Hour), WithAutoClearInterval(time.Minute))
+
+	want := runtimeConfig{maxSize: 3, minSize: 2, maxFreeTime: time.Minute, autoClearInterval: time.Second}
+	if err := p.Reconfigure(WithCap(want.maxSize), WithMinSize(want.minSize), WithMaxFreeTime(want.maxFreeTime), WithAutoClearInterval(want.autoClearInterval)); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := runtimeConfigOf(p); got != want {
+		t.Fatalf("configuration %+v, want %+v", got, want)
+	}
+
+	// The raised min size warms the pool
+	waitUntil(t, "the pool was warmed to the new min size", func() bool { return p.Size() == want.minSize })
+}
+
+func TestReconfigure_NeverHalfApplied(t *testing.T) {
+	p := newTestPool(t, counter(), WithTestabilityMode(), WithCap(10), WithMinSize(8))
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		// Alternates between two valid configurations, each invalid combined with a setting of the other
+		for i := 0; i < 200; i++ {
+			cap, minSize := 4, 2
+			if i%2 == 1 {
+				cap, minSize = 10, 8
+			}
+
+			if err := p.Reconfigure(WithCap(cap), WithMinSize(minSize)); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+		close(done)
+	}()
+
+	for {
+		select {
+		case <-done:
+			wg.Wait()
+			return
+		default:
+		}
+
+		if config := p.ExportConfig(); config.MinSize > config.MaxSize {
+			t.Fatalf("min size %d above cap %d, a configuration half applied", config.MinSize, config.MaxSize)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package connectpool
+
+import "sync"
+
+// connectorShells recycles the connectors evicted by the clear passes of pool sets, so that churn does not allocate a
+// connector per creation. Each reuse starts a new generation of the shell, letting stale handles detect it.
+var connectorShells = sync.Pool{
+	New: func() any { return new(atomicConnector) },
+}
+
+// recycle clears c and puts it back into connectorShells with its generation advanced. The caller must hold the only
+// reference the pool still has to c: it was removed from its set, claimed so it could not be handed out, and closed,
+// and it is recyclable, no holder keeping a reference to it.
+func (c *atomicConnector) recycle() {
+	c.generation.Add(1) // Stale handles compare generations before touching the shell
+
+	c.connect = nil
+	c.err = nil
+	c.callbacks = nil
+	c.onStop = nil
+	c.clock = nil
+	c.used.Store(false)
+	c.isWorking.Store(false)
+	c.permanentlyWorking.Store(false)
+	c.invalidation.Store(0)
+	c.discarded.Store(false)
+	c.memorySize.Store(0)
+	c.cold.Store(false)
+	c.label.Store("")
+	c.id.Store(0)
+	c.healthCheckPass.Store(0)
+	c.session.Store(nil)
+	c.keepShell.Store(false)
+	c.expiredWork.Store(0)
+	c.releaseCause.Store(0)
+	c.workingDeadline.Store(0)
+	c.retired.Store(false)
+
+	connectorShells.Put(c)
+}
@@ -0,0 +1,71 @@
+package connectpool
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// ReleaseCause tells why a connector handed out stopped working.
+type ReleaseCause int32
+
+const (
+	ReleaseCallerCancelled ReleaseCause = iota + 1 // The holder called its cancelFunc
+	ReleaseDeadlineExpired                         // The deadline of RegisterWithTimeLimit or AcquireWithTimeout fired first
+	releaseByPool                                  // The pool gave back a connector it claimed itself, not counted as a release
+)
+
+var releaseCauseNames = [...]string{
+	ReleaseCallerCancelled: "callerCancelled",
+	ReleaseDeadlineExpired: "deadlineExpired",
+}
+
+func (c ReleaseCause) String() string {
+	if c <= 0 || int(c) >= len(releaseCauseNames) {
+		return "unknown"
+	}
+
+	return releaseCauseNames[c]
+}
+
+// releaseCounts counts the releases of hand-outs by cause.
+type releaseCounts struct {
+	callerCancelled atomic.Uint64 // Hand-outs released by their holder
+	deadlineExpired atomic.Uint64 // Hand-outs released by their deadline
+}
+
+// connectorStopped is invoked whenever a connector stops working. Releases by a deadline are reported here, as no
+// holder is involved; releases by a holder are reported by its cancelFunc. In diagnostics mode, every hold is checked
+// for an anomalous duration.
+func (p *connectPool) connectorStopped(c connector, cause ReleaseCause, hold time.Duration) {
+	if cause == ReleaseDeadlineExpired {
+		p.reportRelease(c, cause)
+	}
+
+	if cause != releaseByPool {
+		p.diagnostics.observeHold(hold)
+	}
+
+	p.idleSignal.signal()
+}
+
+// reportRelease counts the release of c for cause and passes it to the WithOnRelease hook.
+func (p *connectPool) reportRelease(c connector, cause ReleaseCause) {
+	switch cause {
+	case ReleaseCallerCancelled:
+		p.releases.callerCancelled.Add(1)
+	case ReleaseDeadlineExpired:
+		p.releases.deadlineExpired.Add(1)
+	}
+
+	if p.onRelease == nil {
+		return
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			p.handlePanic(r)
+		}
+	}()
+
+	p.onRelease(c.GetConnect(), cause)
+}
@@ -0,0 +1,65 @@
+package connectpool
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HuXin0817/ConnectPool/clock"
+)
+
+func TestReleaseCause_BothPaths(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+
+	var (
+		mutex  sync.Mutex
+		causes []ReleaseCause
+	)
+	p := newTestPool(t, counter(), WithClock(fake), WithTestabilityMode(), WithOnRelease(func(_ any, cause ReleaseCause) {
+		mutex.Lock()
+		causes = append(causes, cause)
+		mutex.Unlock()
+	}))
+
+	// check asserts the counters, the causes passed to OnRelease and the cause recorded on connect's connector
+	check := func(path string, connect any, cancelled, expired uint64, want ...ReleaseCause) {
+		t.Helper()
+
+		if stats := p.Stats(); stats.ReleasesCallerCancelled != cancelled || stats.ReleasesDeadlineExpired != expired {
+			t.Fatalf("after %s, %d cancelled and %d expired releases counted, want %d and %d",
+				path, stats.ReleasesCallerCancelled, stats.ReleasesDeadlineExpired, cancelled, expired)
+		}
+
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if !slices.Equal(causes, want) {
+			t.Fatalf("after %s, OnRelease got %v, want %v", path, causes, want)
+		}
+		if cause, released := p.findConnector(connect).ReleaseCause(); !released || cause != want[len(want)-1] {
+			t.Fatalf("after %s, connector released %v by %v, want by %v", path, released, cause, want[len(want)-1])
+		}
+	}
+
+	// The holder cancels before the deadline
+	connect, cancel := p.RegisterWithTimeLimit(time.Minute)
+	fake.Advance(time.Minute - time.Second)
+	cancel()
+	waitUntil(t, "the deadline's timer was stopped", func() bool { return fake.Pending() == 0 })
+	check("a cancel", connect, 1, 0, ReleaseCallerCancelled)
+
+	// The deadline fires first, and the holder's late cancel counts nothing more
+	connect, cancel = p.RegisterWithTimeLimit(time.Minute)
+	fake.Advance(time.Minute)
+	waitUntil(t, "the deadline released the connection", func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		return len(causes) == 2
+	})
+	check("the deadline", connect, 1, 1, ReleaseCallerCancelled, ReleaseDeadlineExpired)
+
+	cancel()
+	check("a late cancel", connect, 1, 1, ReleaseCallerCancelled, ReleaseDeadlineExpired)
+}
@@ -0,0 +1,51 @@
+package connectpool
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const (
+	retryBackoff    = 10 * time.Millisecond // Wait before the first retry of DoWithRetry
+	maxRetryBackoff = time.Second           // Upper bound of the doubling wait between retries
+)
+
+// DoWithRetry runs fn with a connection, acquiring a fresh one and running fn again, up to maxRetries times, when
+// the pool reclaimed the connection while fn held it or fn reported it broken by returning an error wrapping
+// ErrBadConn. A broken connection is closed rather than reused, so each retry gets another one. Retries back off
+// exponentially and stop when ctx is done. Any other error of fn is returned as is.
+func (p *connectPool) DoWithRetry(ctx context.Context, fn func(connect any) error, maxRetries int) (err error) {
+	backoff := retryBackoff
+
+	for attempt := 0; ; attempt++ {
+		var conn AcquiredConn
+		if conn, err = p.Acquire(ctx); err != nil {
+			return err
+		}
+
+		err = fn(conn.Conn())
+
+		badConn := errors.Is(err, ErrBadConn)
+		if badConn {
+			conn.(*acquiredConn).connector.Discard() // Never hand the broken connection out again
+		}
+
+		invalidated := conn.Invalidated()
+		_ = conn.Close()
+
+		if (!badConn && !invalidated) || attempt >= maxRetries {
+			return err
+		}
+
+		timer := p.clock.NewTimer(backoff)
+		select {
+		case <-timer.C():
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		}
+
+		backoff = min(2*backoff, maxRetryBackoff)
+	}
+}
@@ -0,0 +1,61 @@
+package connectpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestDoWithRetry_FreshConnection(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		fail func(p *connectPool) error // Makes the first attempt fail
+	}{
+		{"Reclaimed", func(p *connectPool) error {
+			p.ForceEvictAll()
+			return nil
+		}},
+		{"BadConn", func(*connectPool) error { return fmt.Errorf("broken pipe: %w", ErrBadConn) }},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var closed []any
+			p := newTestPool(t, counter(), WithTestabilityMode(),
+				WithCloseMethod(func(connect any) { closed = append(closed, connect) }))
+
+			var attempts []any
+			err := p.DoWithRetry(context.Background(), func(connect any) error {
+				attempts = append(attempts, connect)
+				if len(attempts) == 1 {
+					return tt.fail(p)
+				}
+				return nil
+			}, 3)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !slices.Equal(attempts, []any{int64(1), int64(2)}) {
+				t.Fatalf("fn ran with connections %v, want [1 2]", attempts)
+			}
+			if !slices.Equal(closed, []any{int64(1)}) {
+				t.Fatalf("closed connections %v, want the failed one, [1]", closed)
+			}
+		})
+	}
+}
+
+func TestDoWithRetry_GivesUp(t *testing.T) {
+	p := newTestPool(t, counter(), WithTestabilityMode())
+
+	var attempts int
+	err := p.DoWithRetry(context.Background(), func(any) error {
+		attempts++
+		return ErrBadConn
+	}, 2)
+
+	if !errors.Is(err, ErrBadConn) || attempts != 3 {
+		t.Fatalf("DoWithRetry returned %v after %d attempts, want %v after 3", err, attempts, ErrBadConn)
+	}
+}
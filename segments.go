@@ -0,0 +1,14 @@
+package connectpool
+
+// demoteIdle periodically moves the connectors idle for longer than demoteAfter to the cold segment
+// until the pool is closed.
+func (p *connectPool) demoteIdle() {
+	interval := p.demoteInterval
+	if interval <= 0 {
+		interval = p.demoteAfter
+	}
+
+	for p.sleep(interval, nil) {
+		p.pool.Demote(p.demoteAfter)
+	}
+}
@@ -0,0 +1,70 @@
+package connectpool
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// sharedCall is a DoShared call in flight, whose result is handed to every caller of the same key.
+type sharedCall struct {
+	done   chan struct{} // Closed once result and err are set
+	result any           // Value returned by fn
+	err    error         // Error returned by fn, or the failure to acquire a connection
+}
+
+// sharedCalls tracks the DoShared calls in flight by key.
+type sharedCalls struct {
+	mutex sync.Mutex
+	calls map[string]*sharedCall // Calls in flight, removed as soon as they complete
+}
+
+// DoShared runs fn with a connection, sharing the run with every concurrent caller of the same key: only the first
+// caller acquires a connection and runs fn, the others wait for its result. The result is not kept once the call
+// completes. A panic in fn is handled by the panic handler and returned to every caller as an error.
+func (p *connectPool) DoShared(key string, fn func(conn any) (any, error)) (any, error) {
+	p.shared.mutex.Lock()
+	if call, ok := p.shared.calls[key]; ok {
+		p.shared.mutex.Unlock()
+
+		<-call.done
+		return call.result, call.err
+	}
+
+	call := &sharedCall{done: make(chan struct{})}
+	if p.shared.calls == nil {
+		p.shared.calls = make(map[string]*sharedCall)
+	}
+	p.shared.calls[key] = call
+	p.shared.mutex.Unlock()
+
+	defer func() {
+		p.shared.mutex.Lock()
+		delete(p.shared.calls, key)
+		p.shared.mutex.Unlock()
+
+		close(call.done)
+	}()
+
+	call.result, call.err = p.runShared(fn)
+	return call.result, call.err
+}
+
+// runShared runs fn with a connection acquired once, recovering a panic of fn as an error.
+func (p *connectPool) runShared(fn func(conn any) (any, error)) (result any, err error) {
+	conn, err := p.Acquire(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, fmt.Errorf("%w: %v", ErrSharedPanicked, r)
+
+			p.handlePanic(r)
+		}
+	}()
+
+	return fn(conn.Conn())
+}
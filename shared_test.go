@@ -0,0 +1,86 @@
+package connectpool
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// doSharedConcurrently calls DoShared with key and fn from n goroutines at once, returning their results and errors.
+// fn should wait for started to reach n so that every caller joins the same call.
+func doSharedConcurrently(p *connectPool, n int, started *atomic.Int64, fn func(conn any) (any, error)) ([]any, []error) {
+	results, errs := make([]any, n), make([]error, n)
+
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			started.Add(1)
+			results[i], errs[i] = p.DoShared("key", fn)
+		}()
+	}
+	wg.Wait()
+
+	return results, errs
+}
+
+// awaitCallers blocks until started reaches n, then gives the callers the time to join the call in flight.
+func awaitCallers(started *atomic.Int64, n int64) {
+	for started.Load() < n {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+}
+
+func TestDoShared_OneExecution(t *testing.T) {
+	const callers = 20
+	p := newTestPool(t, counter())
+
+	var started, executions atomic.Int64
+	results, errs := doSharedConcurrently(p, callers, &started, func(conn any) (any, error) {
+		executions.Add(1)
+		awaitCallers(&started, callers)
+		return conn, nil
+	})
+
+	for i := range callers {
+		if errs[i] != nil || results[i] != int64(1) {
+			t.Fatalf("caller %d got %v, %v, want the shared result, 1", i, results[i], errs[i])
+		}
+	}
+	if n, borrowed := executions.Load(), p.BorrowCount(); n != 1 || borrowed != 1 {
+		t.Fatalf("%d executions and %d acquisitions for %d callers, want 1 each", n, borrowed, callers)
+	}
+
+	// The result is not kept once the call completed
+	if _, err := p.DoShared("key", func(any) (any, error) { executions.Add(1); return nil, nil }); err != nil {
+		t.Fatal(err)
+	}
+	if n := executions.Load(); n != 2 {
+		t.Fatalf("%d executions after a later call, want 2", n)
+	}
+}
+
+func TestDoShared_PanicReachesEveryCaller(t *testing.T) {
+	const callers = 5
+	p := newTestPool(t, counter(), WithDealPanicMethod(func(any) {}))
+
+	var started atomic.Int64
+	_, errs := doSharedConcurrently(p, callers, &started, func(any) (any, error) {
+		awaitCallers(&started, callers)
+		panic("query")
+	})
+
+	for i, err := range errs {
+		if !errors.Is(err, ErrSharedPanicked) {
+			t.Fatalf("caller %d got error %v, want %v", i, err, ErrSharedPanicked)
+		}
+	}
+	if working := p.WorkingNumber(); working != 0 {
+		t.Fatalf("WorkingNumber = %d after the panic, want the connection released", working)
+	}
+}
@@ -0,0 +1,97 @@
+package connectpool
+
+import (
+	"sync"
+	"time"
+)
+
+// ShrinkTo lowers the cap to n at once, so no connector is created above it, and lets the idle policies shrink the
+// pool naturally. If the pool still holds more than n connectors when deadline passes, the longest-idle and then the
+// oldest connectors are force-evicted. The returned channel receives the number of force-evicted connectors once
+// the pool is at or below n. A negative n, or one not below the cap, changes nothing and the channel receives 0 at once.
+func (p *connectPool) ShrinkTo(n int, deadline time.Duration) <-chan int {
+	forced := make(chan int, 1)
+
+	lowered := false
+	if n >= 0 {
+		p.config.update(func(next *runtimeConfig) {
+			if lowered = n < next.maxSize; lowered {
+				next.maxSize = n
+			}
+		})
+	}
+
+	if !lowered {
+		forced <- 0
+		return forced
+	}
+
+	p.shrinkTarget.Store(int64(n))
+
+	go func() {
+		defer p.shrinkTarget.CompareAndSwap(int64(n), -1)
+
+		timer := p.clock.NewTimer(deadline)
+		defer timer.Stop()
+
+		sizes := make(chan int, 1)
+		stopWatching := p.WatchSize(sizes)
+		defer stopWatching()
+
+		for p.Size() > n {
+			select {
+			case <-sizes:
+			case <-timer.C():
+				forced <- p.forceEvictDownTo(n)
+				return
+
+			case <-p.done:
+				forced <- 0
+				return
+			}
+		}
+
+		forced <- 0
+	}()
+
+	return forced
+}
+
+// forceEvictDownTo force-evicts the longest-idle and then the oldest connectors above n, returning how many.
+func (p *connectPool) forceEvictDownTo(n int) int {
+	if p.Size() <= n {
+		return 0
+	}
+
+	idle, evicted := p.pool.ForceEvict(p.Size() - n)
+	p.closeInOrder(idle, CloseEvicted)
+	p.forceEvicted.Add(uint64(evicted))
+
+	for i := 0; i < evicted; i++ {
+		p.emit(EventEvict)
+	}
+
+	return evicted
+}
+
+// ScheduledShrink calls ShrinkTo(targetSize, MaxFreeTime()) once after has elapsed on the pool's clock, leaving the
+// idle policies one MaxFreeTime to shrink the pool before connectors are force-evicted. Calling the returned
+// CancelFunc before then cancels the shrink. Each pending ScheduledShrink fires independently.
+func (p *connectPool) ScheduledShrink(targetSize int, after time.Duration) CancelFunc {
+	cancelled := make(chan struct{})
+	var once sync.Once
+
+	go func() {
+		timer := p.clock.NewTimer(after)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C():
+			p.ShrinkTo(targetSize, p.MaxFreeTime())
+		case <-cancelled:
+		case <-p.done:
+		}
+	}()
+
+	return func() { once.Do(func() { close(cancelled) }) }
+}
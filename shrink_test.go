@@ -0,0 +1,91 @@
+package connectpool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/HuXin0817/ConnectPool/clock"
+)
+
+// forcedWithin returns what forced receives, failing if it receives nothing within a second.
+func forcedWithin(t *testing.T, forced <-chan int) int {
+	t.Helper()
+
+	select {
+	case n := <-forced:
+		return n
+	case <-time.After(time.Second):
+		t.Fatal("ShrinkTo did not report")
+		return 0
+	}
+}
+
+func TestShrinkTo_NaturalAttrition(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	p := newTestPool(t, counter(), WithClock(fake), WithTestabilityMode(), WithCap(4), WithMaxFreeTime(time.Minute))
+
+	_, cancel, err := p.RegisterN(context.Background(), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+
+	forced := p.ShrinkTo(2, time.Hour)
+	if p.Cap() != 2 || p.Stats().ShrinkTarget != 2 {
+		t.Fatalf("cap %d, shrink target %d during the shrink, want 2 and 2", p.Cap(), p.Stats().ShrinkTarget)
+	}
+
+	// The idle connectors expire well before the deadline
+	fake.Advance(2 * time.Minute)
+	p.ClearNow()
+
+	if n := forcedWithin(t, forced); n != 0 {
+		t.Fatalf("%d connectors force-evicted, want none", n)
+	}
+
+	waitUntil(t, "the shrink ended", func() bool { return p.Stats().ShrinkTarget == -1 })
+	if p.Size() > 2 || p.Stats().TotalForceEvicted != 0 {
+		t.Fatalf("size %d, %d force-evicted, want at most 2 and none", p.Size(), p.Stats().TotalForceEvicted)
+	}
+}
+
+func TestShrinkTo_DeadlineForcesEviction(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	p := newTestPool(t, counter(), WithClock(fake), WithTestabilityMode(), WithCap(4), WithMaxFreeTime(time.Minute))
+
+	// Connectors in use never expire, so only the deadline brings the pool down
+	_, cancel, err := p.RegisterN(context.Background(), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+
+	forced := p.ShrinkTo(2, time.Minute)
+	waitUntil(t, "the deadline was armed", func() bool { return fake.Pending() == 1 })
+
+	select {
+	case <-forced:
+		t.Fatal("ShrinkTo reported before its deadline")
+	default:
+	}
+
+	fake.Advance(time.Minute)
+	if n := forcedWithin(t, forced); n != 2 {
+		t.Fatalf("%d connectors force-evicted, want 2", n)
+	}
+
+	if p.Size() != 2 || p.Stats().TotalForceEvicted != 2 {
+		t.Fatalf("size %d, %d force-evicted, want 2 and 2", p.Size(), p.Stats().TotalForceEvicted)
+	}
+}
+
+func TestShrinkTo_IgnoresInvalidTargets(t *testing.T) {
+	p := newTestPool(t, counter(), WithTestabilityMode(), WithCap(4))
+
+	for _, n := range []int{-1, 4, 10} {
+		if forced := forcedWithin(t, p.ShrinkTo(n, 0)); forced != 0 || p.Cap() != 4 {
+			t.Fatalf("ShrinkTo(%d) force-evicted %d, cap %d, want 0 and 4", n, forced, p.Cap())
+		}
+	}
+}
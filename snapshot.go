@@ -0,0 +1,111 @@
+package connectpool
+
+import (
+	"sort"
+	"time"
+)
+
+// ConnectorSnapshot describes a single connector at the time of a Snapshot.
+type ConnectorSnapshot struct {
+	Token           uint64        // Token the connector is stored under
+	Connect         any           // Connection variable
+	Working         bool          // Whether the connector is in use
+	Pinned          bool          // Whether the connector is pinned by BorrowForever
+	CreatedAt       time.Time     // Time the connector was created
+	LastWorkingTime time.Time     // Time the connector last worked
+	IdleFor         time.Duration // Time since the connector last worked, 0 while working
+	HoldDuration    time.Duration // Time since the connector started working, 0 while idle
+	MemorySize      int64         // Memory held by the connection, as measured by WithConnSize
+	Cold            bool          // Whether the connector was demoted to the cold idle segment
+	Label           string        // Label of the connection's backend, as returned by WithConnLabeler
+}
+
+// snapshotConnector describes c stored under token.
+func snapshotConnector(token uint64, c connector) ConnectorSnapshot {
+	return ConnectorSnapshot{
+		Token:           token,
+		Connect:         c.GetConnect(),
+		Working:         !c.IsFree(),
+		Pinned:          c.IsPermanentlyWorking(),
+		CreatedAt:       c.CreatedAt(),
+		LastWorkingTime: c.LastWorkingTime(),
+		IdleFor:         c.SinceLastWorkingTime(),
+		HoldDuration:    c.HoldDuration(),
+		MemorySize:      c.MemorySize(),
+		Cold:            c.IsCold(),
+		Label:           c.Label(),
+	}
+}
+
+func (p *connectPool) Snapshot() []ConnectorSnapshot {
+	return p.pool.Snapshot()
+}
+
+// SynctestSnapshot is a deterministic description of a pool, for fake-time tests.
+type SynctestSnapshot struct {
+	Now        time.Time           // Time of the snapshot, read from the pool's clock
+	Size       int                 // Number of connectors
+	Working    int                 // Number of connectors in use
+	Connectors []ConnectorSnapshot // Every connector, ordered by Token
+}
+
+// SynctestSnapshot describes the pool with every time read from its WithClock clock and the connectors ordered by
+// token, so two runs driven by the same fake time yield equal snapshots. Evictions of a clear pass triggered by
+// advancing the clock show once the pass has run, after synctest.Wait in a testing/synctest bubble.
+func (p *connectPool) SynctestSnapshot() SynctestSnapshot {
+	connectors := p.pool.Snapshot()
+	sort.Slice(connectors, func(i, j int) bool { return connectors[i].Token < connectors[j].Token })
+
+	snapshot := SynctestSnapshot{
+		Now:        p.clock.Now(),
+		Size:       len(connectors),
+		Connectors: connectors,
+	}
+
+	for _, c := range connectors {
+		if c.Working {
+			snapshot.Working++
+		}
+	}
+
+	return snapshot
+}
+
+// ConnectPoolReadOnly is the inspection-only view of a pool returned by ReadOnlyView.
+type ConnectPoolReadOnly interface {
+	Size() int                          // Gets the number of connectors
+	WorkingNumber() int                 // Gets the number of active connections
+	Stats() PoolStats                   // Gets a point-in-time summary of the pool
+	Snapshot() []ConnectorSnapshot      // Gets a description of every connector
+	WatchSize(ch chan<- int) CancelFunc // Sends the new size on ch whenever it changes
+}
+
+type readOnlyView struct {
+	pool Inspector // Pool being inspected, unexported so the view cannot be converted back
+}
+
+// ReadOnlyView returns a view of pool that can inspect it but not register connections or change it, suitable for
+// monitoring goroutines and HTTP handlers.
+func ReadOnlyView(pool Inspector) ConnectPoolReadOnly {
+	return readOnlyView{pool: pool}
+}
+
+func (v readOnlyView) Size() int {
+	return v.pool.Size()
+}
+
+func (v readOnlyView) WorkingNumber() int {
+	return v.pool.WorkingNumber()
+}
+
+func (v readOnlyView) Stats() PoolStats {
+	return v.pool.Stats()
+}
+
+func (v readOnlyView) Snapshot() []ConnectorSnapshot {
+	return v.pool.Snapshot()
+}
+
+func (v readOnlyView) WatchSize(ch chan<- int) CancelFunc {
+	return v.pool.WatchSize(ch)
+}
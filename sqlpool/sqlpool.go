@@ -0,0 +1,94 @@
+// Package sqlpool pools *sql.Conn connections of a database/sql database with connectpool.
+package sqlpool
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	connectpool "github.com/HuXin0817/ConnectPool"
+)
+
+// ErrNoConn is returned by Conn, wrapping the database's error, when the pool handed out a connection the database
+// failed to provide.
+var ErrNoConn = errors.New("sqlpool: database failed to provide a connection")
+
+const (
+	connectTimeout = 30 * time.Second // Upper bound on obtaining a connection from the database
+	pingTimeout    = 5 * time.Second  // Upper bound on a health check ping
+)
+
+// NewSQLPool returns an Acquirer pool of up to maxSize *sql.Conn connections taken from db. Connections are closed with
+// (*sql.Conn).Close, which returns them to db, and health-checked with PingContext. opts are applied after these
+// defaults, so they can override them.
+func NewSQLPool(db *sql.DB, maxSize int, opts ...connectpool.Option) connectpool.Acquirer {
+	connectMethod := func() any {
+		ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+		defer cancel()
+
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			return err // Handed out as is, Conn reports it and the pool closes it on return
+		}
+
+		return conn
+	}
+
+	options := []connectpool.Option{
+		connectpool.WithCap(maxSize),
+		connectpool.WithCloseMethodE(closeConn, false),
+		connectpool.WithHealthCheck(pingConn),
+		connectpool.WithValidateOnReturn(isConn),
+	}
+
+	return connectpool.NewConnectPool(connectMethod, append(options, opts...)...).(connectpool.Acquirer)
+}
+
+// Conn acquires a connection from pool, a pool returned by NewSQLPool, waiting until ctx is done. The returned
+// AcquiredConn must be closed to give the connection back.
+func Conn(ctx context.Context, pool connectpool.Acquirer) (*sql.Conn, connectpool.AcquiredConn, error) {
+	acquired, err := pool.Acquire(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	conn, ok := acquired.Conn().(*sql.Conn)
+	if !ok {
+		_ = acquired.Close()
+
+		if err, ok := acquired.Conn().(error); ok {
+			return nil, nil, fmt.Errorf("%w: %w", ErrNoConn, err)
+		}
+		return nil, nil, ErrNoConn
+	}
+
+	return conn, acquired, nil
+}
+
+func closeConn(connect any) error {
+	if conn, ok := connect.(*sql.Conn); ok {
+		return conn.Close()
+	}
+
+	return nil // A connection the database failed to provide holds nothing
+}
+
+// isConn reports whether connect is a connection, rather than the error of one the database failed to provide.
+func isConn(connect any) bool {
+	_, ok := connect.(*sql.Conn)
+	return ok
+}
+
+func pingConn(connect any) error {
+	conn, ok := connect.(*sql.Conn)
+	if !ok {
+		return ErrNoConn
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+	defer cancel()
+
+	return conn.PingContext(ctx)
+}
@@ -0,0 +1,108 @@
+package sqlpool
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	connectpool "github.com/HuXin0817/ConnectPool"
+)
+
+// fakeConnector is a driver.Connector handing out fakeConns, or failing with err once set.
+type fakeConnector struct {
+	err    atomic.Pointer[error] // Error Connect fails with, nil to succeed
+	opened atomic.Int64          // Number of connections opened
+	closed atomic.Int64          // Number of connections closed
+}
+
+func (f *fakeConnector) Connect(context.Context) (driver.Conn, error) {
+	if err := f.err.Load(); err != nil {
+		return nil, *err
+	}
+
+	f.opened.Add(1)
+	return &fakeConn{connector: f}, nil
+}
+
+func (f *fakeConnector) Driver() driver.Driver {
+	return nil
+}
+
+// fakeConn is a driver.Conn supporting nothing but Close.
+type fakeConn struct {
+	connector *fakeConnector // Connector counting the closes
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("fakeConn: not supported")
+}
+
+func (c *fakeConn) Close() error {
+	c.connector.closed.Add(1)
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("fakeConn: not supported")
+}
+
+func TestConn(t *testing.T) {
+	connector := new(fakeConnector)
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	pool := NewSQLPool(db, 2)
+
+	conn, acquired, err := Conn(context.Background(), pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = conn.PingContext(context.Background()); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+
+	if err = acquired.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Closing the pool closes the *sql.Conn, returning the connection to db
+	pool.Close()
+	if err = conn.PingContext(context.Background()); !errors.Is(err, sql.ErrConnDone) {
+		t.Fatalf("Ping error = %v after Close, want sql.ErrConnDone", err)
+	}
+}
+
+func TestConn_DriverError(t *testing.T) {
+	connector := new(fakeConnector)
+	refused := errors.New("connection refused")
+	connector.err.Store(&refused)
+
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	var panicked atomic.Int64
+	pool := NewSQLPool(db, 1, connectpool.WithDealPanicMethod(func(any) { panicked.Add(1) }))
+	defer pool.Close()
+
+	// The driver's error is reported, not only the generic one
+	if _, _, err := Conn(context.Background(), pool); !errors.Is(err, ErrNoConn) || !errors.Is(err, refused) {
+		t.Fatalf("Conn error = %v, want ErrNoConn wrapping %v", err, refused)
+	}
+
+	if n := panicked.Load(); n != 0 {
+		t.Fatalf("panic handler called %d times for a dial failure, want 0", n)
+	}
+
+	// The failed connection is not reused once the database recovers
+	connector.err.Store(nil)
+
+	if _, acquired, err := Conn(context.Background(), pool); err != nil {
+		t.Fatalf("Conn error = %v after the database recovered", err)
+	} else {
+		_ = acquired.Close()
+	}
+}
@@ -0,0 +1,133 @@
+package connectpool
+
+import "time"
+
+// PoolStats is a point-in-time summary of the pool's state.
+type PoolStats struct {
+	Name          string        // Name of the pool
+	Size          int           // Number of connectors in the pool
+	Cap           int           // Maximum number of connectors
+	WorkingNumber int           // Number of connectors in use
+	IdleBytes     int64         // Summed memory size of idle connections, as measured by WithConnSize
+	TotalAcquired int64         // Number of times a connection was handed out since the pool was created
+	TotalCreated  uint64        // Number of connections created successfully since the pool was created
+	MaxWaiters    int64         // Largest number of goroutines that were searching for a connection at once
+	MaxHoldTime   time.Duration // Longest time a connection was held before being released
+
+	AffinityHits   uint64 // Number of RegisterForKey calls served by the key's connection
+	AffinityMisses uint64 // Number of RegisterForKey calls that fell back to any connection
+
+	ReleasesCallerCancelled uint64        // Number of hand-outs released by their holder's cancelFunc
+	ReleasesDeadlineExpired uint64        // Number of hand-outs released because their deadline fired first
+	DeadOnArrival           uint64        // Number of connections discarded within the WithDOAWindow window of their creation
+	Uptime                  time.Duration // Time since the pool was created
+
+	ClosedByReason map[string]uint64 // Number of connections closed per CloseReason
+	IdlePerLabel   map[string]int    // Number of idle connections per WithConnLabeler label
+
+	CreationFailureRate float64 // Fraction of connector creations that failed within the creation window
+	CreationBudget      int64   // Creations left before ErrBudgetExhausted, -1 if unlimited
+
+	CallbackQueueDepth int // Number of asynchronous callbacks waiting to run
+
+	WarmupWarmed int64 // Number of connectors created by the WithWarmupRamp warm-up so far
+	WarmupTarget int64 // Number of connectors the WithWarmupRamp warm-up aims for, 0 until it starts
+
+	AvgCloseDuration time.Duration // Mean duration of a closeMethod call
+	MaxCloseDuration time.Duration // Longest duration of a closeMethod call
+	TotalCloseErrors uint64        // Number of errors returned by a WithCloseMethodE close method
+	LastCloseError   error         // Last error returned by a WithCloseMethodE close method, nil if none
+	ShrinkTarget     int           // Size an in-progress ShrinkTo is shrinking to, -1 if none
+
+	HighPriority ClassStats // Wait metrics of the high priority class
+	LowPriority  ClassStats // Wait metrics of the low priority class
+
+	TotalClearSweeps       uint64 // Number of automatic clear passes since the pool was created
+	TotalConnectorsEvicted uint64 // Number of connectors removed by automatic clear passes
+	TotalForceEvicted      uint64 // Number of connectors force-evicted by ShrinkTo
+
+	InvariantViolations uint64 // Number of state transitions that broke an invariant, checked WithInvariantChecks only
+
+	Contention map[string]LockContention // Write lock waits per site, only recorded with the connectpool_debugstats build tag
+}
+
+// statsCache is the last PoolStats computed by a pool WithStatsCache.
+type statsCache struct {
+	stats PoolStats // Cached statistics
+	at    time.Time // Time the statistics were computed
+}
+
+// Stats returns the statistics cached within the WithStatsCache staleness, refreshing them once for all concurrent
+// callers when they are older, or fresh statistics without a cache.
+func (p *connectPool) Stats() PoolStats {
+	if p.statsMaxStaleness <= 0 {
+		return p.StatsFresh()
+	}
+
+	if cached := p.statsCache.Load(); cached != nil && p.clock.Since(cached.at) < p.statsMaxStaleness {
+		return cached.stats
+	}
+
+	p.statsRefresh.Lock()
+	defer p.statsRefresh.Unlock()
+
+	// Another caller may have refreshed the statistics while this one waited
+	if cached := p.statsCache.Load(); cached != nil && p.clock.Since(cached.at) < p.statsMaxStaleness {
+		return cached.stats
+	}
+
+	cached := &statsCache{at: p.clock.Now(), stats: p.StatsFresh()}
+	p.statsCache.Store(cached)
+	return cached.stats
+}
+
+// StatsFresh computes the statistics now, bypassing the WithStatsCache cache.
+func (p *connectPool) StatsFresh() PoolStats {
+	return PoolStats{
+		Name:          p.Name(),
+		Size:          p.Size(),
+		Cap:           p.Cap(),
+		WorkingNumber: p.WorkingNumber(),
+		IdleBytes:     p.pool.IdleBytes(),
+		TotalAcquired: p.BorrowCount(),
+		TotalCreated:  p.totalCreated.Load(),
+		MaxWaiters:    p.maxWaiters.Load(),
+		MaxHoldTime:   time.Duration(p.maxHoldTime.Load()),
+
+		AffinityHits:   p.affinityHits.Load(),
+		AffinityMisses: p.affinityMisses.Load(),
+		Uptime:         p.clock.Since(p.createdAt),
+
+		ReleasesCallerCancelled: p.releases.callerCancelled.Load(),
+		ReleasesDeadlineExpired: p.releases.deadlineExpired.Load(),
+		DeadOnArrival:           p.deadOnArrival.Load(),
+
+		ClosedByReason: p.closedByReasonCounts(),
+		IdlePerLabel:   p.pool.IdleByLabel(),
+
+		CreationFailureRate: p.creations.FailureRate(),
+		CreationBudget:      p.creationBudget.Load(),
+
+		CallbackQueueDepth: p.executor.QueueDepth(),
+
+		WarmupWarmed: p.warmupWarmed.Load(),
+		WarmupTarget: p.warmupTarget.Load(),
+
+		AvgCloseDuration: p.closeDurations.Average(),
+		MaxCloseDuration: time.Duration(p.closeDurations.max.Load()),
+		TotalCloseErrors: p.closeErrors.Load(),
+		LastCloseError:   p.lastCloseErrorValue(),
+		ShrinkTarget:     int(p.shrinkTarget.Load()),
+
+		HighPriority: p.scheduler.stats(High),
+		LowPriority:  p.scheduler.stats(Low),
+
+		TotalClearSweeps:       p.clearSweeps.Load(),
+		TotalConnectorsEvicted: p.evictedConnectors.Load(),
+		TotalForceEvicted:      p.forceEvicted.Load(),
+
+		InvariantViolations: p.invariantViolations.Load(),
+
+		Contention: p.pool.Contention(),
+	}
+}
@@ -0,0 +1,129 @@
+package connectpool
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HuXin0817/ConnectPool/clock"
+)
+
+// acquireOnce registers a connection from p and gives it back.
+func acquireOnce(p *connectPool) {
+	_, cancel := p.Register()
+	cancel()
+}
+
+func TestStatsCache_StalenessBound(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	p := newTestPool(t, counter(), WithClock(fake), WithStatsCache(time.Second))
+
+	acquireOnce(p)
+	if n := p.Stats().TotalAcquired; n != 1 {
+		t.Fatalf("TotalAcquired = %d, want 1", n)
+	}
+
+	// Within the staleness bound the cached statistics are served, while StatsFresh follows every transition
+	for i := int64(2); i <= 4; i++ {
+		acquireOnce(p)
+		fake.Advance(300 * time.Millisecond)
+
+		if n := p.Stats().TotalAcquired; n != 1 {
+			t.Fatalf("cached TotalAcquired = %d after %v, want 1", n, time.Duration(i-1)*300*time.Millisecond)
+		}
+		if n := p.StatsFresh().TotalAcquired; n != i {
+			t.Fatalf("StatsFresh TotalAcquired = %d, want %d", n, i)
+		}
+	}
+
+	// Once the cache reaches maxStaleness the next call refreshes it
+	fake.Advance(100 * time.Millisecond)
+	if n := p.Stats().TotalAcquired; n != 4 {
+		t.Fatalf("TotalAcquired = %d after the staleness bound, want 4", n)
+	}
+}
+
+func TestStatsCache_ConcurrentRefresh(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	p := newTestPool(t, counter(), WithClock(fake), WithStatsCache(time.Second))
+
+	acquireOnce(p)
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				if n := p.Stats().TotalAcquired; n < 1 {
+					t.Errorf("TotalAcquired = %d, want at least 1", n)
+					return
+				}
+			}
+		}()
+	}
+
+	for range 10 {
+		acquireOnce(p)
+		fake.Advance(time.Second)
+	}
+	wg.Wait()
+
+	if n := p.Stats().TotalAcquired; n != 11 {
+		t.Fatalf("TotalAcquired = %d, want 11", n)
+	}
+}
+
+func TestStats_WithoutCacheAlwaysFresh(t *testing.T) {
+	p := newTestPool(t, counter())
+
+	for i := int64(1); i <= 3; i++ {
+		acquireOnce(p)
+		if n := p.Stats().TotalAcquired; n != i {
+			t.Fatalf("TotalAcquired = %d, want %d", n, i)
+		}
+	}
+}
+
+func TestStats_Name(t *testing.T) {
+	if name := newTestPool(t, counter()).Stats().Name; name != "default" {
+		t.Fatalf("Stats().Name = %q without WithName, want %q", name, "default")
+	}
+
+	if name := newTestPool(t, counter(), WithName("shard-7")).Stats().Name; name != "shard-7" {
+		t.Fatalf("Stats().Name = %q, want %q", name, "shard-7")
+	}
+}
+
+func TestBorrowCount(t *testing.T) {
+	p := newTestPool(t, counter(), WithCap(2))
+
+	// Reused and new connections count alike, while WorkingNumber only follows the ones held
+	for i := int64(1); i <= 5; i++ {
+		_, first := p.Register()
+		_, second := p.Register()
+		if working := p.WorkingNumber(); working != 2 {
+			t.Fatalf("WorkingNumber = %d with two connections held, want 2", working)
+		}
+		first()
+		second()
+
+		if n := p.BorrowCount(); n != 2*i {
+			t.Fatalf("BorrowCount = %d after %d cycles, want %d", n, i, 2*i)
+		}
+	}
+
+	_, release, err := p.AcquireConnector()
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+
+	// A failed acquisition is not a borrow
+	p.Close()
+	p.Register()
+
+	if n, total := p.BorrowCount(), p.StatsFresh().TotalAcquired; n != 11 || total != 11 {
+		t.Fatalf("BorrowCount = %d, TotalAcquired = %d, want 11", n, total)
+	}
+}
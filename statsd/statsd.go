@@ -0,0 +1,173 @@
+// Package statsd reports connection pool metrics to a statsd-compatible UDP endpoint.
+package statsd
+
+import (
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	connectpool "github.com/HuXin0817/ConnectPool"
+)
+
+const (
+	maxDatagramSize = 1432                 // Largest payload that fits a single Ethernet frame without fragmentation
+	queueSize       = 1024                 // Number of metric lines buffered before dropping
+	writeTimeout    = 5 * time.Millisecond // Upper bound on a single datagram write
+	sampleInterval  = time.Second          // Interval between two samples of the pool.size and pool.working gauges
+)
+
+type client struct {
+	conn      net.Conn      // UDP connection to the statsd endpoint
+	prefix    string        // Prefix prepended to every metric name, the pool's name included
+	lines     chan string   // Metric lines waiting to be batched
+	done      chan struct{} // Closed when the pool is closed
+	closeOnce sync.Once     // Guards the closing of done
+}
+
+// WithStatsd returns an Option that sends pool.acquired and pool.released counters on each pool event, and
+// pool.size and pool.working gauges sampled every second, to the statsd endpoint at addr. Each pool the option is
+// applied to dials its own UDP connection, closed along with the pool; a failed dial is handed to the pool's panic
+// handler and leaves the pool without metrics. Metrics are batched into UDP datagrams and dropped rather than
+// blocking the pool when the send buffer is full.
+func WithStatsd(addr string, prefix string) connectpool.Option {
+	if prefix != "" {
+		prefix += "."
+	}
+
+	return connectpool.WithEventHookFactory(func(pool connectpool.ConnectPool) (func(connectpool.ConnectPool, connectpool.Event), error) {
+		conn, err := net.Dial("udp", addr)
+		if err != nil {
+			return nil, err
+		}
+
+		name := "" // Name of the pool, which only a pool created by NewConnectPool has
+		if inspector, ok := pool.(connectpool.Inspector); ok {
+			name = inspector.Name()
+		}
+
+		c := &client{
+			conn:   conn,
+			prefix: prefix + name + ".",
+			lines:  make(chan string, queueSize),
+			done:   make(chan struct{}),
+		}
+
+		go c.run()        // Starts the batching sender
+		go c.sample(pool) // Starts sampling the gauges
+		return c.handle, nil
+	})
+}
+
+// handle converts a pool event into metric lines.
+func (c *client) handle(pool connectpool.ConnectPool, event connectpool.Event) {
+	switch event {
+	case connectpool.EventAcquire:
+		c.send(c.counter("pool.acquired", 1))
+	case connectpool.EventRelease:
+		c.send(c.counter("pool.released", 1))
+	case connectpool.EventClose:
+		c.sendGauges(pool)                       // Reports the final gauges
+		c.closeOnce.Do(func() { close(c.done) }) // Stops the sender after flushing pending lines
+	}
+}
+
+// sample sends the gauges every sampleInterval until the client is closed, keeping the pool's size scans off the
+// acquisition path.
+func (c *client) sample(pool connectpool.ConnectPool) {
+	ticker := time.NewTicker(sampleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.sendGauges(pool)
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// sendGauges queues the pool.size and pool.working gauges.
+func (c *client) sendGauges(pool connectpool.ConnectPool) {
+	c.send(c.gauge("pool.size", pool.Size()))
+	c.send(c.gauge("pool.working", pool.WorkingNumber()))
+}
+
+// counter formats a counter line, prefixed with the client prefix.
+func (c *client) counter(name string, delta int) string {
+	return c.prefix + name + ":" + strconv.Itoa(delta) + "|c"
+}
+
+// gauge formats a gauge line, prefixed with the client prefix.
+func (c *client) gauge(name string, value int) string {
+	return c.prefix + name + ":" + strconv.Itoa(value) + "|g"
+}
+
+// send queues line without blocking, dropping it if the queue is full or the client is closed.
+func (c *client) send(line string) {
+	select {
+	case <-c.done:
+	case c.lines <- line:
+	default:
+	}
+}
+
+// run batches queued lines into datagrams until the client is closed.
+func (c *client) run() {
+	defer c.conn.Close()
+
+	buf := make([]byte, 0, maxDatagramSize)
+
+	for {
+		select {
+		case line := <-c.lines:
+			buf = c.appendLine(buf, line)
+
+			// Drains whatever is already queued into the same datagram
+			for drained := false; !drained; {
+				select {
+				case line = <-c.lines:
+					buf = c.appendLine(buf, line)
+				default:
+					drained = true
+				}
+			}
+
+			buf = c.flush(buf)
+
+		case <-c.done:
+			for len(c.lines) > 0 {
+				buf = c.appendLine(buf, <-c.lines)
+			}
+
+			c.flush(buf)
+			return
+		}
+	}
+}
+
+// appendLine adds line to buf, flushing first if the datagram would exceed maxDatagramSize.
+func (c *client) appendLine(buf []byte, line string) []byte {
+	if len(buf) > 0 && len(buf)+1+len(line) > maxDatagramSize {
+		buf = c.flush(buf)
+	}
+
+	if len(buf) > 0 {
+		buf = append(buf, '\n')
+	}
+
+	return append(buf, line...)
+}
+
+// flush writes buf as a single datagram, dropping it if the write would block.
+func (c *client) flush(buf []byte) []byte {
+	if len(buf) == 0 {
+		return buf
+	}
+
+	_ = c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	_, _ = c.conn.Write(buf) // Errors are dropped, metrics are best-effort
+
+	return buf[:0]
+}
@@ -0,0 +1,93 @@
+package statsd
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	connectpool "github.com/HuXin0817/ConnectPool"
+)
+
+// listen starts a local UDP listener standing in for the statsd endpoint.
+func listen(t *testing.T) net.PacketConn {
+	t.Helper()
+
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { listener.Close() })
+	return listener
+}
+
+// receive reads datagrams from listener until every line of want was received, failing after a second.
+func receive(t *testing.T, listener net.PacketConn, want ...string) {
+	t.Helper()
+
+	missing := make(map[string]bool, len(want))
+	for _, line := range want {
+		missing[line] = true
+	}
+
+	buf := make([]byte, maxDatagramSize)
+	_ = listener.SetReadDeadline(time.Now().Add(time.Second))
+
+	for len(missing) > 0 {
+		n, _, err := listener.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("missing metrics %v: %v", missing, err)
+		}
+
+		for _, line := range strings.Split(string(buf[:n]), "\n") {
+			delete(missing, line)
+		}
+	}
+}
+
+func TestWithStatsd(t *testing.T) {
+	listener := listen(t)
+
+	pool := connectpool.NewConnectPool(func() any { return 1 }, WithStatsd(listener.LocalAddr().String(), "test"), connectpool.WithName("db"))
+
+	_, cancel := pool.Register()
+	cancel()
+	receive(t, listener, "test.db.pool.acquired:1|c", "test.db.pool.released:1|c")
+
+	pool.Close()
+	receive(t, listener, "test.db.pool.size:0|g", "test.db.pool.working:0|g")
+}
+
+func TestWithStatsd_PerPoolConnection(t *testing.T) {
+	listener := listen(t)
+	option := WithStatsd(listener.LocalAddr().String(), "")
+
+	first := connectpool.NewConnectPool(func() any { return 1 }, option, connectpool.WithName("first"))
+	second := connectpool.NewConnectPool(func() any { return 2 }, option, connectpool.WithName("second"))
+	defer second.Close()
+
+	// Closing the first pool must not stop the metrics of the second one
+	first.Close()
+
+	_, cancel := second.Register()
+	cancel()
+	receive(t, listener, "second.pool.acquired:1|c", "second.pool.released:1|c")
+}
+
+func TestWithStatsd_DialError(t *testing.T) {
+	var reported any
+	pool := connectpool.NewConnectPool(func() any { return 1 }, WithStatsd("missing-port", "test"), connectpool.WithDealPanicMethod(func(panicInfo any) { reported = panicInfo }))
+	defer pool.Close()
+
+	if _, ok := reported.(error); !ok {
+		t.Fatalf("dial error not reported to the panic handler, got %v", reported)
+	}
+
+	// The pool still works without metrics
+	if _, cancel := pool.Register(); cancel == nil {
+		t.Fatal("Register failed")
+	} else {
+		cancel()
+	}
+}
@@ -0,0 +1,28 @@
+package connectpool
+
+import (
+	"context"
+
+	"golang.org/x/time/rate"
+)
+
+// Throttle limits acquisitions from the pool to maxPerSecond, making each one wait for its turn, until the returned
+// CancelFunc is called. A later call replaces the previous limit.
+func (p *connectPool) Throttle(maxPerSecond float64) CancelFunc {
+	limiter := rate.NewLimiter(rate.Limit(maxPerSecond), 1)
+	p.throttle.Store(limiter)
+
+	return func() {
+		p.throttle.CompareAndSwap(limiter, nil) // Leaves a limiter installed by a later call in place
+	}
+}
+
+// waitThrottle waits until the current Throttle limit, if any, allows an acquisition or ctx is done.
+func (p *connectPool) waitThrottle(ctx context.Context) error {
+	limiter := p.throttle.Load()
+	if limiter == nil {
+		return nil
+	}
+
+	return limiter.Wait(ctx)
+}
@@ -0,0 +1,159 @@
+package connectpool
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Thresholds of the Tuning suggestion heuristics.
+const (
+	tuningDominantShare = 0.5 // Share of the evictions from which a reason is considered the binding constraint
+	tuningDialShare     = 0.1 // Share of dialing acquisitions from which idle evictions are considered costly
+)
+
+// TuningReport summarizes which configuration limit closes the pool's connections, since the pool was created or
+// ResetTuning was last called.
+type TuningReport struct {
+	Since             time.Time         // Start of the reported window
+	Evictions         uint64            // Number of connections closed for any reason but Close
+	EvictionsByReason map[string]uint64 // Number of connections closed per CloseReason
+	AvgIdleAtEviction time.Duration     // Mean idle time of the connections closed by clear passes
+	AvgAgeAtEviction  time.Duration     // Mean age of the connections closed by clear passes
+	Acquisitions      int64             // Number of connections handed out
+	DialFraction      float64           // Fraction of the acquisitions that had to create a connection
+	Suggestion        string            // Plain-English advice derived from the figures above
+}
+
+// tuningCounters accumulates the figures of the Tuning report that other statistics do not record.
+type tuningCounters struct {
+	dialed        atomic.Uint64 // Number of acquisitions served by a connection created for them
+	idleEvictions atomic.Uint64 // Number of connections closed by clear passes
+	idleTotal     atomic.Int64  // Summed idle time of those connections, in nanoseconds
+	ageTotal      atomic.Int64  // Summed age of those connections, in nanoseconds
+
+	mu       sync.Mutex   // Guards baseline
+	baseline tuningSample // Cumulative figures when ResetTuning was last called
+}
+
+// tuningSample holds the cumulative figures a TuningReport is the difference of.
+type tuningSample struct {
+	at            time.Time
+	closed        [closeReasonCount]uint64
+	dialed        uint64
+	idleEvictions uint64
+	idleTotal     int64
+	ageTotal      int64
+	acquisitions  int64
+}
+
+// observeIdleEviction records the idle time and age of c, closed by a clear pass.
+func (p *connectPool) observeIdleEviction(c connector) {
+	p.tuning.idleEvictions.Add(1)
+	p.tuning.idleTotal.Add(int64(p.clock.Since(c.LastWorkingTime())))
+	p.tuning.ageTotal.Add(int64(c.Age()))
+}
+
+// tuningSample reads the pool's cumulative figures.
+func (p *connectPool) tuningSample() tuningSample {
+	sample := tuningSample{
+		at:            p.clock.Now(),
+		dialed:        p.tuning.dialed.Load(),
+		idleEvictions: p.tuning.idleEvictions.Load(),
+		idleTotal:     p.tuning.idleTotal.Load(),
+		ageTotal:      p.tuning.ageTotal.Load(),
+		acquisitions:  p.BorrowCount(),
+	}
+
+	for reason := range closeReasonCount {
+		sample.closed[reason] = p.closedByReason[reason].Load()
+	}
+
+	return sample
+}
+
+// Tuning reports which configuration limit closes the pool's connections since the pool was created or ResetTuning
+// was last called.
+func (p *connectPool) Tuning() TuningReport {
+	p.tuning.mu.Lock()
+	baseline := p.tuning.baseline
+	p.tuning.mu.Unlock()
+
+	if baseline.at.IsZero() {
+		baseline.at = p.createdAt
+	}
+
+	return tuningReport(baseline, p.tuningSample(), p.MaxFreeTime())
+}
+
+// ResetTuning starts a new Tuning window.
+func (p *connectPool) ResetTuning() {
+	sample := p.tuningSample()
+
+	p.tuning.mu.Lock()
+	p.tuning.baseline = sample
+	p.tuning.mu.Unlock()
+}
+
+// tuningReport computes the report of the window from since to now for a pool closing idle connections after
+// maxFreeTime.
+func tuningReport(since, now tuningSample, maxFreeTime time.Duration) TuningReport {
+	report := TuningReport{
+		Since:             since.at,
+		EvictionsByReason: make(map[string]uint64, closeReasonCount),
+		Acquisitions:      now.acquisitions - since.acquisitions,
+	}
+
+	var byReason [closeReasonCount]uint64
+	for reason := range closeReasonCount {
+		byReason[reason] = now.closed[reason] - since.closed[reason]
+		report.EvictionsByReason[reason.String()] = byReason[reason]
+
+		if reason != CloseShutdown {
+			report.Evictions += byReason[reason]
+		}
+	}
+
+	if idleEvictions := int64(now.idleEvictions - since.idleEvictions); idleEvictions > 0 {
+		report.AvgIdleAtEviction = time.Duration((now.idleTotal - since.idleTotal) / idleEvictions)
+		report.AvgAgeAtEviction = time.Duration((now.ageTotal - since.ageTotal) / idleEvictions)
+	}
+
+	if report.Acquisitions > 0 {
+		report.DialFraction = float64(now.dialed-since.dialed) / float64(report.Acquisitions)
+	}
+
+	report.Suggestion = suggestTuning(report, byReason, maxFreeTime)
+	return report
+}
+
+// suggestTuning names the limit that dominates the evictions of report, if any.
+func suggestTuning(report TuningReport, byReason [closeReasonCount]uint64, maxFreeTime time.Duration) string {
+	if report.Evictions == 0 {
+		if report.DialFraction >= tuningDominantShare {
+			return fmt.Sprintf("%.0f%% of acquisitions dial without any eviction; consider warming the pool with WithMinSize", report.DialFraction*100)
+		}
+
+		return "no connection was evicted; no limit is binding"
+	}
+
+	share := func(reason CloseReason) float64 {
+		return float64(byReason[reason]) / float64(report.Evictions)
+	}
+
+	switch {
+	case share(CloseIdle) >= tuningDominantShare && report.AvgIdleAtEviction < 2*maxFreeTime && report.DialFraction >= tuningDialShare:
+		return fmt.Sprintf("%.0f%% of evictions are idle-timeout after <2× maxFreeTime while %.0f%% of acquisitions dial; consider raising maxFreeTime", share(CloseIdle)*100, report.DialFraction*100)
+	case share(CloseIdle) >= tuningDominantShare:
+		return fmt.Sprintf("%.0f%% of evictions are idle-timeout but few acquisitions dial; maxFreeTime is not binding", share(CloseIdle)*100)
+	case share(CloseUnhealthy) >= tuningDominantShare:
+		return fmt.Sprintf("%.0f%% of evictions failed a health check; check the backend rather than the pool limits", share(CloseUnhealthy)*100)
+	case share(CloseDiscarded) >= tuningDominantShare:
+		return fmt.Sprintf("%.0f%% of evictions are connections discarded by their holders; check why callers discard them", share(CloseDiscarded)*100)
+	case share(CloseEvicted) >= tuningDominantShare:
+		return fmt.Sprintf("%.0f%% of evictions are on demand by Flush, ShrinkTo or EvictByLabel; consider lowering the pool's cap", share(CloseEvicted)*100)
+	}
+
+	return "no single limit dominates the evictions"
+}
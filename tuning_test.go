@@ -0,0 +1,192 @@
+package connectpool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/HuXin0817/ConnectPool/clock"
+)
+
+// tuningWindow returns the sample ending a window that starts at since and records delta.
+func tuningWindow(since, delta tuningSample) tuningSample {
+	now := tuningSample{
+		at:            since.at.Add(time.Hour),
+		dialed:        since.dialed + delta.dialed,
+		idleEvictions: since.idleEvictions + delta.idleEvictions,
+		idleTotal:     since.idleTotal + delta.idleTotal,
+		ageTotal:      since.ageTotal + delta.ageTotal,
+		acquisitions:  since.acquisitions + delta.acquisitions,
+	}
+
+	for reason := range closeReasonCount {
+		now.closed[reason] = since.closed[reason] + delta.closed[reason]
+	}
+
+	return now
+}
+
+func TestTuningReport_SyntheticHistories(t *testing.T) {
+	const maxFreeTime = time.Minute
+
+	// The window starts after some history, which the report must leave out
+	since := tuningSample{
+		at:            time.Unix(1000, 0),
+		closed:        [closeReasonCount]uint64{CloseIdle: 50, CloseUnhealthy: 50, CloseShutdown: 10},
+		dialed:        500,
+		idleEvictions: 50,
+		idleTotal:     int64(50 * time.Hour),
+		ageTotal:      int64(50 * time.Hour),
+		acquisitions:  1000,
+	}
+
+	// idle describes n clear pass evictions idling for idle and aged age each
+	idle := func(n uint64, idle, age time.Duration) tuningSample {
+		return tuningSample{
+			closed:        [closeReasonCount]uint64{CloseIdle: n},
+			idleEvictions: n,
+			idleTotal:     int64(n) * int64(idle),
+			ageTotal:      int64(n) * int64(age),
+		}
+	}
+
+	for _, tc := range []struct {
+		name       string
+		delta      tuningSample
+		evictions  uint64
+		suggestion string
+	}{
+		{
+			name:       "Quiet",
+			delta:      tuningSample{dialed: 1, acquisitions: 100},
+			suggestion: "no connection was evicted; no limit is binding",
+		},
+		{
+			name:       "ColdWithoutEvictions",
+			delta:      tuningSample{dialed: 60, acquisitions: 100},
+			suggestion: "60% of acquisitions dial without any eviction; consider warming the pool with WithMinSize",
+		},
+		{
+			name: "IdleTimeoutBinding",
+			delta: func() tuningSample {
+				d := idle(88, 90*time.Second, 10*time.Minute)
+				d.closed[CloseUnhealthy] = 12
+				d.dialed, d.acquisitions = 20, 100
+				return d
+			}(),
+			evictions:  100,
+			suggestion: "88% of evictions are idle-timeout after <2× maxFreeTime while 20% of acquisitions dial; consider raising maxFreeTime",
+		},
+		{
+			name: "IdleTimeoutWithFewDials",
+			delta: func() tuningSample {
+				d := idle(10, 90*time.Second, 10*time.Minute)
+				d.dialed, d.acquisitions = 5, 100
+				return d
+			}(),
+			evictions:  10,
+			suggestion: "100% of evictions are idle-timeout but few acquisitions dial; maxFreeTime is not binding",
+		},
+		{
+			name: "IdleFarBeyondMaxFreeTime",
+			delta: func() tuningSample {
+				d := idle(10, 2*maxFreeTime, 10*time.Minute)
+				d.dialed, d.acquisitions = 50, 100
+				return d
+			}(),
+			evictions:  10,
+			suggestion: "100% of evictions are idle-timeout but few acquisitions dial; maxFreeTime is not binding",
+		},
+		{
+			name:       "Unhealthy",
+			delta:      tuningSample{closed: [closeReasonCount]uint64{CloseUnhealthy: 3, CloseIdle: 1}},
+			evictions:  4,
+			suggestion: "75% of evictions failed a health check; check the backend rather than the pool limits",
+		},
+		{
+			name:       "Discarded",
+			delta:      tuningSample{closed: [closeReasonCount]uint64{CloseDiscarded: 1, CloseEvicted: 1}},
+			evictions:  2,
+			suggestion: "50% of evictions are connections discarded by their holders; check why callers discard them",
+		},
+		{
+			name:       "Evicted",
+			delta:      tuningSample{closed: [closeReasonCount]uint64{CloseEvicted: 6, CloseUnhealthy: 4}},
+			evictions:  10,
+			suggestion: "60% of evictions are on demand by Flush, ShrinkTo or EvictByLabel; consider lowering the pool's cap",
+		},
+		{
+			name:       "Mixed",
+			delta:      tuningSample{closed: [closeReasonCount]uint64{CloseDiscarded: 4, CloseUnhealthy: 3, CloseEvicted: 3}},
+			evictions:  10,
+			suggestion: "no single limit dominates the evictions",
+		},
+		{
+			name:       "ShutdownNotAnEviction",
+			delta:      tuningSample{closed: [closeReasonCount]uint64{CloseShutdown: 100, CloseUnhealthy: 1}},
+			evictions:  1,
+			suggestion: "100% of evictions failed a health check; check the backend rather than the pool limits",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			report := tuningReport(since, tuningWindow(since, tc.delta), maxFreeTime)
+
+			if report.Since != since.at || report.Evictions != tc.evictions || report.Acquisitions != tc.delta.acquisitions {
+				t.Fatalf("report since %v with %d evictions and %d acquisitions, want %v, %d and %d",
+					report.Since, report.Evictions, report.Acquisitions, since.at, tc.evictions, tc.delta.acquisitions)
+			}
+			for reason := range closeReasonCount {
+				if got := report.EvictionsByReason[reason.String()]; got != tc.delta.closed[reason] {
+					t.Fatalf("%d %v evictions, want %d", got, reason, tc.delta.closed[reason])
+				}
+			}
+			if report.Suggestion != tc.suggestion {
+				t.Fatalf("suggestion %q, want %q", report.Suggestion, tc.suggestion)
+			}
+		})
+	}
+}
+
+func TestTuningReport_Averages(t *testing.T) {
+	since := tuningSample{at: time.Unix(0, 0), dialed: 7, idleEvictions: 3, idleTotal: int64(time.Hour), acquisitions: 9}
+	now := tuningWindow(since, tuningSample{
+		closed:        [closeReasonCount]uint64{CloseIdle: 4},
+		dialed:        1,
+		idleEvictions: 4,
+		idleTotal:     int64(4 * time.Minute),
+		ageTotal:      int64(40 * time.Minute),
+		acquisitions:  8,
+	})
+
+	report := tuningReport(since, now, time.Minute)
+	if report.AvgIdleAtEviction != time.Minute || report.AvgAgeAtEviction != 10*time.Minute || report.DialFraction != 0.125 {
+		t.Fatalf("average idle %v, average age %v, dial fraction %v, want 1m0s, 10m0s and 0.125",
+			report.AvgIdleAtEviction, report.AvgAgeAtEviction, report.DialFraction)
+	}
+
+	// An empty window averages nothing
+	if report := tuningReport(now, now, time.Minute); report.AvgIdleAtEviction != 0 || report.AvgAgeAtEviction != 0 || report.DialFraction != 0 {
+		t.Fatalf("empty window reported %+v", report)
+	}
+}
+
+func TestTuning_ResetWindow(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	p := newTestPool(t, counter(), WithClock(fake), WithTestabilityMode(), WithMaxFreeTime(time.Minute))
+
+	// One dialing acquisition, then a clear pass evicts the connection after 90s idle
+	_, cancel := p.Register()
+	fake.Advance(30 * time.Second)
+	cancel()
+	fake.Advance(90 * time.Second)
+	p.ClearNow()
+
+	report := p.Tuning()
+	if report.Evictions != 1 || report.AvgIdleAtEviction != 90*time.Second || report.AvgAgeAtEviction != 2*time.Minute || report.DialFraction != 1 {
+		t.Fatalf("report %+v, want 1 eviction after 1m30s idle at 2m0s old, every acquisition dialing", report)
+	}
+
+	p.ResetTuning()
+	if report := p.Tuning(); report.Since != fake.Now() || report.Evictions != 0 || report.Acquisitions != 0 {
+		t.Fatalf("report %+v after ResetTuning, want an empty window since %v", report, fake.Now())
+	}
+}
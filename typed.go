@@ -0,0 +1,110 @@
+package connectpool
+
+import (
+	"context"
+	"sync"
+)
+
+// leaseReleases recycles the releases of the Leases, so that a warm Acquire and Release cycle does not allocate.
+var leaseReleases = sync.Pool{
+	New: func() any { return new(release) },
+}
+
+// Pool is a pool of connections of type T. The connections are boxed into the untyped core once, when created, and
+// handed out as T by a type assertion, which does not allocate. Together with the recycled hand-out bookkeeping, a
+// warm Acquire and Release cycle, one finding an idle connection, makes no allocation; a cycle that creates a
+// connection allocates its connector and boxes the connection once. Diagnostics mode records a stack per hand-out and
+// so allocates on every cycle.
+type Pool[T any] struct {
+	pool *connectPool // Untyped pool storing the connections
+}
+
+// Lease is a connection of a Pool held by its caller until Release. It is a value: copies refer to the same hand-out.
+type Lease[T any] struct {
+	conn  T        // Connection held
+	r     *release // Hand-out of the connection, reused once released
+	lease uint64   // Number of r's lease this Lease holds
+}
+
+// NewPool creates a Pool of the connections created by connectMethod, configured by options. Options replacing the
+// connectMethod, such as WithDialerPerPool, make Acquire fail with ErrConnType, and WithCloseInterception does not
+// apply: the connections are handed out as they are. It panics with ErrNilConnectMethod if connectMethod is nil.
+func NewPool[T any](connectMethod func() T, options ...Option) *Pool[T] {
+	if connectMethod == nil {
+		panic(ErrNilConnectMethod)
+	}
+
+	return &Pool[T]{pool: NewConnectPool(func() any { return connectMethod() }, options...).(*connectPool)}
+}
+
+// Acquire waits for a connection until ctx is done. It fails with the creation error of a connection that could not
+// be created, or ErrConnType if the connection is not a T.
+func (p *Pool[T]) Acquire(ctx context.Context) (Lease[T], error) {
+	var config registerConfig
+
+	c, err := p.pool.searchConnector(ctx, config.priority)
+	if err != nil {
+		return Lease[T]{}, err
+	}
+
+	config.startWorking(c)
+
+	var r *release
+	if p.pool.diagnostics == nil {
+		r = leaseReleases.Get().(*release)
+	} else {
+		r = new(release)
+	}
+	p.pool.track(r, c)
+
+	lease := Lease[T]{r: r, lease: r.lease.Load()}
+
+	if err = c.Err(); err != nil {
+		lease.Release()
+		return Lease[T]{}, err
+	}
+
+	conn, ok := c.GetConnect().(T)
+	if !ok {
+		lease.Release()
+		return Lease[T]{}, ErrConnType
+	}
+
+	lease.conn = conn
+	return lease, nil
+}
+
+// Conn returns the connection held.
+func (l Lease[T]) Conn() T {
+	return l.conn
+}
+
+// Release gives the connection back to the pool. Only the first Release of a hand-out, by any copy of the Lease,
+// has an effect.
+func (l Lease[T]) Release() {
+	if l.r == nil || !l.r.lease.CompareAndSwap(l.lease, l.lease+1) {
+		return
+	}
+
+	l.r.cancel()
+
+	// A release recording its stack for diagnostics carries a finalizer, so it is not reused
+	if l.r.stack != nil {
+		return
+	}
+
+	l.r.pool = nil
+	l.r.connector = nil
+	l.r.released.Store(false)
+	leaseReleases.Put(l.r)
+}
+
+// Untyped returns the untyped pool storing the connections, for statistics and configuration.
+func (p *Pool[T]) Untyped() ConnectPool {
+	return p.pool
+}
+
+// Close closes the pool.
+func (p *Pool[T]) Close() {
+	p.pool.Close()
+}
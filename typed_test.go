@@ -0,0 +1,59 @@
+package connectpool
+
+import (
+	"context"
+	"testing"
+)
+
+// warmPool returns a Pool[int64] holding one idle connection, so that its Acquire and Release cycles are warm.
+func warmPool(tb testing.TB) *Pool[int64] {
+	tb.Helper()
+
+	var n int64
+	p := NewPool(func() int64 { n++; return n }, WithTestabilityMode())
+	tb.Cleanup(p.Close)
+
+	lease, err := p.Acquire(context.Background())
+	if err != nil {
+		tb.Fatal(err)
+	}
+	lease.Release()
+	return p
+}
+
+func TestPool_WarmCycleDoesNotAllocate(t *testing.T) {
+	if raceEnabled {
+		t.Skip("the race detector drops recycled releases at random")
+	}
+
+	p := warmPool(t)
+	ctx := context.Background()
+
+	allocs := testing.AllocsPerRun(1000, func() {
+		lease, err := p.Acquire(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lease.Release()
+	})
+
+	if allocs != 0 {
+		t.Fatalf("%v allocations per warm Acquire and Release, want none", allocs)
+	}
+}
+
+// BenchmarkPool_WarmCycle acquires and releases the idle connection of a Pool[int64], reporting 0 allocs/op.
+func BenchmarkPool_WarmCycle(b *testing.B) {
+	p := warmPool(b)
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		lease, err := p.Acquire(ctx)
+		if err != nil {
+			b.Fatal(err)
+		}
+		lease.Release()
+	}
+}
@@ -0,0 +1,24 @@
+package connectpool
+
+import "fmt"
+
+// Validate checks the pool's invariants and returns one error per violation, nil if it is consistent: the size is
+// within the cap, no more connectors work than the pool holds, no connector last worked in the future or is stored
+// under two Tokens, and the autoClear goroutine runs until the pool is closed. Unlike the checks of
+// WithInvariantChecks, it walks the whole pool, so it is meant for tests and debugging rather than every transition.
+func (p *connectPool) Validate() (errs []error) {
+	// Reads the working number before the size, so that a connector created for a concurrent acquisition in between
+	// raises the size rather than only the working number
+	working := p.WorkingNumber()
+	size, maxSize := p.Size(), p.Cap()
+
+	if size > maxSize {
+		errs = append(errs, fmt.Errorf("connectpool: size %d exceeds cap %d", size, maxSize))
+	}
+
+	if working > size {
+		errs = append(errs, fmt.Errorf("connectpool: working number %d exceeds size %d", working, size))
+	}
+
+	return append(errs, p.pool.Validate()...)
+}
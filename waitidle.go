@@ -0,0 +1,73 @@
+package connectpool
+
+import (
+	"context"
+	"sync"
+)
+
+// broadcast wakes every goroutine waiting on it, like a sync.Cond whose wait can be abandoned.
+type broadcast struct {
+	mutex sync.Mutex
+	ch    chan struct{} // Closed by the next signal, nil while no one waits
+}
+
+// wait returns a channel closed by the next signal.
+func (b *broadcast) wait() <-chan struct{} {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.ch == nil {
+		b.ch = make(chan struct{})
+	}
+
+	return b.ch
+}
+
+// signal wakes the current waiters.
+func (b *broadcast) signal() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.ch != nil {
+		close(b.ch)
+		b.ch = nil
+	}
+}
+
+// WaitIdle blocks until no connection is in use, or also until every connection was evicted if untilEmpty is true.
+// It returns ctx's error if ctx is done first.
+func (p *connectPool) WaitIdle(ctx context.Context, untilEmpty bool) error {
+	for {
+		// Takes the signal before checking, so a change in between is never missed
+		changed := p.idleSignal.wait()
+
+		if p.WorkingNumber() == 0 && (!untilEmpty || p.Size() == 0) {
+			return nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// WaitForDrain blocks until every connector was evicted from the pool, including connectors still in use, which
+// WaitIdle would wait for to be released first. It returns ctx's error if ctx is done first.
+func (p *connectPool) WaitForDrain(ctx context.Context) error {
+	for {
+		// Takes the signal before checking, so a change in between is never missed
+		changed := p.idleSignal.wait()
+
+		if p.Size() == 0 {
+			return nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
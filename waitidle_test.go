@@ -0,0 +1,101 @@
+package connectpool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWaitIdle_DrainingWorkload(t *testing.T) {
+	p := newTestPool(t, counter(), WithMaxFreeTime(time.Millisecond), WithAutoClearInterval(time.Millisecond))
+
+	// Five holders give their connections back one after the other
+	var released atomic.Int64
+	for i := range 5 {
+		_, cancel := p.Register()
+		time.AfterFunc(time.Duration(i+1)*5*time.Millisecond, func() {
+			released.Add(1)
+			cancel()
+		})
+	}
+
+	if err := p.WaitIdle(context.Background(), false); err != nil {
+		t.Fatal(err)
+	}
+	if n, working := released.Load(), p.WorkingNumber(); n != 5 || working != 0 {
+		t.Fatalf("WaitIdle returned with %d of 5 connections released, %d working", n, working)
+	}
+
+	// The clear passes then evict the idle connections
+	if err := p.WaitIdle(context.Background(), true); err != nil {
+		t.Fatal(err)
+	}
+	if size := p.Size(); size != 0 {
+		t.Fatalf("WaitIdle until empty returned with size %d", size)
+	}
+}
+
+func TestWaitIdle_ContextDone(t *testing.T) {
+	p := newTestPool(t, counter())
+
+	_, cancel := p.Register()
+	defer cancel()
+
+	ctx, stop := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer stop()
+
+	if err := p.WaitIdle(ctx, false); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("WaitIdle error = %v with a connection held, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestWaitForDrain_AutoClear(t *testing.T) {
+	p := newTestPool(t, counter(), WithMaxFreeTime(time.Millisecond), WithAutoClearInterval(time.Millisecond))
+
+	// Three holders give their connections back one after the other, and the clear passes evict them
+	var released atomic.Int64
+	for i := range 3 {
+		_, cancel := p.Register()
+		time.AfterFunc(time.Duration(i+1)*5*time.Millisecond, func() {
+			released.Add(1)
+			cancel()
+		})
+	}
+
+	ctx, stop := context.WithTimeout(context.Background(), time.Second)
+	defer stop()
+
+	if err := p.WaitForDrain(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n, size := released.Load(), p.Size(); n != 3 || size != 0 {
+		t.Fatalf("WaitForDrain returned with %d of 3 connections released, size %d", n, size)
+	}
+
+	// An empty pool is drained already
+	if err := p.WaitForDrain(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWaitForDrain_ContextDone(t *testing.T) {
+	p := newTestPool(t, counter(), WithMaxFreeTime(time.Millisecond), WithAutoClearInterval(time.Millisecond))
+
+	// The clear passes evict the idle connection but never the held one
+	_, cancel := p.Register()
+	defer cancel()
+	_, release := p.Register()
+	release()
+
+	ctx, stop := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer stop()
+
+	if err := p.WaitForDrain(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("WaitForDrain error = %v with a connection held, want %v", err, context.DeadlineExceeded)
+	}
+	if size := p.Size(); size != 1 {
+		t.Fatalf("size %d after the clear passes, want the held connection only", size)
+	}
+}
@@ -0,0 +1,170 @@
+package connectpool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Warm creates connectors concurrently until the pool holds MinSize (bounded by Cap) connectors or ctx is done.
+// Each failed creation is sent on the returned channel, which is closed once warming stops; callers should range
+// over it, since unread failures hold warming back until ctx is done. Warming also stops once the pool is closed,
+// has failed or has spent its creation budget, and after a round of creations that all failed, so a broken
+// backend is not dialed in a loop.
+func (p *connectPool) Warm(ctx context.Context) <-chan error {
+	errs := make(chan error)
+
+	go func() {
+		defer close(errs)
+
+		target := min(p.MinSize(), p.Cap())
+
+		for ctx.Err() == nil && !p.isClosed() {
+			missing := target - p.Size()
+			if missing <= 0 {
+				return
+			}
+
+			// Creates the missing connectors concurrently, then re-checks since failed ones are discarded
+			var created, stopped atomic.Bool
+			var wg sync.WaitGroup
+			wg.Add(missing)
+
+			for i := 0; i < missing; i++ {
+				go func() {
+					defer wg.Done()
+
+					// Waits for a slot of the warm limiter, if any, before dialing
+					if !p.warmLimiter.acquire(ctx) {
+						return
+					}
+					defer p.warmLimiter.release()
+
+					err := p.warmConnector()
+					if err == nil {
+						created.Store(true)
+						return
+					}
+
+					if stopsWarming(err) {
+						stopped.Store(true)
+					}
+
+					select {
+					case errs <- err:
+					case <-ctx.Done():
+					}
+				}()
+			}
+
+			wg.Wait()
+
+			if stopped.Load() || !created.Load() {
+				return
+			}
+		}
+	}()
+
+	return errs
+}
+
+// StaggeredWarmUp creates n connectors one at a time, pausing interval between creations, so a backend is not hit
+// with n simultaneous connection attempts. It stops early once the pool reaches its cap or is closed. Each failed
+// creation is sent on the returned channel, which is buffered for all of them and closed once warming stops.
+func (p *connectPool) StaggeredWarmUp(n int, interval time.Duration) <-chan error {
+	errs := make(chan error, max(n, 0))
+
+	go func() {
+		defer close(errs)
+
+		for i := 0; i < n && p.Size() < p.Cap(); i++ {
+			if i > 0 {
+				timer := p.clock.NewTimer(interval)
+				select {
+				case <-timer.C():
+				case <-p.done:
+					timer.Stop()
+					return
+				}
+			}
+
+			if err := p.warmConnector(); err != nil {
+				errs <- err
+
+				if stopsWarming(err) {
+					return
+				}
+			}
+		}
+	}()
+
+	return errs
+}
+
+// stopsWarming reports whether err, returned by warmConnector, fails every further creation.
+func stopsWarming(err error) bool {
+	return errors.Is(err, ErrPoolClosed) || errors.Is(err, ErrBudgetExhausted) || errors.Is(err, ErrPoolFailed)
+}
+
+// WarmLimiter bounds how many connectors Warm creates concurrently. A limiter passed to several pools
+// WithWarmLimiter bounds their warm-ups together; otherwise each pool has its own.
+type WarmLimiter struct {
+	slots chan struct{} // One element per creation in progress
+}
+
+// NewWarmLimiter returns a WarmLimiter allowing n concurrent creations.
+func NewWarmLimiter(n int) *WarmLimiter {
+	return &WarmLimiter{slots: make(chan struct{}, max(n, 1))}
+}
+
+// acquire waits for a free slot, reporting false if ctx is done first. A nil limiter never waits.
+func (l *WarmLimiter) acquire(ctx context.Context) bool {
+	if l == nil {
+		return true
+	}
+
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// release frees a slot taken by acquire.
+func (l *WarmLimiter) release() {
+	if l != nil {
+		<-l.slots
+	}
+}
+
+// warmConnector adds a single idle connector to the pool, discarding it if its creation failed.
+func (p *connectPool) warmConnector() error {
+	if err := p.Err(); err != nil {
+		return err
+	}
+
+	if !p.takeCreationBudget() {
+		return ErrBudgetExhausted
+	}
+
+	c := p.pool.AddConnector()
+	if c == nil {
+		return ErrPoolClosed
+	}
+
+	p.recordCreation(c)
+	p.labelConnector(c)
+
+	if err := c.Err(); err != nil {
+		p.pool.RemoveConnector(c, nil)
+		return err
+	}
+
+	c.ReleaseUnused() // Hands the new connector over to the idle pool, idle from now on
+	p.sampleSize(c)
+	p.emit(EventCreate)
+	return nil
+}
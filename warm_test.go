@@ -0,0 +1,203 @@
+package connectpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HuXin0817/ConnectPool/clock"
+)
+
+// drain collects the errors sent on errs until it is closed, failing the test if it stays open for a second.
+func drain(t *testing.T, errs <-chan error) (received []error) {
+	t.Helper()
+
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case err, ok := <-errs:
+			if !ok {
+				return received
+			}
+
+			received = append(received, err)
+		case <-timeout:
+			t.Fatalf("warming did not stop, %d errors received", len(received))
+		}
+	}
+}
+
+// waitUntil polls cond until it holds, failing the test after a second.
+func waitUntil(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	for deadline := time.Now().Add(time.Second); !cond(); time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting until %s", what)
+		}
+	}
+}
+
+func TestWarm(t *testing.T) {
+	p := newTestPool(t, counter(), WithMinSize(3))
+
+	if errs := drain(t, p.Warm(context.Background())); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	if size := p.Size(); size != 3 {
+		t.Fatalf("Size = %d after Warm, want 3", size)
+	}
+}
+
+func TestWarm_StopsOnClosedPool(t *testing.T) {
+	p := newTestPool(t, counter(), WithMinSize(3))
+	p.Close()
+
+	drain(t, p.Warm(context.Background()))
+}
+
+func TestWarm_StopsOnRepeatedFailures(t *testing.T) {
+	p := newTestPool(t, func() any { panic("backend down") }, WithMinSize(3), WithDealPanicMethod(func(any) {}))
+
+	// A single round of failures ends warming instead of dialing the broken backend forever
+	if errs := drain(t, p.Warm(context.Background())); len(errs) != 3 {
+		t.Fatalf("received %d errors, want 3", len(errs))
+	}
+}
+
+func TestWarm_StopsOnExhaustedBudget(t *testing.T) {
+	p := newTestPool(t, counter(), WithMinSize(3), WithCreationBudget(1))
+
+	errs := drain(t, p.Warm(context.Background()))
+	if len(errs) == 0 || !errors.Is(errs[0], ErrBudgetExhausted) {
+		t.Fatalf("received %v, want %v", errs, ErrBudgetExhausted)
+	}
+
+	if size := p.Size(); size != 1 {
+		t.Fatalf("Size = %d, want the 1 connector of the budget", size)
+	}
+}
+
+func TestStaggeredWarmUp_Timing(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	p := newTestPool(t, counter(), WithClock(fake), WithTestabilityMode())
+
+	errs := p.StaggeredWarmUp(3, time.Second)
+
+	// The first connector is created at once, then each one waits for the interval's timer
+	for size := 1; size <= 3; size++ {
+		waitUntil(t, fmt.Sprintf("%d connectors were created", size), func() bool { return p.Size() == size })
+		if size == 3 {
+			break
+		}
+
+		waitUntil(t, "the interval's timer was armed", func() bool { return fake.Pending() == 1 })
+		fake.Advance(time.Second - time.Millisecond)
+		if got := p.Size(); got != size || fake.Pending() != 1 {
+			t.Fatalf("Size = %d before the interval elapsed, want %d", got, size)
+		}
+		fake.Advance(time.Millisecond)
+	}
+
+	if received := drain(t, errs); len(received) > 0 {
+		t.Fatal(received)
+	}
+}
+
+// contendedDialer returns a connectMethod holding its own mutex for delay per connection, like a factory guarding
+// per-call state.
+func contendedDialer(delay time.Duration) func() any {
+	var mutex sync.Mutex
+	connect := counter()
+	return func() any {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		time.Sleep(delay)
+		return connect()
+	}
+}
+
+// BenchmarkWarm_SharedFactory warms 20 pools of 4 connections at once, their connections dialed 100µs each by one
+// shared factory or by a factory per pool.
+func BenchmarkWarm_SharedFactory(b *testing.B) {
+	const pools, size = 20, 4
+
+	warmAll := func(b *testing.B, options func() []Option) {
+		for range b.N {
+			var wg sync.WaitGroup
+			for range pools {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+
+					p := NewConnectPool(counter(), append(options(), WithCap(size), WithMinSize(size))...)
+					defer p.Close()
+
+					for err := range p.(*connectPool).Warm(context.Background()) {
+						b.Error(err)
+					}
+				}()
+			}
+			wg.Wait()
+		}
+	}
+
+	b.Run("Shared", func(b *testing.B) {
+		shared := contendedDialer(100 * time.Microsecond)
+		warmAll(b, func() []Option {
+			return []Option{WithDialerPerPool(func(string) func() any { return shared })}
+		})
+	})
+
+	b.Run("PerPool", func(b *testing.B) {
+		warmAll(b, func() []Option {
+			return []Option{WithDialerPerPool(func(string) func() any { return contendedDialer(100 * time.Microsecond) })}
+		})
+	})
+}
+
+func TestUnusedGracePeriod_PreWarmedPool(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		options []Option
+		evicted int // Connections evicted right after the warm-up
+	}{
+		{"WithoutGrace", nil, 1},
+		{"WithGrace", []Option{WithUnusedGracePeriod(time.Minute)}, 0},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := clock.NewFake(time.Unix(0, 0))
+
+			// Each dial takes a second, so warming three connections outlasts maxFreeTime
+			connect := counter()
+			dial := func() any {
+				fake.Advance(time.Second)
+				return connect()
+			}
+
+			p := newTestPool(t, dial, append([]Option{WithClock(fake), WithTestabilityMode(), WithMinSize(3),
+				WithMaxFreeTime(time.Second)}, tt.options...)...)
+
+			if errs := drain(t, p.Warm(context.Background())); len(errs) > 0 {
+				t.Fatal(errs)
+			}
+
+			// The first connection has waited two seconds, beyond maxFreeTime, the second one second, the third none
+			if evicted := p.ClearNow(); evicted != tt.evicted {
+				t.Fatalf("the clear pass after the warm-up evicted %d connections, want %d", evicted, tt.evicted)
+			}
+
+			// Past the grace period the never used connections expire too
+			fake.Advance(2 * time.Minute)
+			p.ClearNow()
+			if size := p.Size(); size != 0 {
+				t.Fatalf("Size = %d past the grace period, want 0", size)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package connectpool
+
+import "sync"
+
+// CancelFunc stops a background activity started by the pool.
+type CancelFunc func()
+
+// sizeWatchers keeps the signal channels of the goroutines started by WatchSize.
+type sizeWatchers struct {
+	mutex   sync.Mutex
+	signals map[chan struct{}]struct{}
+}
+
+// notify wakes every watcher without blocking; a watcher that is already awake coalesces the signal.
+func (w *sizeWatchers) notify() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	for signal := range w.signals {
+		select {
+		case signal <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// WatchSize sends the pool's new size on ch whenever a connector is added or removed. Sends happen on a
+// dedicated goroutine, so a slow reader never blocks the pool; sizes it misses are coalesced into the latest one.
+func (p *connectPool) WatchSize(ch chan<- int) CancelFunc {
+	signal := make(chan struct{}, 1)
+	done := make(chan struct{})
+
+	p.sizeWatchers.mutex.Lock()
+	if p.sizeWatchers.signals == nil {
+		p.sizeWatchers.signals = make(map[chan struct{}]struct{})
+	}
+	p.sizeWatchers.signals[signal] = struct{}{}
+	p.sizeWatchers.mutex.Unlock()
+
+	go func() {
+		last := p.Size()
+
+		for {
+			select {
+			case <-signal:
+			case <-done:
+				return
+			}
+
+			// Delivers the latest size, recomputing it if the size changes again while the reader is busy
+			for size := p.Size(); size != last; size = p.Size() {
+				select {
+				case ch <- size:
+					last = size
+				case <-signal:
+				case <-done:
+					return
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			p.sizeWatchers.mutex.Lock()
+			delete(p.sizeWatchers.signals, signal)
+			p.sizeWatchers.mutex.Unlock()
+
+			close(done)
+		})
+	}
+}